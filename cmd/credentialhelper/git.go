@@ -23,12 +23,12 @@ func runGit(ctx context.Context, goModulePath string, port int, args []string) e
 		return err
 	}
 	_ = os.Stdin.Close()
-	if !util.PathIsLexicalDescendant(goModulePath, c.Host+"/"+c.Path) {
-		return fmt.Errorf("stdin (host = %#v, path = %#v) is unexpectedly inconsistent with Go module path flag %#v", c.Host,
-			c.Path, goModulePath)
-	}
+	// The server checks that c is consistent with goModulePath.
 	respBody := &credentialhelpergit.UserPassword{}
-	err = doRequest(ctx, port, goModulePath, respBody)
+	err = doRequest(ctx, port, &credentialhelpergit.Request{
+		CredentialHelperParams: *c,
+		GoModulePath:           goModulePath,
+	}, respBody)
 	if err != nil {
 		return err
 	}
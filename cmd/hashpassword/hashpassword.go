@@ -0,0 +1,48 @@
+package hashpassword
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/passwordhash"
+)
+
+// CLI is a type reflected by "github.com/alecthomas/kong" that configures the CLI command for hashing passwords.
+type CLI struct {
+	Algorithm  string `default:"argon2id" enum:"argon2id,bcrypt" help:"Hash algorithm. Must be one of argon2id and bcrypt"`
+	BcryptCost int    `default:"12" help:"Cost of bcrypt hashes"`
+}
+
+// Run reads a password from the first line of stdin and prints its hash, which can be used as the value of
+// .clientAuth.identities[].passwordHash of the server's config file.
+func Run(ctx context.Context, opts *CLI) error {
+	if opts.Algorithm == "bcrypt" && opts.BcryptCost < passwordhash.MinBcryptCost {
+		return fmt.Errorf("value of bcrypt cost flag must be at least %d", passwordhash.MinBcryptCost)
+	}
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("error reading password from stdin: %w", err)
+	}
+	password = strings.TrimSuffix(strings.TrimSuffix(password, "\n"), "\r")
+	if password == "" {
+		return fmt.Errorf("password read from stdin must not be empty")
+	}
+	var hash string
+	switch opts.Algorithm {
+	case "argon2id":
+		hash, err = passwordhash.GenerateArgon2id(password)
+	case "bcrypt":
+		hash, err = passwordhash.GenerateBcrypt(password, opts.BcryptCost)
+	default:
+		return fmt.Errorf("unsupported algorithm %#v", opts.Algorithm)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(hash)
+	return err
+}
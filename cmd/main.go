@@ -10,7 +10,10 @@ import (
 
 	"github.com/go-mod-proxy/go-mod-proxy/cmd/clientforwardproxy"
 	"github.com/go-mod-proxy/go-mod-proxy/cmd/credentialhelper"
+	"github.com/go-mod-proxy/go-mod-proxy/cmd/hashpassword"
+	"github.com/go-mod-proxy/go-mod-proxy/cmd/migratestorage"
 	"github.com/go-mod-proxy/go-mod-proxy/cmd/server"
+	"github.com/go-mod-proxy/go-mod-proxy/cmd/warm"
 )
 
 func main() {
@@ -30,7 +33,10 @@ var CLI struct {
 
 	ClientForwardProxy clientforwardproxy.CLI `cmd:""`
 	CredentialHelper   credentialhelper.CLI   `cmd:"" help:"Credential helper utility used by server"`
+	HashPassword       hashpassword.CLI       `cmd:"" help:"Read a password from stdin and print a hash of it for .clientAuth.identities[].passwordHash"`
+	MigrateStorage     migratestorage.CLI     `cmd:"" help:"Copy all objects from one storage to another"`
 	Server             server.CLI             `cmd:""`
+	Warm               warm.CLI               `cmd:"" help:"Pre-populate the caches of a server with the build lists of go.mod, go.sum or go.work files"`
 }
 
 func mainCore() error {
@@ -51,8 +57,14 @@ func mainCore() error {
 	case "credential-helper <args>":
 		log.SetOutput(os.Stderr)
 		return credentialhelper.Run(ctx, &CLI.CredentialHelper)
+	case "hash-password":
+		return hashpassword.Run(ctx, &CLI.HashPassword)
+	case "migrate-storage":
+		return migratestorage.Run(ctx, &CLI.MigrateStorage)
 	case "server":
 		return server.Run(ctx, &CLI.Server)
+	case "warm <files>":
+		return warm.Run(ctx, &CLI.Warm)
 	default:
 		panic(kongCtx.Command())
 	}
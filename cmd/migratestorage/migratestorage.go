@@ -0,0 +1,97 @@
+package migratestorage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	servicestorage "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	servicestoragemigrate "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/migrate"
+	servicestoragestorageconfig "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/storageconfig"
+)
+
+// namePrefixes are the prefixes of the names of the objects stored by the server.
+var namePrefixes = []string{"gomod/", "zip/", "concat/"}
+
+// CLI is a type reflected by "github.com/alecthomas/kong" that configures the CLI command for migrating storage.
+type CLI struct {
+	DestinationConfigFile string `required:"" type:"existingfile" help:"Name of the YAML file that configures the destination storage. The file has the same format as the value of .storage of the server's config file"`
+	Parallelism           int    `default:"16" help:"Maximum number of objects to copy at the same time"`
+	ScratchDir            string `help:"Directory in which to create temporary scratch files"`
+	SkipVerify            bool   `help:"Do not compare the data and metadata of objects in the destination storage with those in the source storage"`
+	SourceConfigFile      string `required:"" type:"existingfile" help:"Name of the YAML file that configures the source storage. The file has the same format as the value of .storage of the server's config file"`
+}
+
+// Run copies all objects of the server from the source storage to the destination storage and prints a report. Objects
+// that already exist in the destination storage are skipped, so an interrupted run can be resumed by running again.
+// Servers should not use the destination storage until Run has succeeded.
+func Run(ctx context.Context, opts *CLI) error {
+	if opts.Parallelism <= 0 {
+		return fmt.Errorf("value of parallelism flag must be positive")
+	}
+	sourceCfg, err := config.LoadStorageFromYAMLFile(opts.SourceConfigFile)
+	if err != nil {
+		return err
+	}
+	destinationCfg, err := config.LoadStorageFromYAMLFile(opts.DestinationConfigFile)
+	if err != nil {
+		return err
+	}
+	if sourceCfg.Cache != nil || destinationCfg.Cache != nil {
+		return fmt.Errorf("the storage config files must not set .cache")
+	}
+	httpClient := cleanhttp.DefaultPooledClient()
+	var googleHTTPClient *http.Client
+	if sourceCfg.GCS != nil || destinationCfg.GCS != nil {
+		googleCredentials, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			return err
+		}
+		googleHTTPClient = &http.Client{
+			Transport: &oauth2.Transport{
+				Base:   httpClient.Transport,
+				Source: googleCredentials.TokenSource,
+			},
+		}
+	}
+	newStorage := func(cfg *config.Storage) (servicestorage.Storage, error) {
+		return servicestoragestorageconfig.NewStorage(ctx, servicestoragestorageconfig.StorageOptions{
+			Config:           cfg,
+			GoogleHTTPClient: googleHTTPClient,
+			HTTPClient:       httpClient,
+		})
+	}
+	source, err := newStorage(sourceCfg)
+	if err != nil {
+		return err
+	}
+	destination, err := newStorage(destinationCfg)
+	if err != nil {
+		return err
+	}
+	report, err := servicestoragemigrate.Migrate(ctx, servicestoragemigrate.Options{
+		Destination:  destination,
+		NamePrefixes: namePrefixes,
+		Parallelism:  opts.Parallelism,
+		ScratchDir:   opts.ScratchDir,
+		Source:       source,
+		Verify:       !opts.SkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := report.WriteTo(os.Stdout); err != nil {
+		return err
+	}
+	if !report.OK() {
+		return fmt.Errorf("migration is incomplete: %d object(s) failed and %d object(s) mismatched (see report)",
+			len(report.Failed), len(report.Mismatched))
+	}
+	return nil
+}
@@ -8,8 +8,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
-	gcs "cloud.google.com/go/storage"
 	"github.com/alessio/shellescape"
 	"github.com/hashicorp/go-cleanhttp"
 	jaspergoogle "github.com/jbrekelmans/go-lib/auth/google"
@@ -27,15 +27,19 @@ import (
 	"github.com/go-mod-proxy/go-mod-proxy/internal/server/credentialhelper"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
 	serviceauthaccesstoken "github.com/go-mod-proxy/go-mod-proxy/internal/service/auth/accesstoken"
+	serviceauthbasic "github.com/go-mod-proxy/go-mod-proxy/internal/service/auth/basic"
 	serviceauthgce "github.com/go-mod-proxy/go-mod-proxy/internal/service/auth/gce"
 	servicegomodulegocmd "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/gocmd"
-	servicestorage "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
-	servicestoragegcs "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/gcs"
+	servicegomodulewarm "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/warm"
+	servicestoragestorageconfig "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/storageconfig"
 )
 
 // Value of http.Server.MaxHeaderBytes
 const maxHeaderBytes = 5 * (1 << 10) // 5 Kibibytes (KiB)
 
+// Interval at which concat objects left behind by interrupted indexing are reconciled.
+const reconcileInterval = 15 * time.Minute
+
 // CLI is a type reflected by "github.com/alecthomas/kong" that configures the CLI command for the client forward proxy.
 type CLI struct {
 	ConfigFile           string `required:"" type:"existingfile" help:"Name of the YAML config file"`
@@ -117,25 +121,16 @@ func Run(ctx context.Context, opts *CLI) error {
 			},
 		}
 	}
-	var storage servicestorage.Storage
-	if cfg.Storage.GCS != nil {
-		storageHTTPClient := googleHTTPClient
-		gcsClient, err := gcs.NewClient(ctx, option.WithHTTPClient(storageHTTPClient))
-		if err != nil {
-			return err
-		}
-		storage, err = servicestoragegcs.NewStorage(servicestoragegcs.StorageOptions{
-			Bucket:     cfg.Storage.GCS.Bucket,
-			GCSClient:  gcsClient,
-			HTTPClient: storageHTTPClient,
-		})
-		if err != nil {
-			return err
-		}
-	} else {
-		return fmt.Errorf("non-GCS storage is not implemented")
+	storage, err := servicestoragestorageconfig.NewStorage(ctx, servicestoragestorageconfig.StorageOptions{
+		Config:           cfg.Storage,
+		GoogleHTTPClient: googleHTTPClient,
+		HTTPClient:       httpClient,
+	})
+	if err != nil {
+		return err
 	}
 	var accessTokenAuth *serviceauthaccesstoken.Authenticator
+	var basicAuth *serviceauthbasic.Authenticator
 	var gceAuth *serviceauthgce.Authenticator
 	var identityStore auth.IdentityStore
 	realm := ""
@@ -160,6 +155,16 @@ func Run(ctx context.Context, opts *CLI) error {
 		if err != nil {
 			return err
 		}
+		if basic := cfg.ClientAuth.Authenticators.Basic; basic != nil {
+			var cacheTimeToLive time.Duration
+			if !basic.DisableCache {
+				cacheTimeToLive = basic.CacheTimeToLive
+			}
+			basicAuth, err = serviceauthbasic.NewAuthenticator(identityStore, cacheTimeToLive)
+			if err != nil {
+				return err
+			}
+		}
 		if !enableGCEAuth {
 			log.Infof("not enabling GCE authentication because .clientAuth.enabled is not true or no element of .clientAuth.identities in %#v has .gceInstanceIdentityBinding != null",
 				opts.ConfigFile)
@@ -226,33 +231,76 @@ func Run(ctx context.Context, opts *CLI) error {
 	if scratchDir == "" {
 		scratchDir = os.TempDir()
 	}
+	var downloadLeaseTimeToLive time.Duration
+	if cfg.DownloadLease != nil {
+		downloadLeaseTimeToLive = cfg.DownloadLease.TimeToLive
+	}
+	var vcsCacheMaxBytes int64
+	if cfg.VCSCache != nil {
+		vcsCacheMaxBytes = cfg.VCSCache.MaxBytes
+	}
+	var zipURLTimeToLive time.Duration
+	if cfg.Storage.SignedURLRedirect != nil {
+		zipURLTimeToLive = cfg.Storage.SignedURLRedirect.TimeToLive
+	}
 	goModuleService, err := servicegomodulegocmd.NewService(servicegomodulegocmd.ServiceOptions{
 		GitCredentialHelperShell: fmt.Sprintf("exec %s --log-level=%s credential-helper --type=git --port=%d",
 			shellescape.Quote(executable2),
 			log.GetLevel().String(),
 			opts.CredentialHelperPort),
-		HTTPProxyInfo:       httpProxyInfo,
-		HTTPTransport:       httpTransport,
-		MaxParallelCommands: cfg.MaxChildProcesses,
-		ParentProxy:         cfg.ParentProxy.URLParsed,
-		PrivateModules:      cfg.PrivateModules,
-		PublicModules:       &cfg.PublicModules,
-		ScratchDir:          scratchDir,
-		Storage:             storage,
+		DownloadLeaseTimeToLive:     downloadLeaseTimeToLive,
+		FetchPublicModulesInProcess: cfg.PublicModules.FetchInProcess,
+		FetchZipsLazily:             cfg.FetchZipsLazily,
+		HTTPProxyInfo:               httpProxyInfo,
+		HTTPTransport:               httpTransport,
+		MaxParallelCommands:         cfg.MaxChildProcesses,
+		NegativeCache:               cfg.NegativeCache,
+		ParentProxy:                 cfg.ParentProxy.URLParsed,
+		PrivateModules:              cfg.PrivateModules,
+		PublicModules:               &cfg.PublicModules,
+		ScratchDir:                  scratchDir,
+		Storage:                     storage,
+		VCSCacheMaxBytes:            vcsCacheMaxBytes,
+		VerifyHashesOnRead:          cfg.VerifyHashesOnRead,
+		ZipURLTimeToLive:            zipURLTimeToLive,
 	})
 	if err != nil {
 		return err
 	}
+	go goModuleService.RunReconciler(ctx, reconcileInterval)
+	var warmer *servicegomodulewarm.Warmer
+	var warmIdentities []string
+	if cfg.Warm != nil {
+		warmer, err = servicegomodulewarm.NewWarmer(ctx, servicegomodulewarm.WarmerOptions{
+			GoModuleService: goModuleService,
+			Parallelism:     cfg.Warm.Parallelism,
+			QueueSize:       cfg.Warm.QueueSize,
+		})
+		if err != nil {
+			return err
+		}
+		warmIdentities = cfg.Warm.Identities
+	}
+	var gitHubWebhookSecret []byte
+	if cfg.GitHubWebhook != nil {
+		gitHubWebhookSecret = cfg.GitHubWebhook.Secret.Plaintext
+	}
 	server, err := server.NewServer(server.ServerOptions{
 		AccessControlList:        cfg.ClientAuth.AccessControlList,
 		AccessTokenAuthenticator: accessTokenAuth,
+		BasicAuthenticator:       basicAuth,
 		ClientAuthEnabled:        cfg.ClientAuth.Enabled,
 		GCEAuthenticator:         gceAuth,
+		GitHubWebhookSecret:      gitHubWebhookSecret,
 		GoModuleService:          goModuleService,
 		IdentityStore:            identityStore,
+		ModuleVersionIndexer:     goModuleService,
+		PrivateModules:           cfg.PrivateModules,
 		Realm:                    realm,
 		SumDatabaseProxy:         cfg.SumDatabaseProxy,
 		Transport:                httpTransport,
+		WarmIdentities:           warmIdentities,
+		Warmer:                   warmer,
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,220 @@
+package warm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	jasperurl "github.com/jbrekelmans/go-url"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	servicegomodulewarm "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/warm"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+// CLI is a type reflected by "github.com/alecthomas/kong" that configures the CLI command for warming up the caches of a
+// server.
+type CLI struct {
+	Files     []string `arg:"" type:"existingfile" help:"Names of go.mod, go.sum or go.work files, or of files that list a module@version per line (the format of a file is inferred from its base name)"`
+	Password  string   `required:"" help:"Password component of credentials to access server"`
+	ServerURL string   `required:"" help:"URL of the server"`
+	User      string   `required:"" help:"Username component of credentials to access server"`
+}
+
+type app struct {
+	accessToken string
+	ctx         context.Context
+	httpClient  *http.Client
+	serverURL   *url.URL
+}
+
+// Run warms up the caches of the server with the build list of each file and prints the report of the server (a stream of
+// newline-delimited JSON values) to stdout. Run returns an error if any file of a module version failed to be fetched.
+func Run(ctx context.Context, opts *CLI) error {
+	var err error
+	a := &app{
+		ctx:        ctx,
+		httpClient: cleanhttp.DefaultPooledClient(),
+	}
+	a.serverURL, err = jasperurl.ValidateURL(opts.ServerURL, jasperurl.ValidateURLOptions{
+		Abs:                                      jasperurl.NewBool(true),
+		AllowedSchemes:                           []string{"http", "https"},
+		StripFragment:                            true,
+		StripQuery:                               true,
+		StripPathTrailingSlashes:                 true,
+		StripPathTrailingSlashesNoPercentEncoded: true,
+		User:                                     new(bool),
+	})
+	if err != nil {
+		return fmt.Errorf("server URL is invalid: %w", err)
+	}
+	if err := a.authenticate(opts.User, opts.Password); err != nil {
+		return err
+	}
+	errorCount := 0
+	for _, file := range opts.Files {
+		format, data, err := readFile(file)
+		if err != nil {
+			return err
+		}
+		summary, err := a.warm(format, data)
+		if err != nil {
+			return fmt.Errorf("error warming up %#v: %w", file, err)
+		}
+		errorCount += summary.Errors
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%d files of module versions could not be fetched", errorCount)
+	}
+	return nil
+}
+
+func (a *app) authenticate(user, password string) error {
+	reqBody, err := json.Marshal(struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}{
+		User:     user,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, a.serverURL.JoinPath("auth", "userpassword").String(),
+		bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	var respBody struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := util.ReadJSON200Response(resp, &respBody, false); err != nil {
+		return fmt.Errorf("error authenticating: %w", err)
+	}
+	a.accessToken = respBody.AccessToken
+	return nil
+}
+
+func (a *app) warm(format servicegomodulewarm.Format, data []byte) (*servicegomodulewarm.Summary, error) {
+	u := a.serverURL.JoinPath("admin", "warm")
+	u.RawQuery = url.Values{"format": {string(format)}}.Encode()
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.accessToken)
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return nil, fmt.Errorf("server responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event servicegomodulewarm.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("error parsing report: %w", err)
+		}
+		fmt.Println(scanner.Text())
+		if event.Summary != nil {
+			return event.Summary, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("report ended before its summary")
+}
+
+// readFile reads file and infers its format from its base name. The use directives of go.work files can not be resolved
+// by the server, so go.work files are converted to go.mod files that require the requirements of the modules used by the
+// workspace.
+func readFile(file string) (servicegomodulewarm.Format, []byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	switch filepath.Base(file) {
+	case "go.mod":
+		return servicegomodulewarm.FormatGoMod, data, nil
+	case "go.sum":
+		return servicegomodulewarm.FormatGoSum, data, nil
+	case "go.work":
+		data, err = goWorkToGoMod(file, data)
+		if err != nil {
+			return "", nil, err
+		}
+		return servicegomodulewarm.FormatGoMod, data, nil
+	}
+	return servicegomodulewarm.FormatList, data, nil
+}
+
+func goWorkToGoMod(file string, data []byte) ([]byte, error) {
+	workFile, err := modfile.ParseWork(file, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	modFile, err := modfile.Parse("go.mod", []byte("module warm\n"), nil)
+	if err != nil {
+		return nil, err
+	}
+	workspaceModulePaths := map[string]struct{}{}
+	var useModFiles []*modfile.File
+	for _, use := range workFile.Use {
+		useModFile := filepath.Join(filepath.Dir(file), filepath.FromSlash(use.Path), "go.mod")
+		useModData, err := os.ReadFile(useModFile)
+		if err != nil {
+			return nil, err
+		}
+		f, err := modfile.Parse(useModFile, useModData, nil)
+		if err != nil {
+			return nil, err
+		}
+		if f.Module != nil {
+			workspaceModulePaths[f.Module.Mod.Path] = struct{}{}
+		}
+		useModFiles = append(useModFiles, f)
+	}
+	// Minimal version selection selects the maximum required version of each module.
+	requires := map[string]string{}
+	for _, f := range useModFiles {
+		for _, require := range f.Require {
+			if _, ok := workspaceModulePaths[require.Mod.Path]; ok {
+				continue
+			}
+			if version, ok := requires[require.Mod.Path]; !ok || semver.Compare(version, require.Mod.Version) < 0 {
+				requires[require.Mod.Path] = require.Mod.Version
+			}
+		}
+	}
+	for path, version := range requires {
+		modFile.AddNewRequire(path, version, false)
+	}
+	for _, replace := range workFile.Replace {
+		if replace.New.Version != "" {
+			if err := modFile.AddReplace(replace.Old.Path, replace.Old.Version, replace.New.Path, replace.New.Version); err != nil {
+				return nil, err
+			}
+		}
+	}
+	modFile.Cleanup()
+	return modFile.Format()
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	internalhttpproxy "github.com/go-mod-proxy/go-mod-proxy/internal/httpproxy"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/passwordhash"
 )
 
 type Access int
@@ -46,10 +47,34 @@ type AccessTokenAuthenticator struct {
 	TimeToLive time.Duration `yaml:"timeToLive"`
 }
 
+type AzureBlobStorage struct {
+	Account string `yaml:"account"`
+	// Endpoint is the base URL of the Blob service. Defaults to https://<account>.blob.core.windows.net.
+	Endpoint       string   `yaml:"endpoint"`
+	EndpointParsed *url.URL `yaml:"-"`
+	Container      string   `yaml:"container"`
+	// Exactly one of SASToken and SharedKey must be set.
+	SASToken *Secret `yaml:"sasToken"`
+	// SharedKey is the base64-encoded storage account key.
+	SharedKey        *Secret `yaml:"sharedKey"`
+	SharedKeyDecoded []byte  `yaml:"-"`
+}
+
+// BasicAuthenticator configures authentication of module requests by the name and password of an identity, sent in an
+// Authorization header with the Basic scheme.
+type BasicAuthenticator struct {
+	// CacheTimeToLive is the time for which a successful authentication of an identity is cached, so that its password
+	// does not have to be verified on every request. Defaults to 5 minutes.
+	CacheTimeToLive time.Duration `yaml:"cacheTimeToLive"`
+	// DisableCache disables caching successful authentications.
+	DisableCache bool `yaml:"disableCache"`
+}
+
 type ClientAuth struct {
 	AccessControlList []*AccessControlListElement `yaml:"acl"`
 	Authenticators    *struct {
 		AccessToken         *AccessTokenAuthenticator         `yaml:"accessToken"`
+		Basic               *BasicAuthenticator               `yaml:"basic"`
 		GCEInstanceIdentity *GCEInstanceIdentityAuthenticator `yaml:"gceInstanceIdentity"`
 	} `yaml:"authenticators"`
 	Enabled    bool        `yaml:"enabled"`
@@ -57,16 +82,33 @@ type ClientAuth struct {
 }
 
 type Config struct {
-	ClientAuth        ClientAuth               `yaml:"clientAuth"`
-	GitHub            []*GitHubInstance        `yaml:"gitHub"`
-	HTTPProxy         *HTTPProxy               `yaml:"httpProxy"`
-	MaxChildProcesses int                      `yaml:"maxChildProcesses"`
-	ParentProxy       ParentProxy              `yaml:"parentProxy"`
-	PrivateModules    []*PrivateModulesElement `yaml:"privateModules"`
-	PublicModules     PublicModules            `yaml:"publicModules"`
-	Storage           *Storage                 `yaml:"storage"`
-	SumDatabaseProxy  *SumDatabaseProxy        `yaml:"sumDatabaseProxy"`
-	TLS               *TLS                     `yaml:"tls"`
+	ClientAuth         ClientAuth               `yaml:"clientAuth"`
+	DownloadLease      *DownloadLease           `yaml:"downloadLease"`
+	FetchZipsLazily    bool                     `yaml:"fetchZipsLazily"`
+	GitHub             []*GitHubInstance        `yaml:"gitHub"`
+	GitHubWebhook      *GitHubWebhook           `yaml:"gitHubWebhook"`
+	HTTPProxy          *HTTPProxy               `yaml:"httpProxy"`
+	MaxChildProcesses  int                      `yaml:"maxChildProcesses"`
+	NegativeCache      *NegativeCache           `yaml:"negativeCache"`
+	ParentProxy        ParentProxy              `yaml:"parentProxy"`
+	PrivateModules     []*PrivateModulesElement `yaml:"privateModules"`
+	PublicModules      PublicModules            `yaml:"publicModules"`
+	Storage            *Storage                 `yaml:"storage"`
+	SumDatabaseProxy   *SumDatabaseProxy        `yaml:"sumDatabaseProxy"`
+	TLS                *TLS                     `yaml:"tls"`
+	VCSCache           *VCSCache                `yaml:"vcsCache"`
+	VerifyHashesOnRead bool                     `yaml:"verifyHashesOnRead"`
+	Warm               *Warm                    `yaml:"warm"`
+}
+
+// DownloadLease configures leases that prevent replicas from downloading the same module version at the same time.
+type DownloadLease struct {
+	// TimeToLive is the time after which the lease of a replica that died expires. Defaults to 1 minute.
+	TimeToLive time.Duration `yaml:"timeToLive"`
+}
+
+type FilesystemStorage struct {
+	Dir string `yaml:"dir"`
 }
 
 type GCEInstanceIdentityAuthenticator struct {
@@ -81,6 +123,15 @@ type GCSStorage struct {
 	Bucket string `yaml:"bucket"`
 }
 
+// GitCredentials are static credentials that git uses to authenticate to repositories via HTTPS.
+type GitCredentials struct {
+	// Host restricts the credentials to a host. If Host is empty then the credentials are used for hosts that are not the
+	// Host of other elements of the same PrivateModulesElementAuth.Credentials.
+	Host  string  `yaml:"host"`
+	Token *Secret `yaml:"token"`
+	User  string  `yaml:"user"`
+}
+
 type GitHubApp struct {
 	ID               int64           `yaml:"id"`
 	PrivateKey       *Secret         `yaml:"privateKey"`
@@ -93,6 +144,13 @@ type GitHubInstance struct {
 	isValid    bool         `yaml:"-"`
 }
 
+// GitHubWebhook configures the POST /webhooks/github endpoint, which receives the create and push events of tags of
+// repositories of private modules, so that new module versions are stored before they are first requested.
+type GitHubWebhook struct {
+	// Secret is the secret of the webhook, which is used to verify the X-Hub-Signature-256 headers of deliveries.
+	Secret *Secret `yaml:"secret"`
+}
+
 type HTTPProxy struct {
 	isValid       bool                       `yaml:"-"`
 	NoProxy       string                     `yaml:"noProxy"`
@@ -107,6 +165,25 @@ type Identity struct {
 	Name                       string                      `yaml:"name"`
 	GCEInstanceIdentityBinding *GCEInstanceIdentityBinding `yaml:"gceInstanceIdentityBinding"`
 	Password                   *Secret                     `yaml:"password"`
+	// PasswordHash is a bcrypt or argon2id hash of the password of the identity (see the hash-password command), which is
+	// an alternative to Password.
+	PasswordHash       string            `yaml:"passwordHash"`
+	PasswordHashParsed passwordhash.Hash `yaml:"-"`
+}
+
+// NegativeCache configures caching of the outcome that a module or module version does not exist, so that repeated
+// requests of it do not run a Go command (or send a request to the parent proxy) every time.
+type NegativeCache struct {
+	// MaxEntries bounds the number of outcomes cached in memory. Defaults to 10000.
+	MaxEntries int `yaml:"maxEntries"`
+	// PrivateModulesTimeToLive is the time for which outcomes of private modules are cached. Zero disables caching
+	// outcomes of private modules.
+	PrivateModulesTimeToLive time.Duration `yaml:"privateModulesTimeToLive"`
+	// PublicModulesTimeToLive is the time for which outcomes of public modules are cached. Zero disables caching outcomes
+	// of public modules.
+	PublicModulesTimeToLive time.Duration `yaml:"publicModulesTimeToLive"`
+	// ShareViaStorage enables sharing outcomes with other replicas through the storage.
+	ShareViaStorage bool `yaml:"shareViaStorage"`
 }
 
 type ParentProxy struct {
@@ -114,19 +191,77 @@ type ParentProxy struct {
 	URLParsed *url.URL `yaml:"-"`
 }
 
+// ListCache configures caching of the versions of a module and of the latest version of a module, as determined by Go
+// commands.
+type ListCache struct {
+	// StaleWhileRevalidate is the time after TimeToLive for which a cached result is still used, while it is revalidated
+	// in the background. Defaults to zero.
+	StaleWhileRevalidate time.Duration `yaml:"staleWhileRevalidate"`
+	// TimeToLive is the time for which a cached result is used without revalidating it.
+	TimeToLive time.Duration `yaml:"timeToLive"`
+}
+
 type PrivateModulesElement struct {
 	Auth           PrivateModulesElementAuth `yaml:"auth"`
 	isValid        bool                      `yaml:"-"`
+	ListCache      *ListCache                `yaml:"listCache"`
 	PathPrefix     string                    `yaml:"pathPrefix"`
 	PathPrefixHost string                    `yaml:"-"`
+	// RepoHost is the host of the repositories of the modules, which is the host of Vanity.RepoPathTemplate if Vanity is
+	// set and PathPrefixHost otherwise.
+	RepoHost string  `yaml:"-"`
+	Vanity   *Vanity `yaml:"vanity"`
+}
+
+// RepoModulePath maps modulePath, which must be matched by p.PathPrefix, to the path it would have if it were not a vanity
+// import path (see p.Vanity). For example, if p.PathPrefix is "go.corp.example" and p.Vanity.RepoPathTemplate is
+// "github.com/corp/{name}" then "go.corp.example/foo/sub" is mapped to "github.com/corp/foo/sub". Also returns the prefix
+// of modulePath that is the root of the repository (i.e. "go.corp.example/foo"). If p.Vanity is nil then modulePath is
+// returned as is and repoRoot is empty. ok is false if modulePath does not have a repository.
+func (p *PrivateModulesElement) RepoModulePath(modulePath string) (repoModulePath, repoRoot string, ok bool) {
+	if p.Vanity == nil {
+		return modulePath, "", true
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(modulePath, p.PathPrefix), "/")
+	if !strings.Contains(p.Vanity.RepoPathTemplate, VanityNamePlaceholder) {
+		return p.Vanity.RepoPathTemplate + strings.TrimPrefix(modulePath, p.PathPrefix), p.PathPrefix, true
+	}
+	name, rest, _ := strings.Cut(rest, "/")
+	if name == "" {
+		return "", "", false
+	}
+	repoModulePath = strings.Replace(p.Vanity.RepoPathTemplate, VanityNamePlaceholder, name, 1)
+	if rest != "" {
+		repoModulePath += "/" + rest
+	}
+	return repoModulePath, p.PathPrefix + "/" + name, true
 }
 
+// PrivateModulesElementAuth configures how Go commands authenticate to the repositories of private modules. Exactly one
+// of Credentials, GitHubApp and SSH must be set.
 type PrivateModulesElementAuth struct {
-	GitHubApp *int64 `yaml:"gitHubApp"`
+	// Credentials are static credentials, i.e. for repositories on GitLab, Bitbucket, Gitea or Azure DevOps.
+	Credentials []*GitCredentials `yaml:"credentials"`
+	GitHubApp   *int64            `yaml:"gitHubApp"`
+	SSH         *SSHAuth          `yaml:"ssh"`
 }
 
 type PublicModules struct {
-	SumDatabase *SumDatabaseElement `yaml:"sumDatabase"`
+	FetchInProcess bool                `yaml:"fetchInProcess"`
+	SumDatabase    *SumDatabaseElement `yaml:"sumDatabase"`
+}
+
+type S3Storage struct {
+	// AccessKeyID and SecretAccessKey must either both be set or both be nil. If both are nil then credentials are
+	// sourced from the environment variables AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	AccessKeyID     *Secret  `yaml:"accessKeyId"`
+	Bucket          string   `yaml:"bucket"`
+	Endpoint        string   `yaml:"endpoint"`
+	EndpointParsed  *url.URL `yaml:"-"`
+	Region          string   `yaml:"region"`
+	SecretAccessKey *Secret  `yaml:"secretAccessKey"`
+	SessionToken    *Secret  `yaml:"sessionToken"`
+	UsePathStyle    bool     `yaml:"usePathStyle"`
 }
 
 type Secret struct {
@@ -136,8 +271,52 @@ type Secret struct {
 	Plaintext []byte  `yaml:"-"`
 }
 
+// SSHAuth configures git to fetch repositories via SSH (instead of HTTPS), authenticating with a private key (i.e. a deploy
+// key).
+type SSHAuth struct {
+	// KnownHosts are lines of a known_hosts file (see sshd(8)) that pin the host keys of the SSH server.
+	KnownHosts []string `yaml:"knownHosts"`
+	// Port is the port of the SSH server. Defaults to 22.
+	Port int `yaml:"port"`
+	// PrivateKey is an unencrypted private key in a format supported by ssh(1) (i.e. "-----BEGIN OPENSSH PRIVATE
+	// KEY-----").
+	PrivateKey *Secret `yaml:"privateKey"`
+	// User is the user to log in as on the SSH server. Defaults to "git".
+	User string `yaml:"user"`
+}
+
 type Storage struct {
-	GCS *GCSStorage `yaml:"gcs"`
+	AzureBlob         *AzureBlobStorage         `yaml:"azureBlob"`
+	Cache             *StorageCache             `yaml:"cache"`
+	Filesystem        *FilesystemStorage        `yaml:"filesystem"`
+	GCS               *GCSStorage               `yaml:"gcs"`
+	S3                *S3Storage                `yaml:"s3"`
+	SignedURLRedirect *StorageSignedURLRedirect `yaml:"signedURLRedirect"`
+}
+
+// StorageCache configures a read-through cache in front of the storage. At least one of Disk and Memory must be set.
+type StorageCache struct {
+	// Disk caches .zip files.
+	Disk *StorageCacheDisk `yaml:"disk"`
+	// Memory caches .mod files and object metadata.
+	Memory *StorageCacheMemory `yaml:"memory"`
+}
+
+type StorageCacheDisk struct {
+	Dir      string `yaml:"dir"`
+	MaxBytes int64  `yaml:"maxBytes"`
+}
+
+type StorageCacheMemory struct {
+	MaxBytes       int64 `yaml:"maxBytes"`
+	MaxObjectBytes int64 `yaml:"maxObjectBytes"`
+}
+
+// StorageSignedURLRedirect configures redirecting .zip requests to signed URLs of the storage. Only GCS and S3 storage
+// support signed URLs.
+type StorageSignedURLRedirect struct {
+	// TimeToLive is the time after which signed URLs expire. Defaults to 5 minutes.
+	TimeToLive time.Duration `yaml:"timeToLive"`
 }
 
 type SumDatabaseElement struct {
@@ -185,3 +364,48 @@ func (t *TLSVersion) UnmarshalYAML(unmarshal func(any) error) error {
 	}
 	return nil
 }
+
+// VCSCache configures a cache of the version control system repositories of private modules that persists across Go
+// commands, so that a repository is not cloned again for every module version.
+type VCSCache struct {
+	// MaxBytes bounds the disk space used by the cache. Least recently used repositories are evicted first.
+	MaxBytes int64 `yaml:"maxBytes"`
+}
+
+// VanityNamePlaceholder is the placeholder of Vanity.RepoPathTemplate.
+const VanityNamePlaceholder = "{name}"
+
+// Vanity configures the modules of a PrivateModulesElement to have vanity import paths, i.e. "go.corp.example/foo" for a
+// module whose repository is "github.com/corp/foo". The Go command discovers the repository of a vanity import path via
+// go-import meta tags, which must be served by the host of the vanity import path. The server serves these meta tags if
+// the host of the vanity import path resolves to the server.
+type Vanity struct {
+	// GoSource optionally configures go-source meta tags (see https://github.com/golang/gddo/wiki/Source-Code-Links).
+	GoSource *VanityGoSource `yaml:"goSource"`
+	// PublicMetaTags is true if meta tags are served without client authentication. Go commands run by the server do not
+	// authenticate to the server, so PublicMetaTags must be true if client authentication is enabled and the host of the
+	// vanity import paths resolves to the server.
+	PublicMetaTags bool `yaml:"publicMetaTags"`
+	// RepoPathTemplate is the path of the repository of a module without scheme (i.e. "github.com/corp/{name}"), where
+	// "{name}" is replaced by the first element of the module path after PathPrefix. If RepoPathTemplate does not contain
+	// "{name}" then the modules are in the single repository RepoPathTemplate, whose root is PathPrefix.
+	RepoPathTemplate string `yaml:"repoPathTemplate"`
+}
+
+// VanityGoSource are the templates of the directory and file URLs of a go-source meta tag, in which "{name}" is replaced
+// like in Vanity.RepoPathTemplate (i.e. "https://github.com/corp/{name}/tree/main{/dir}").
+type VanityGoSource struct {
+	Directory string `yaml:"directory"`
+	File      string `yaml:"file"`
+}
+
+// Warm configures the POST /admin/warm endpoint, which pre-populates the caches of the server with the build lists of Go
+// modules.
+type Warm struct {
+	// Identities are the names of the identities that are allowed to use the endpoint.
+	Identities []string `yaml:"identities"`
+	// Parallelism is the maximum number of files fetched at the same time by warm-ups. Defaults to 4.
+	Parallelism int `yaml:"parallelism"`
+	// QueueSize is the maximum number of files waiting to be fetched by warm-ups. Defaults to 1000.
+	QueueSize int `yaml:"queueSize"`
+}
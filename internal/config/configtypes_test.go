@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrivateModulesElement_RepoModulePath(t *testing.T) {
+	assertRepoModulePath := func(p *PrivateModulesElement, modulePath, expectedRepoModulePath, expectedRepoRoot string,
+		expectedOK bool) {
+		t.Helper()
+		repoModulePath, repoRoot, ok := p.RepoModulePath(modulePath)
+		assert.Equal(t, expectedRepoModulePath, repoModulePath)
+		assert.Equal(t, expectedRepoRoot, repoRoot)
+		assert.Equal(t, expectedOK, ok)
+	}
+	p := &PrivateModulesElement{PathPrefix: "github.com/corp"}
+	assertRepoModulePath(p, "github.com/corp/foo", "github.com/corp/foo", "", true)
+	p = &PrivateModulesElement{
+		PathPrefix: "go.corp.example",
+		Vanity:     &Vanity{RepoPathTemplate: "github.com/corp/go-{name}"},
+	}
+	assertRepoModulePath(p, "go.corp.example/foo", "github.com/corp/go-foo", "go.corp.example/foo", true)
+	assertRepoModulePath(p, "go.corp.example/foo/sub/v2", "github.com/corp/go-foo/sub/v2", "go.corp.example/foo", true)
+	assertRepoModulePath(p, "go.corp.example", "", "", false)
+	p = &PrivateModulesElement{
+		PathPrefix: "go.corp.example/tools",
+		Vanity:     &Vanity{RepoPathTemplate: "github.com/corp/tools"},
+	}
+	assertRepoModulePath(p, "go.corp.example/tools", "github.com/corp/tools", "go.corp.example/tools", true)
+	assertRepoModulePath(p, "go.corp.example/tools/cmd", "github.com/corp/tools/cmd", "go.corp.example/tools", true)
+}
@@ -4,41 +4,66 @@ import (
 	"bytes"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	jasperurl "github.com/jbrekelmans/go-url"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/mod/module"
 	"gopkg.in/yaml.v2"
 
 	internalhttpproxy "github.com/go-mod-proxy/go-mod-proxy/internal/httpproxy"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/passwordhash"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
 )
 
 // LoadFromYAMLFile loads configuration from a YAML file.
 func LoadFromYAMLFile(file string) (*Config, error) {
+	var cfg *Config
+	err := loadFromYAMLFile(file, func(loader *Loader) (err error) {
+		cfg, err = loader.Run()
+		return
+	})
+	return cfg, err
+}
+
+// LoadStorageFromYAMLFile loads storage configuration from a YAML file. The YAML file has the same format as the value of
+// .storage of the YAML file loaded by LoadFromYAMLFile.
+func LoadStorageFromYAMLFile(file string) (*Storage, error) {
+	var storage *Storage
+	err := loadFromYAMLFile(file, func(loader *Loader) (err error) {
+		storage, err = loader.RunStorage()
+		return
+	})
+	return storage, err
+}
+
+func loadFromYAMLFile(file string, run func(loader *Loader) error) error {
 	fd, err := os.Open(file)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer fd.Close()
 	dir := filepath.Dir(file)
 	if dir == file {
-		return nil, fmt.Errorf("invalid file")
+		return fmt.Errorf("invalid file")
 	}
 	loader, err := NewLoader(fd, dir)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	cfg, err := loader.Run()
-	if err != nil {
-		return nil, fmt.Errorf("error loading %#v: %w", file, err)
+	if err := run(loader); err != nil {
+		return fmt.Errorf("error loading %#v: %w", file, err)
 	}
-	return cfg, nil
+	return nil
 }
 
 // Loader is a helper type to split up configuration loading into multiple functions.
@@ -91,6 +116,26 @@ func (l *Loader) Run() (*Config, error) {
 	return l.cfg, nil
 }
 
+// RunStorage loads storage configuration.
+func (l *Loader) RunStorage() (*Storage, error) {
+	if l.identityByName == nil {
+		return nil, fmt.Errorf("l must be created via NewLoader")
+	}
+	decoder := yaml.NewDecoder(l.reader)
+	decoder.SetStrict(true)
+	storage := &Storage{}
+	if err := decoder.Decode(storage); err != nil {
+		return nil, err
+	}
+	l.validateStorage(&validateValueContext{
+		errorBag: l.errors,
+	}, storage)
+	if err := l.errors.Err(); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
 func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 	vctxClientAuth := vctx.Child("clientAuth")
 	vctxIdentities := vctxClientAuth.Child("identities")
@@ -114,10 +159,26 @@ func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 				vctxIdentity.Child("password").AddError("effective value of secret must not be empty")
 			}
 		}
+		if identity.PasswordHash != "" {
+			vctxPasswordHash := vctxIdentity.Child("passwordHash")
+			if identity.Password != nil {
+				vctxIdentity.AddError(".password and .passwordHash must not both be set")
+			}
+			if hash, err := passwordhash.Parse(identity.PasswordHash); err != nil {
+				vctxPasswordHash.AddError(err.Error())
+			} else if err := hash.CheckStrength(); err != nil {
+				vctxPasswordHash.AddError(err.Error())
+			} else {
+				identity.PasswordHashParsed = hash
+			}
+		}
 		if b := identity.GCEInstanceIdentityBinding; b != nil {
 			vctxIdentity.Child("gceInstanceIdentityBinding").RequiredString(b.Email)
 		}
 	}
+	if cfg.ClientAuth.Authenticators != nil && cfg.ClientAuth.Authenticators.Basic != nil {
+		l.validateBasicAuthenticator(vctxClientAuth.Child("authenticators").Child("basic"), cfg.ClientAuth.Authenticators.Basic)
+	}
 	vctxAccessControlList := vctxClientAuth.Child("accessControlList")
 	for i, aclElem := range cfg.ClientAuth.AccessControlList {
 		if aclElem == nil {
@@ -127,6 +188,10 @@ func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 		}
 	}
 
+	if cfg.DownloadLease != nil {
+		l.validateDownloadLease(vctx.Child("downloadLease"), cfg.DownloadLease)
+	}
+
 	gitHubInstanceIndex := map[string]int{}
 	for i, gitHubInstance := range cfg.GitHub {
 		if gitHubInstance == nil {
@@ -145,6 +210,9 @@ func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 		}
 	}
 
+	if cfg.GitHubWebhook != nil {
+		l.validateGitHubWebhook(vctx.Child("gitHubWebhook"), cfg.GitHubWebhook)
+	}
 	if cfg.HTTPProxy != nil {
 		l.validateHTTPProxy(vctx.Child("httpProxy"), cfg.HTTPProxy)
 	}
@@ -153,15 +221,18 @@ func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 	} else if cfg.MaxChildProcesses < 0 {
 		vctx.Child("maxChildProcesses").AddErrorf("value must not be negative")
 	}
+	if cfg.NegativeCache != nil {
+		l.validateNegativeCache(vctx.Child("negativeCache"), cfg.NegativeCache)
+	}
 	l.validateParentProxy(vctx.Child("parentProxy"), &l.cfg.ParentProxy)
 	l.validatePrivateModules(vctx.Child("privateModules"), l.cfg.PrivateModules)
 	for i, privateModulesElement := range l.cfg.PrivateModules {
 		if privateModulesElement != nil && privateModulesElement.isValid {
 			if privateModulesElement.Auth.GitHubApp != nil {
-				j := gitHubInstanceIndex[privateModulesElement.PathPrefixHost]
+				j := gitHubInstanceIndex[privateModulesElement.RepoHost]
 				if j == 0 {
 					vctx.AddErrorf(`.privateModules[%d].auth.gitHubApp != null but .gitHub has no element with .host equal to `+
-						`the host of .privateModules[%d].pathPrefix (%#v)`, i, i, privateModulesElement.PathPrefixHost)
+						`the host of the repositories of .privateModules[%d] (%#v)`, i, i, privateModulesElement.RepoHost)
 				} else if j > 0 {
 					gitHubInstance := l.cfg.GitHub[j-1]
 					if gitHubInstance.isValid {
@@ -175,8 +246,8 @@ func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 						if !found {
 							vctx.AddErrorf(`.privateModules[%d] configures GitHub App based authentication on host %#v `+
 								`and .gitHub[%d] has .host %#v but .gitHub[%d].gitHubApps has no element with .id equal to `+
-								`.privateModules[%d].auth.gitHubApp = %d`, i, privateModulesElement.PathPrefixHost, j-1,
-								privateModulesElement.PathPrefixHost, j-1, i, *privateModulesElement.Auth.GitHubApp)
+								`.privateModules[%d].auth.gitHubApp = %d`, i, privateModulesElement.RepoHost, j-1,
+								privateModulesElement.RepoHost, j-1, i, *privateModulesElement.Auth.GitHubApp)
 						}
 					}
 				}
@@ -190,12 +261,26 @@ func (l *Loader) validateConfig(vctx *validateValueContext, cfg *Config) {
 		vctx.Child("storage").AddRequiredError()
 	} else {
 		l.validateStorage(vctx.Child("storage"), l.cfg.Storage)
+		if l.cfg.Storage.SignedURLRedirect != nil && l.cfg.VerifyHashesOnRead {
+			// Zips downloaded via signed URLs bypass the server, so they cannot be verified.
+			vctx.AddError(".storage.signedURLRedirect must be null if .verifyHashesOnRead is true")
+		}
 	}
 	if l.cfg.SumDatabaseProxy == nil {
 		vctx.Child("sumDatabaseProxy").AddRequiredError()
 	} else {
 		l.validateSumDatabaseProxy(vctx.Child("sumDatabaseProxy"), l.cfg.SumDatabaseProxy)
 	}
+	if l.cfg.VCSCache != nil && l.cfg.VCSCache.MaxBytes <= 0 {
+		vctx.Child("vcsCache").Child("maxBytes").AddError("value must be set (to a positive integer)")
+	}
+	if l.cfg.Warm != nil {
+		if !l.cfg.ClientAuth.Enabled {
+			vctx.Child("warm").AddError("value must be null if .clientAuth.enabled is false")
+		} else {
+			l.validateWarm(vctx.Child("warm"), l.cfg.Warm)
+		}
+	}
 }
 
 func (l *Loader) validateAccessControlListElement(vctx *validateValueContext, aclElem *AccessControlListElement) {
@@ -217,12 +302,99 @@ func (l *Loader) validateAccessControlListElement(vctx *validateValueContext, ac
 	}
 }
 
+func (l *Loader) validateAzureBlobStorage(vctx *validateValueContext, azureBlob *AzureBlobStorage) {
+	vctx.Child("account").RequiredString(azureBlob.Account)
+	vctx.Child("container").RequiredString(azureBlob.Container)
+	if azureBlob.Endpoint != "" {
+		var err error
+		azureBlob.EndpointParsed, err = jasperurl.ValidateURL(azureBlob.Endpoint, jasperurl.ValidateURLOptions{
+			Abs:                                      jasperurl.NewBool(true),
+			AllowedSchemes:                           []string{"http", "https"},
+			NormalizePort:                            new(bool),
+			StripFragment:                            true,
+			StripQuery:                               true,
+			StripPathTrailingSlashes:                 true,
+			StripPathTrailingSlashesNoPercentEncoded: true,
+			User:                                     new(bool),
+		})
+		if err != nil {
+			vctx.Child("endpoint").AddErrorf("value is not a valid URL: %v", err)
+		}
+	}
+	if (azureBlob.SASToken == nil) == (azureBlob.SharedKey == nil) {
+		vctx.AddError("exactly one of .sasToken and .sharedKey must be set (to a non-null value)")
+	}
+	if azureBlob.SASToken != nil {
+		l.validateSecret(vctx.Child("sasToken"), azureBlob.SASToken)
+	}
+	if azureBlob.SharedKey != nil {
+		vctxSharedKey := vctx.Child("sharedKey")
+		l.validateSecret(vctxSharedKey, azureBlob.SharedKey)
+		if azureBlob.SharedKey.isValid {
+			var err error
+			azureBlob.SharedKeyDecoded, err = base64.StdEncoding.DecodeString(
+				strings.TrimSpace(string(azureBlob.SharedKey.Plaintext)))
+			if err != nil {
+				vctxSharedKey.AddErrorf("effective secret value is not valid base64: %v", err)
+			} else if len(azureBlob.SharedKeyDecoded) == 0 {
+				vctxSharedKey.AddError("effective secret value must not be empty")
+			}
+		}
+	}
+}
+
+func (l *Loader) validateBasicAuthenticator(vctx *validateValueContext, basic *BasicAuthenticator) {
+	if basic.CacheTimeToLive == 0 {
+		basic.CacheTimeToLive = 5 * time.Minute
+	} else if basic.CacheTimeToLive < 0 {
+		vctx.Child("cacheTimeToLive").AddError("value must not be negative")
+	}
+}
+
+func (l *Loader) validateDownloadLease(vctx *validateValueContext, downloadLease *DownloadLease) {
+	if downloadLease.TimeToLive == 0 {
+		downloadLease.TimeToLive = time.Minute
+	} else if downloadLease.TimeToLive < time.Second {
+		vctx.Child("timeToLive").AddError("value must be at least 1s")
+	}
+}
+
+func (l *Loader) validateFilesystemStorage(vctx *validateValueContext, filesystem *FilesystemStorage) {
+	if filesystem.Dir == "" {
+		vctx.AddError(".dir must not be empty")
+	} else {
+		filesystem.Dir = l.resolveFile(filesystem.Dir)
+	}
+}
+
 func (l *Loader) validateGCSStorage(vctx *validateValueContext, gcs *GCSStorage) {
 	if gcs.Bucket == "" {
 		vctx.AddError(".bucket must not be empty")
 	}
 }
 
+func (l *Loader) validateGitCredentials(vctx *validateValueContext, gitCredentials *GitCredentials) {
+	if strings.ContainsAny(gitCredentials.Host, "/\n") {
+		vctx.Child("host").AddError(`value must be a host (and must not contain "/")`)
+	}
+	if vctx.Child("user").RequiredString(gitCredentials.User) && strings.ContainsAny(gitCredentials.User, "\n") {
+		vctx.Child("user").AddError("value must not contain a line feed character")
+	}
+	vctxToken := vctx.Child("token")
+	if gitCredentials.Token == nil {
+		vctxToken.AddRequiredError()
+		return
+	}
+	l.validateSecret(vctxToken, gitCredentials.Token)
+	if gitCredentials.Token.isValid {
+		if len(gitCredentials.Token.Plaintext) == 0 {
+			vctxToken.AddError("effective value of secret must not be empty")
+		} else if bytes.ContainsAny(gitCredentials.Token.Plaintext, "\n") {
+			vctxToken.AddError("effective value of secret must not contain a line feed character")
+		}
+	}
+}
+
 func (l *Loader) validateGitHubInstance(vctx *validateValueContext, gitHubInstance *GitHubInstance) {
 	n := vctx.ErrorCount()
 	vctx.RequiredString(gitHubInstance.Host)
@@ -255,6 +427,18 @@ func (l *Loader) validateGitHubInstance(vctx *validateValueContext, gitHubInstan
 	gitHubInstance.isValid = n == vctx.ErrorCount()
 }
 
+func (l *Loader) validateGitHubWebhook(vctx *validateValueContext, gitHubWebhook *GitHubWebhook) {
+	vctxSecret := vctx.Child("secret")
+	if gitHubWebhook.Secret == nil {
+		vctxSecret.AddRequiredError()
+		return
+	}
+	l.validateSecret(vctxSecret, gitHubWebhook.Secret)
+	if gitHubWebhook.Secret.isValid && len(gitHubWebhook.Secret.Plaintext) == 0 {
+		vctxSecret.AddError("effective value of secret must not be empty")
+	}
+}
+
 func (l *Loader) validateHTTPProxy(vctx *validateValueContext, httpProxy *HTTPProxy) {
 	n := l.errors.ErrorCount()
 	if vctx.Child("url").RequiredString(httpProxy.URL) {
@@ -299,6 +483,32 @@ func (l *Loader) validateHTTPProxy(vctx *validateValueContext, httpProxy *HTTPPr
 	}
 }
 
+func (l *Loader) validateListCache(vctx *validateValueContext, listCache *ListCache) {
+	if listCache.StaleWhileRevalidate < 0 {
+		vctx.Child("staleWhileRevalidate").AddError("value must not be negative")
+	}
+	if listCache.TimeToLive <= 0 {
+		vctx.Child("timeToLive").AddError("value must be positive")
+	}
+}
+
+func (l *Loader) validateNegativeCache(vctx *validateValueContext, negativeCache *NegativeCache) {
+	if negativeCache.MaxEntries == 0 {
+		negativeCache.MaxEntries = 10000
+	} else if negativeCache.MaxEntries < 0 {
+		vctx.Child("maxEntries").AddError("value must not be negative")
+	}
+	if negativeCache.PrivateModulesTimeToLive < 0 {
+		vctx.Child("privateModulesTimeToLive").AddError("value must not be negative")
+	}
+	if negativeCache.PublicModulesTimeToLive < 0 {
+		vctx.Child("publicModulesTimeToLive").AddError("value must not be negative")
+	}
+	if negativeCache.PrivateModulesTimeToLive == 0 && negativeCache.PublicModulesTimeToLive == 0 {
+		vctx.AddError("at least one of .privateModulesTimeToLive and .publicModulesTimeToLive must be positive")
+	}
+}
+
 func (l *Loader) validateParentProxy(vctx *validateValueContext, parentProxy *ParentProxy) {
 	var err error
 	parentProxy.URLParsed, err = jasperurl.ValidateURL(parentProxy.URL, jasperurl.ValidateURLOptions{
@@ -340,8 +550,39 @@ func (l *Loader) validatePrivateModules(vctx *validateValueContext, privateModul
 
 func (l *Loader) validatePrivateModulesElement(vctx *validateValueContext, privateModulesElement *PrivateModulesElement) {
 	n := vctx.ErrorCount()
-	if privateModulesElement.Auth.GitHubApp == nil {
-		vctx.Child("auth").AddError(".gitHubApp must be set (to a non-null value)")
+	auth := &privateModulesElement.Auth
+	x := 0
+	if len(auth.Credentials) > 0 {
+		x++
+	}
+	if auth.GitHubApp != nil {
+		x++
+	}
+	if auth.SSH != nil {
+		x++
+		l.validateSSHAuth(vctx.Child("auth").Child("ssh"), auth.SSH)
+	}
+	if x != 1 {
+		vctx.Child("auth").AddError("exactly one of .credentials, .gitHubApp and .ssh must be set (to a non-null value)")
+	}
+	vctxCredentials := vctx.Child("auth").Child("credentials")
+	credentialsIndex := map[string]int{}
+	for i, gitCredentials := range auth.Credentials {
+		if gitCredentials == nil {
+			vctxCredentials.Child(i).AddRequiredError()
+			continue
+		}
+		l.validateGitCredentials(vctxCredentials.Child(i), gitCredentials)
+		if state := credentialsIndex[gitCredentials.Host]; state == 0 {
+			credentialsIndex[gitCredentials.Host] = i + 1
+		} else if state > 0 {
+			vctxCredentials.AddErrorf("no two elements must have the same .host but [%d] and [%d] have .host %#v", i,
+				state-1, gitCredentials.Host)
+			credentialsIndex[gitCredentials.Host] = -1
+		}
+	}
+	if privateModulesElement.ListCache != nil {
+		l.validateListCache(vctx.Child("listCache"), privateModulesElement.ListCache)
 	}
 	if privateModulesElement.PathPrefix == "" {
 		vctx.Child("pathPrefix").AddError("value must be set (to a non-empty string)")
@@ -356,6 +597,12 @@ func (l *Loader) validatePrivateModulesElement(vctx *validateValueContext, priva
 			host = privateModulesElement.PathPrefix
 		}
 		privateModulesElement.PathPrefixHost = host
+		privateModulesElement.RepoHost = host
+	}
+	if privateModulesElement.Vanity != nil {
+		l.validateVanity(vctx.Child("vanity"), privateModulesElement.Vanity)
+		repoHost, _, _ := strings.Cut(privateModulesElement.Vanity.RepoPathTemplate, "/")
+		privateModulesElement.RepoHost = repoHost
 	}
 	privateModulesElement.isValid = n == vctx.ErrorCount()
 }
@@ -406,11 +653,109 @@ func (l *Loader) validateRSAPrivateKey(vctx *validateValueContext, bytes []byte)
 }
 
 func (l *Loader) validateStorage(vctx *validateValueContext, storage *Storage) {
-	if storage.GCS == nil {
-		vctx.AddError(".gcs must be set (to a non-null value)")
-	} else {
+	x := 0
+	if storage.AzureBlob != nil {
+		x++
+		l.validateAzureBlobStorage(vctx.Child("azureBlob"), storage.AzureBlob)
+	}
+	if storage.Filesystem != nil {
+		x++
+		l.validateFilesystemStorage(vctx.Child("filesystem"), storage.Filesystem)
+	}
+	if storage.GCS != nil {
+		x++
 		l.validateGCSStorage(vctx.Child("gcs"), storage.GCS)
 	}
+	if storage.S3 != nil {
+		x++
+		l.validateS3Storage(vctx.Child("s3"), storage.S3)
+	}
+	if x != 1 {
+		vctx.AddError("exactly one of .azureBlob, .filesystem, .gcs and .s3 must be set (to a non-null value)")
+	}
+	if storage.Cache != nil {
+		l.validateStorageCache(vctx.Child("cache"), storage.Cache)
+	}
+	if storage.SignedURLRedirect != nil {
+		if storage.GCS == nil && storage.S3 == nil {
+			vctx.Child("signedURLRedirect").AddError("value must be null unless .gcs or .s3 is set (to a non-null value)")
+		}
+		l.validateStorageSignedURLRedirect(vctx.Child("signedURLRedirect"), storage.SignedURLRedirect)
+	}
+}
+
+func (l *Loader) validateStorageCache(vctx *validateValueContext, cache *StorageCache) {
+	if cache.Disk == nil && cache.Memory == nil {
+		vctx.AddError("at least one of .disk and .memory must be set (to a non-null value)")
+	}
+	if disk := cache.Disk; disk != nil {
+		vctxDisk := vctx.Child("disk")
+		if disk.Dir == "" {
+			vctxDisk.AddError(".dir must not be empty")
+		} else {
+			disk.Dir = l.resolveFile(disk.Dir)
+		}
+		if disk.MaxBytes <= 0 {
+			vctxDisk.Child("maxBytes").AddError("value must be set (to a positive integer)")
+		}
+	}
+	if memory := cache.Memory; memory != nil {
+		vctxMemory := vctx.Child("memory")
+		if memory.MaxBytes <= 0 {
+			vctxMemory.Child("maxBytes").AddError("value must be set (to a positive integer)")
+		}
+		if memory.MaxObjectBytes <= 0 {
+			vctxMemory.Child("maxObjectBytes").AddError("value must be set (to a positive integer)")
+		} else if memory.MaxBytes > 0 && memory.MaxObjectBytes > memory.MaxBytes {
+			vctxMemory.Child("maxObjectBytes").AddError("value must not be greater than .maxBytes")
+		}
+	}
+}
+
+func (l *Loader) validateStorageSignedURLRedirect(vctx *validateValueContext, signedURLRedirect *StorageSignedURLRedirect) {
+	// Signed URLs of GCS and S3 expire after at most 7 days.
+	const maxTimeToLive = 7 * 24 * time.Hour
+	if signedURLRedirect.TimeToLive == 0 {
+		signedURLRedirect.TimeToLive = 5 * time.Minute
+	} else if signedURLRedirect.TimeToLive < time.Second || signedURLRedirect.TimeToLive > maxTimeToLive {
+		vctx.Child("timeToLive").AddErrorf("value must be at least 1s and at most %s", maxTimeToLive)
+	}
+}
+
+func (l *Loader) validateS3Storage(vctx *validateValueContext, s3 *S3Storage) {
+	vctx.Child("bucket").RequiredString(s3.Bucket)
+	vctx.Child("region").RequiredString(s3.Region)
+	if s3.Endpoint != "" {
+		var err error
+		s3.EndpointParsed, err = jasperurl.ValidateURL(s3.Endpoint, jasperurl.ValidateURLOptions{
+			Abs:                                      jasperurl.NewBool(true),
+			AllowedSchemes:                           []string{"http", "https"},
+			NormalizePort:                            new(bool),
+			StripFragment:                            true,
+			StripQuery:                               true,
+			StripPathTrailingSlashes:                 true,
+			StripPathTrailingSlashesNoPercentEncoded: true,
+			User:                                     new(bool),
+		})
+		if err != nil {
+			vctx.Child("endpoint").AddErrorf("value is not a valid URL: %v", err)
+		}
+	}
+	if (s3.AccessKeyID == nil) != (s3.SecretAccessKey == nil) {
+		vctx.AddError("either .accessKeyId and .secretAccessKey must both be set (to a non-null value) or neither")
+	}
+	if s3.AccessKeyID != nil {
+		l.validateSecret(vctx.Child("accessKeyId"), s3.AccessKeyID)
+	}
+	if s3.SecretAccessKey != nil {
+		l.validateSecret(vctx.Child("secretAccessKey"), s3.SecretAccessKey)
+	}
+	if s3.SessionToken != nil {
+		if s3.AccessKeyID == nil {
+			vctx.Child("sessionToken").AddError("value must not be set (to a non-null value) if .accessKeyId is not set")
+		}
+		l.validateSecret(vctx.Child("sessionToken"), s3.SessionToken)
+	}
 }
 
 func (l *Loader) validateSecret(vctx *validateValueContext, secret *Secret) {
@@ -441,6 +786,46 @@ func (l *Loader) validateSecret(vctx *validateValueContext, secret *Secret) {
 	secret.isValid = vctx.ErrorCount() == n
 }
 
+func (l *Loader) validateSSHAuth(vctx *validateValueContext, sshAuth *SSHAuth) {
+	vctxKnownHosts := vctx.Child("knownHosts")
+	if len(sshAuth.KnownHosts) == 0 {
+		vctxKnownHosts.AddError("value must be set (to a non-empty list)")
+	}
+	for i, line := range sshAuth.KnownHosts {
+		if strings.ContainsAny(line, "\n") {
+			vctxKnownHosts.Child(i).AddError("value must not contain a line feed character")
+		} else if _, _, _, _, _, err := ssh.ParseKnownHosts([]byte(line)); err != nil {
+			vctxKnownHosts.Child(i).AddErrorf("value must be a line of a known_hosts file that has a host key: %v", err)
+		}
+	}
+	if sshAuth.Port == 0 {
+		sshAuth.Port = 22
+	} else if sshAuth.Port < 0 || sshAuth.Port > 65535 {
+		vctx.Child("port").AddError("value must be a port number")
+	}
+	vctxPrivateKey := vctx.Child("privateKey")
+	if sshAuth.PrivateKey == nil {
+		vctxPrivateKey.AddRequiredError()
+	} else {
+		l.validateSecret(vctxPrivateKey, sshAuth.PrivateKey)
+		if sshAuth.PrivateKey.isValid {
+			if _, err := ssh.ParseRawPrivateKey(sshAuth.PrivateKey.Plaintext); err != nil {
+				var passphraseMissingError *ssh.PassphraseMissingError
+				if errors.As(err, &passphraseMissingError) {
+					vctxPrivateKey.AddError("effective value of secret must be an unencrypted private key")
+				} else {
+					vctxPrivateKey.AddErrorf("effective value of secret must be a private key: %v", err)
+				}
+			}
+		}
+	}
+	if sshAuth.User == "" {
+		sshAuth.User = "git"
+	} else if strings.ContainsAny(sshAuth.User, "@/:\n") {
+		vctx.Child("user").AddError(`value must not contain "@", "/", ":" or a line feed character`)
+	}
+}
+
 func (l *Loader) validateSumDatabaseElement(vctx *validateValueContext, sumDBElement *SumDatabaseElement) {
 	n := vctx.ErrorCount()
 	var err error
@@ -494,3 +879,55 @@ func (l *Loader) validateSumDatabaseProxy(vctx *validateValueContext, sumDatabas
 		}
 	}
 }
+
+func (l *Loader) validateVanity(vctx *validateValueContext, vanity *Vanity) {
+	vctxRepoPathTemplate := vctx.Child("repoPathTemplate")
+	if !vctxRepoPathTemplate.RequiredString(vanity.RepoPathTemplate) {
+		return
+	}
+	repoPath := vanity.RepoPathTemplate
+	if i := strings.Index(repoPath, VanityNamePlaceholder); i >= 0 {
+		if strings.Contains(repoPath[i+len(VanityNamePlaceholder):], "/") {
+			vctxRepoPathTemplate.AddErrorf("%s must be in the last element of value", VanityNamePlaceholder)
+			return
+		}
+		repoPath = strings.Replace(repoPath, VanityNamePlaceholder, "name", 1)
+	}
+	if err := module.CheckPath(repoPath); err != nil {
+		vctxRepoPathTemplate.AddErrorf(`value must be a path of a repository without scheme (i.e. "github.com/corp/%s"): %v`,
+			VanityNamePlaceholder, err)
+	}
+	if vanity.GoSource != nil {
+		vctxGoSource := vctx.Child("goSource")
+		if vctxGoSource.Child("directory").RequiredString(vanity.GoSource.Directory) &&
+			strings.ContainsAny(vanity.GoSource.Directory, " \t\n") {
+			vctxGoSource.Child("directory").AddError("value must not contain whitespace")
+		}
+		if vctxGoSource.Child("file").RequiredString(vanity.GoSource.File) &&
+			strings.ContainsAny(vanity.GoSource.File, " \t\n") {
+			vctxGoSource.Child("file").AddError("value must not contain whitespace")
+		}
+	}
+}
+
+func (l *Loader) validateWarm(vctx *validateValueContext, warm *Warm) {
+	vctxIdentities := vctx.Child("identities")
+	if len(warm.Identities) == 0 {
+		vctxIdentities.AddRequiredError()
+	}
+	for i, name := range warm.Identities {
+		if l.identityByName[name] == nil {
+			vctxIdentities.Child(i).AddErrorf(`value (%#v) names an identity that has not been defined in .clientAuth.identities`, name)
+		}
+	}
+	if warm.Parallelism == 0 {
+		warm.Parallelism = 4
+	} else if warm.Parallelism < 0 {
+		vctx.Child("parallelism").AddError("value must not be negative")
+	}
+	if warm.QueueSize == 0 {
+		warm.QueueSize = 1000
+	} else if warm.QueueSize < 0 {
+		vctx.Child("queueSize").AddError("value must not be negative")
+	}
+}
@@ -0,0 +1,104 @@
+// Package lru implements a least-recently-used cache bounded by the sum of the sizes of its entries.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+type lruEntry struct {
+	key   string
+	size  int64
+	value any
+}
+
+// Cache is a concurrency-safe least-recently-used cache bounded by the sum of the sizes of its entries.
+type Cache struct {
+	entries map[string]*list.Element
+	list    *list.List
+	maxSize int64
+	mu      sync.Mutex
+	// onEvict, if not nil, is called (without holding mu) for each entry that is evicted to make room for another entry.
+	onEvict func(key string, value any)
+	size    int64
+}
+
+// New creates a new Cache. The sum of the sizes of the entries of the Cache is at most maxSize.
+func New(maxSize int64, onEvict func(key string, value any)) *Cache {
+	return &Cache{
+		entries: map[string]*list.Element{},
+		list:    list.New(),
+		maxSize: maxSize,
+		onEvict: onEvict,
+	}
+}
+
+// Add adds or replaces the entry with key key and marks it as most recently used. Entries larger than the maximum size
+// are not added. Returns false if the entry was not added.
+func (l *Cache) Add(key string, value any, size int64) bool {
+	if size > l.maxSize {
+		return false
+	}
+	var evicted []*lruEntry
+	l.mu.Lock()
+	if elem := l.entries[key]; elem != nil {
+		entry := elem.Value.(*lruEntry)
+		l.size -= entry.size
+		entry.size = size
+		entry.value = value
+		l.list.MoveToFront(elem)
+	} else {
+		l.entries[key] = l.list.PushFront(&lruEntry{
+			key:   key,
+			size:  size,
+			value: value,
+		})
+	}
+	l.size += size
+	for l.size > l.maxSize {
+		entry := l.removeElement(l.list.Back())
+		evicted = append(evicted, entry)
+	}
+	l.mu.Unlock()
+	if l.onEvict != nil {
+		for _, entry := range evicted {
+			l.onEvict(entry.key, entry.value)
+		}
+	}
+	return true
+}
+
+// Get returns the value of the entry with key key and marks the entry as most recently used.
+func (l *Cache) Get(key string) (any, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elem := l.entries[key]
+	if elem == nil {
+		return nil, false
+	}
+	l.list.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Remove removes the entry with key key, if any. onEvict is not called.
+func (l *Cache) Remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if elem := l.entries[key]; elem != nil {
+		l.removeElement(elem)
+	}
+}
+
+// Size returns the sum of the sizes of all entries.
+func (l *Cache) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+func (l *Cache) removeElement(elem *list.Element) *lruEntry {
+	entry := l.list.Remove(elem).(*lruEntry)
+	delete(l.entries, entry.key)
+	l.size -= entry.size
+	return entry
+}
@@ -0,0 +1,25 @@
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Cache(t *testing.T) {
+	var evicted []string
+	l := New(3, func(key string, _ any) {
+		evicted = append(evicted, key)
+	})
+	assert.True(t, l.Add("a", 1, 1))
+	assert.True(t, l.Add("b", 2, 1))
+	assert.True(t, l.Add("c", 3, 1))
+	_, ok := l.Get("a")
+	assert.True(t, ok)
+	assert.True(t, l.Add("d", 4, 1))
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.False(t, l.Add("e", 5, 4))
+	assert.True(t, l.Add("a", 1, 3))
+	assert.Equal(t, []string{"b", "c", "d"}, evicted)
+	assert.Equal(t, int64(3), l.Size())
+}
@@ -45,6 +45,14 @@ func doRequestCommon(ctx context.Context, baseURL string, client *http.Client, m
 	return resp, nil
 }
 
+func getVersionURLSuffix(version, ext string) (string, error) {
+	versionEscaped, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("version is invalid: %v", err)
+	}
+	return "/@v/" + url.PathEscape(versionEscaped) + ext, nil
+}
+
 func getURL(baseURL, modulePath, suffix string) (string, error) {
 	var sb strings.Builder
 	sb.Grow(len(baseURL) + len(modulePath) + len(suffix))
@@ -0,0 +1,35 @@
+package modproxyclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	module "golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// GoMod does a GET $baseURL/<module>/@v/<version>.mod request and returns the response body. moduleVersion.Version must
+// be canonical. GoMod returns an error if the body is larger than "golang.org/x/mod/zip".MaxGoMod bytes.
+func GoMod(ctx context.Context, baseURL string, client *http.Client, moduleVersion *module.Version) ([]byte, error) {
+	urlSuffix, err := getVersionURLSuffix(moduleVersion.Version, ".mod")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doRequestCommon(ctx, baseURL, client, moduleVersion.Path, urlSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, modzip.MaxGoMod+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading body of %d-response to %s %s: %v", resp.StatusCode,
+			resp.Request.Method, resp.Request.URL.String(), err)
+	}
+	if len(data) > modzip.MaxGoMod {
+		return nil, fmt.Errorf("body of %d-response to %s %s is larger than %d bytes", resp.StatusCode,
+			resp.Request.Method, resp.Request.URL.String(), modzip.MaxGoMod)
+	}
+	return data, nil
+}
@@ -0,0 +1,32 @@
+package modproxyclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	module "golang.org/x/mod/module"
+
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+// Info does a GET $baseURL/<module>/@v/<version>.info request. moduleVersion.Version need not be canonical, in which case
+// the server resolves the version (i.e. a branch name).
+func Info(ctx context.Context, baseURL string, client *http.Client, moduleVersion *module.Version) (*gomoduleservice.Info, error) {
+	urlSuffix, err := getVersionURLSuffix(moduleVersion.Version, ".info")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doRequestCommon(ctx, baseURL, client, moduleVersion.Path, urlSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	x := &gomoduleservice.Info{}
+	if err := util.UnmarshalJSON(resp.Body, x, false); err != nil {
+		return nil, fmt.Errorf("error unmarshalling body of %d-response to %s %s: %v", resp.StatusCode,
+			resp.Request.Method, resp.Request.URL.String(), err)
+	}
+	return x, nil
+}
@@ -0,0 +1,39 @@
+package modproxyclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	module "golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// Zip does a GET $baseURL/<module>/@v/<version>.zip request and copies the response body to w. moduleVersion.Version
+// must be canonical. Zip returns an error if the body is larger than "golang.org/x/mod/zip".MaxZipFile bytes.
+func Zip(ctx context.Context, baseURL string, client *http.Client, moduleVersion *module.Version, w io.Writer) (int64, error) {
+	urlSuffix, err := getVersionURLSuffix(moduleVersion.Version, ".zip")
+	if err != nil {
+		return 0, err
+	}
+	resp, err := doRequestCommon(ctx, baseURL, client, moduleVersion.Path, urlSuffix)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength > modzip.MaxZipFile {
+		return 0, fmt.Errorf("body of %d-response to %s %s is larger than %d bytes", resp.StatusCode,
+			resp.Request.Method, resp.Request.URL.String(), modzip.MaxZipFile)
+	}
+	n, err := io.Copy(w, io.LimitReader(resp.Body, modzip.MaxZipFile+1))
+	if err != nil {
+		return n, fmt.Errorf("error reading body of %d-response to %s %s: %v", resp.StatusCode,
+			resp.Request.Method, resp.Request.URL.String(), err)
+	}
+	if n > modzip.MaxZipFile {
+		return n, fmt.Errorf("body of %d-response to %s %s is larger than %d bytes", resp.StatusCode,
+			resp.Request.Method, resp.Request.URL.String(), modzip.MaxZipFile)
+	}
+	return n, nil
+}
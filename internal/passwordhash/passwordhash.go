@@ -0,0 +1,201 @@
+// Package passwordhash implements password hashes, so that passwords do not have to be stored in plaintext. Two formats
+// are supported:
+//   - argon2id in the PHC string format: $argon2id$v=19$m=<memory in KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>,
+//     where salt and key are encoded with unpadded standard base64.
+//   - bcrypt in the modular crypt format: $2a$, $2b$ or $2y$ followed by the cost and the salt and hash.
+package passwordhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Parameters of argon2id hashes generated by GenerateArgon2id (see the second recommended option of
+// https://www.rfc-editor.org/rfc/rfc9106#section-4).
+const (
+	DefaultArgon2idIterations  = 3
+	DefaultArgon2idKeyLength   = 32
+	DefaultArgon2idMemory      = 64 * 1024
+	DefaultArgon2idParallelism = 4
+	DefaultArgon2idSaltLength  = 16
+)
+
+// DefaultBcryptCost is the cost of bcrypt hashes generated by GenerateBcrypt if no cost is given.
+const DefaultBcryptCost = 12
+
+// Minimum parameters of hashes accepted by Hash.CheckStrength (see
+// https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html).
+const (
+	MinArgon2idIterations = 2
+	MinArgon2idKeyLength  = 16
+	MinArgon2idMemory     = 19 * 1024
+	MinArgon2idSaltLength = 16
+	MinBcryptCost         = 10
+)
+
+// Maximum parameters of hashes accepted by Parse, which bound the memory and time used by Hash.Verify. Verifying an
+// argon2id hash uses m KiB of memory.
+const (
+	MaxArgon2idIterations  = 16
+	MaxArgon2idMemory      = 256 * 1024
+	MaxArgon2idParallelism = 16
+	MaxBcryptCost          = 16
+)
+
+const (
+	prefixArgon2id = "$argon2id$"
+)
+
+// Hash is a parsed password hash.
+type Hash interface {
+	// CheckStrength returns an error if the parameters of the hash are weaker than the minimum parameters of its
+	// algorithm.
+	CheckStrength() error
+	// Verify returns true if and only if password is the password of the hash. The duration of Verify does not depend
+	// on how much of the hash of password matches.
+	Verify(password string) bool
+}
+
+// Parse parses a password hash in one of the supported formats.
+func Parse(s string) (Hash, error) {
+	if strings.HasPrefix(s, prefixArgon2id) {
+		return parseArgon2id(s)
+	}
+	if strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$") {
+		cost, err := bcrypt.Cost([]byte(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+		if cost > MaxBcryptCost {
+			return nil, fmt.Errorf("cost of bcrypt hash (%d) must be at most %d", cost, MaxBcryptCost)
+		}
+		return &bcryptHash{
+			cost: cost,
+			hash: []byte(s),
+		}, nil
+	}
+	return nil, fmt.Errorf(`hash must start with %#v, "$2a$", "$2b$" or "$2y$"`, prefixArgon2id)
+}
+
+// GenerateArgon2id returns an argon2id hash of password with the default parameters and a random salt.
+func GenerateArgon2id(password string) (string, error) {
+	h := &argon2idHash{
+		iterations:  DefaultArgon2idIterations,
+		memory:      DefaultArgon2idMemory,
+		parallelism: DefaultArgon2idParallelism,
+		salt:        make([]byte, DefaultArgon2idSaltLength),
+	}
+	if _, err := rand.Read(h.salt); err != nil {
+		return "", err
+	}
+	h.key = h.deriveKey(password, DefaultArgon2idKeyLength)
+	return h.String(), nil
+}
+
+// GenerateBcrypt returns a bcrypt hash of password with cost cost and a random salt.
+func GenerateBcrypt(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+type argon2idHash struct {
+	iterations  uint32
+	key         []byte
+	memory      uint32
+	parallelism uint8
+	salt        []byte
+}
+
+func parseArgon2id(s string) (*argon2idHash, error) {
+	// Split "$argon2id$v=19$m=...,t=...,p=...$<salt>$<key>" into "", "argon2id", "v=19", "m=...,t=...,p=...", "<salt>"
+	// and "<key>".
+	parts := strings.Split(s, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf(`invalid argon2id hash: must have the format %sv=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>`,
+			prefixArgon2id)
+	}
+	if parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return nil, fmt.Errorf("invalid argon2id hash: version must be v=%d", argon2.Version)
+	}
+	h := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: error parsing parameters %#v: %w", parts[3], err)
+	}
+	if fmt.Sprintf("m=%d,t=%d,p=%d", h.memory, h.iterations, h.parallelism) != parts[3] {
+		return nil, fmt.Errorf("invalid argon2id hash: parameters %#v are not in canonical form", parts[3])
+	}
+	if h.iterations == 0 || h.parallelism == 0 {
+		return nil, fmt.Errorf("invalid argon2id hash: iterations and parallelism must be positive")
+	}
+	if h.memory > MaxArgon2idMemory || h.iterations > MaxArgon2idIterations || h.parallelism > MaxArgon2idParallelism {
+		return nil, fmt.Errorf("argon2id hash has parameters %#v but memory (m), iterations (t) and parallelism (p) must be at "+
+			"most %d KiB, %d and %d, respectively", parts[3], MaxArgon2idMemory, MaxArgon2idIterations, MaxArgon2idParallelism)
+	}
+	var err error
+	h.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: error decoding salt: %w", err)
+	}
+	h.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: error decoding key: %w", err)
+	}
+	if len(h.key) == 0 {
+		return nil, fmt.Errorf("invalid argon2id hash: key must not be empty")
+	}
+	return h, nil
+}
+
+func (h *argon2idHash) CheckStrength() error {
+	if h.memory < MinArgon2idMemory {
+		return fmt.Errorf("memory of argon2id hash (m=%d) must be at least %d KiB", h.memory, MinArgon2idMemory)
+	}
+	if h.iterations < MinArgon2idIterations {
+		return fmt.Errorf("iterations of argon2id hash (t=%d) must be at least %d", h.iterations, MinArgon2idIterations)
+	}
+	if len(h.salt) < MinArgon2idSaltLength {
+		return fmt.Errorf("salt of argon2id hash must be at least %d bytes", MinArgon2idSaltLength)
+	}
+	if len(h.key) < MinArgon2idKeyLength {
+		return fmt.Errorf("key of argon2id hash must be at least %d bytes", MinArgon2idKeyLength)
+	}
+	return nil
+}
+
+func (h *argon2idHash) deriveKey(password string, keyLength uint32) []byte {
+	return argon2.IDKey([]byte(password), h.salt, h.iterations, h.memory, h.parallelism, keyLength)
+}
+
+func (h *argon2idHash) String() string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", prefixArgon2id, argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(h.salt), base64.RawStdEncoding.EncodeToString(h.key))
+}
+
+func (h *argon2idHash) Verify(password string) bool {
+	return subtle.ConstantTimeCompare(h.deriveKey(password, uint32(len(h.key))), h.key) == 1
+}
+
+type bcryptHash struct {
+	cost int
+	hash []byte
+}
+
+func (h *bcryptHash) CheckStrength() error {
+	if h.cost < MinBcryptCost {
+		return fmt.Errorf("cost of bcrypt hash (%d) must be at least %d", h.cost, MinBcryptCost)
+	}
+	return nil
+}
+
+func (h *bcryptHash) Verify(password string) bool {
+	return bcrypt.CompareHashAndPassword(h.hash, []byte(password)) == nil
+}
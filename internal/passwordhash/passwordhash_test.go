@@ -0,0 +1,66 @@
+package passwordhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_GenerateArgon2id(t *testing.T) {
+	s, err := GenerateArgon2id("test")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=65536,t=3,p=4\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, s)
+	hash, err := Parse(s)
+	require.NoError(t, err)
+	assert.NoError(t, hash.CheckStrength())
+	assert.True(t, hash.Verify("test"))
+	assert.False(t, hash.Verify("test2"))
+	assert.False(t, hash.Verify(""))
+}
+
+func Test_GenerateBcrypt(t *testing.T) {
+	s, err := GenerateBcrypt("test", bcrypt.MinCost)
+	require.NoError(t, err)
+	hash, err := Parse(s)
+	require.NoError(t, err)
+	assert.EqualError(t, hash.CheckStrength(), "cost of bcrypt hash (4) must be at least 10")
+	assert.True(t, hash.Verify("test"))
+	assert.False(t, hash.Verify("test2"))
+}
+
+func Test_Parse(t *testing.T) {
+	// A hash of "test" with weak parameters.
+	weak := &argon2idHash{
+		iterations:  1,
+		memory:      8,
+		parallelism: 1,
+		salt:        []byte("0123456789abcdef"),
+	}
+	weak.key = weak.deriveKey("test", 32)
+	hash, err := Parse(weak.String())
+	require.NoError(t, err)
+	assert.True(t, hash.Verify("test"))
+	assert.EqualError(t, hash.CheckStrength(), "memory of argon2id hash (m=8) must be at least 19456 KiB")
+
+	for _, s := range []string{
+		"",
+		"test",
+		"$argon2i$v=19$m=65536,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=16$m=65536,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$t=3,m=65536,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=0,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA==$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA$",
+		"$2b$12$invalid",
+		"$argon2id$v=19$m=4194304,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=1000,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=255$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$2b$31$Ro0CUfOqk6cXEKf3dyaM7OhSCvnwM9s4wIX9JeLapehKK5YdLxKcm",
+	} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
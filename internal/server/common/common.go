@@ -3,6 +3,7 @@ package common
 import (
 	"net/http"
 
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
 )
 
@@ -12,3 +13,26 @@ func InternalServerError(w http.ResponseWriter) {
 	code := http.StatusInternalServerError
 	http.Error(w, http.StatusText(code), code)
 }
+
+// Authorize returns the access of identity to the module modulePath according to acl: the access of the first element of
+// acl that applies, or config.AccessDeny if no element applies.
+func Authorize(acl []*config.AccessControlListElement, identity *auth.Identity, modulePath string) config.Access {
+	for _, aclElem := range acl {
+		if len(aclElem.Identities) > 0 {
+			found := false
+			for _, identityName := range aclElem.Identities {
+				if identity.Name == identityName {
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		if aclElem.ModuleRegexp.Value != nil && !aclElem.ModuleRegexp.Value.MatchString(modulePath) {
+			continue
+		}
+		return aclElem.Access
+	}
+	return config.AccessDeny
+}
@@ -63,6 +63,12 @@ func (i *instrumentedResponseWriter) Write(p []byte) (n int, err error) {
 	return
 }
 
+// Unwrap allows http.ResponseController to access optional interfaces of the wrapped http.ResponseWriter (such as
+// http.Flusher).
+func (i *instrumentedResponseWriter) Unwrap() http.ResponseWriter {
+	return i.w
+}
+
 func LoggingMiddleware(logger *log.Logger, lvl log.Level, prefix string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
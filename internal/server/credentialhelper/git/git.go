@@ -10,6 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/git"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/github"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
 )
@@ -42,11 +43,12 @@ func NewServer(opts ServerOptions) (*Server, error) {
 }
 
 func (s *Server) post(w http.ResponseWriter, req *http.Request) {
-	var goModulePath string
-	if err := util.UnmarshalJSON(req.Body, &goModulePath, true); err != nil {
+	var reqBody Request
+	if err := util.UnmarshalJSON(req.Body, &reqBody, true); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	goModulePath := reqBody.GoModulePath
 	var privateModulesElement2 *config.PrivateModulesElement
 	for _, privateModulesElement1 := range s.privateModules {
 		if util.PathIsLexicalDescendant(goModulePath, privateModulesElement1.PathPrefix) {
@@ -58,10 +60,22 @@ func (s *Server) post(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, fmt.Sprintf(`Go module path (%#v) is not known to be a private module`, goModulePath), http.StatusInternalServerError)
 		return
 	}
+	// If goModulePath is a vanity import path then credentials are for the repository it maps to. Credentials are only
+	// returned for that repository, so that they are not sent to other hosts.
+	repoModulePath, _, ok := privateModulesElement2.RepoModulePath(goModulePath)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Go module path (%#v) has no repository", goModulePath), http.StatusBadRequest)
+		return
+	}
+	if !util.PathIsLexicalDescendant(repoModulePath, reqBody.Host+"/"+strings.TrimSuffix(reqBody.Path, ".git")) {
+		http.Error(w, fmt.Sprintf("host (%#v) and path (%#v) are unexpectedly inconsistent with Go module path (%#v)",
+			reqBody.Host, reqBody.Path, goModulePath), http.StatusBadRequest)
+		return
+	}
 	if privateModulesElement2.Auth.GitHubApp != nil {
-		goModulePathParts := strings.SplitN(goModulePath, "/", 3)
+		goModulePathParts := strings.SplitN(repoModulePath, "/", 3)
 		if len(goModulePathParts) < 2 {
-			http.Error(w, `Go module path (%#v) must contain a "/"`, http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf(`Go module path (%#v) must contain a "/"`, repoModulePath), http.StatusBadRequest)
 			return
 		}
 		host, repoOwner := goModulePathParts[0], goModulePathParts[1]
@@ -83,17 +97,51 @@ func (s *Server) post(w http.ResponseWriter, req *http.Request) {
 			http.Error(w, http.StatusText(code), code)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(UserPassword{
+		writeUserPassword(w, &UserPassword{
 			User:     "x-access-token",
 			Password: token,
 		})
 		return
 	}
-	error := fmt.Sprintf("TODO implement a mechanism to retrieve credentials for non-GitHub repositories (%#v)", goModulePath)
-	log.Debugf("git credential helper server: %s", error)
-	http.Error(w, error, http.StatusInternalServerError)
+	gitCredentials := findGitCredentials(privateModulesElement2.Auth.Credentials, reqBody.Host)
+	if gitCredentials == nil {
+		error := fmt.Sprintf("no credentials of Go module path (%#v) are configured for host %#v", goModulePath, reqBody.Host)
+		log.Debugf("git credential helper server: %s", error)
+		http.Error(w, error, http.StatusNotFound)
+		return
+	}
+	writeUserPassword(w, &UserPassword{
+		User:     gitCredentials.User,
+		Password: string(gitCredentials.Token.Plaintext),
+	})
+}
+
+// findGitCredentials returns the element of credentials whose Host is host, or else the element without a Host, or else
+// nil.
+func findGitCredentials(credentials []*config.GitCredentials, host string) *config.GitCredentials {
+	var fallback *config.GitCredentials
+	for _, gitCredentials := range credentials {
+		if gitCredentials.Host == host {
+			return gitCredentials
+		}
+		if gitCredentials.Host == "" {
+			fallback = gitCredentials
+		}
+	}
+	return fallback
+}
+
+func writeUserPassword(w http.ResponseWriter, userPassword *UserPassword) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(userPassword)
+}
+
+// Request is the body of POST /git requests, which are sent by git credential helpers to get credentials for the
+// repository of a Go module (see CredentialHelperParams).
+type Request struct {
+	git.CredentialHelperParams
+	GoModulePath string `json:"goModulePath"`
 }
 
 type UserPassword struct {
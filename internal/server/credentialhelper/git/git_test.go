@@ -0,0 +1,69 @@
+package git
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/git"
+)
+
+func Test_FindGitCredentials(t *testing.T) {
+	gitCredentials1 := &config.GitCredentials{Host: "git.example.com", User: "a"}
+	gitCredentials2 := &config.GitCredentials{User: "b"}
+	credentials := []*config.GitCredentials{gitCredentials2, gitCredentials1}
+	assert.Same(t, gitCredentials1, findGitCredentials(credentials, "git.example.com"))
+	assert.Same(t, gitCredentials2, findGitCredentials(credentials, "other.example.com"))
+	assert.Nil(t, findGitCredentials(credentials[1:], "other.example.com"))
+}
+
+func Test_Server_Post(t *testing.T) {
+	s := &Server{
+		privateModules: []*config.PrivateModulesElement{
+			{
+				Auth: config.PrivateModulesElementAuth{
+					Credentials: []*config.GitCredentials{
+						{User: "a", Token: &config.Secret{Plaintext: []byte("token")}},
+					},
+				},
+				PathPrefix: "git.example.com/corp",
+			},
+			{
+				Auth: config.PrivateModulesElementAuth{
+					Credentials: []*config.GitCredentials{
+						{Host: "other.example.com", User: "b", Token: &config.Secret{Plaintext: []byte("token")}},
+					},
+				},
+				PathPrefix: "git2.example.com/corp",
+			},
+		},
+	}
+	post := func(goModulePath, host, path string) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(&Request{
+			CredentialHelperParams: git.CredentialHelperParams{Host: host, Path: path},
+			GoModulePath:           goModulePath,
+		})
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		s.post(rec, httptest.NewRequest(http.MethodPost, "/git", strings.NewReader(string(reqBody))))
+		return rec
+	}
+
+	rec := post("git.example.com/corp/m/sub", "git.example.com", "corp/m.git")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var userPassword UserPassword
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &userPassword))
+	assert.Equal(t, UserPassword{User: "a", Password: "token"}, userPassword)
+
+	// The host-less credentials must not be sent to other hosts or for other repositories.
+	assert.Equal(t, http.StatusBadRequest, post("git.example.com/corp/m", "evil.example.com", "corp/m.git").Code)
+	assert.Equal(t, http.StatusBadRequest, post("git.example.com/corp/m", "git.example.com", "corp/other").Code)
+
+	assert.Equal(t, http.StatusNotFound, post("git2.example.com/corp/m", "git2.example.com", "corp/m").Code)
+}
@@ -0,0 +1,219 @@
+package githubwebhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+)
+
+const (
+	headerNameEvent     = "X-GitHub-Event"
+	headerNameSignature = "X-Hub-Signature-256"
+	// indexTimeout bounds the duration of indexing the module version of a tag.
+	indexTimeout = 10 * time.Minute
+	// maxRequestBodySize is the maximum size of the payload of a webhook delivery (see
+	// https://docs.github.com/en/webhooks/webhook-events-and-payloads#payload-cap).
+	maxRequestBodySize = 25 << 20
+	refPrefixTags      = "refs/tags/"
+	signaturePrefix    = "sha256="
+)
+
+// ModuleVersionIndexer is implemented by "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/gocmd".Service.
+type ModuleVersionIndexer interface {
+	// IndexModuleVersion stores a module version of a private module unless it has already been stored, and invalidates
+	// cached outcomes of lookups of the module.
+	// Returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound)
+	// is true if the module is not private or the module version does not exist.
+	IndexModuleVersion(ctx context.Context, moduleVersion *module.Version) error
+}
+
+type ServerOptions struct {
+	Indexer ModuleVersionIndexer
+	// UseEncodedPath must have been called on ParentRouter for correct routing.
+	ParentRouter *mux.Router
+	Secret       []byte
+}
+
+// Server implements the POST /webhooks/github endpoint, which receives GitHub webhook deliveries. The module version of a
+// tag of a create or push event is indexed in the background (see ModuleVersionIndexer), so that it is stored before it is
+// first requested. Other events are ignored.
+type Server struct {
+	indexer ModuleVersionIndexer
+	secret  []byte
+}
+
+// NewServer is a constructor for Server.
+func NewServer(opts ServerOptions) (*Server, error) {
+	if opts.Indexer == nil {
+		return nil, fmt.Errorf("opts.Indexer must not be nil")
+	}
+	if len(opts.Secret) == 0 {
+		return nil, fmt.Errorf("opts.Secret must not be empty")
+	}
+	s := &Server{
+		indexer: opts.Indexer,
+		secret:  opts.Secret,
+	}
+	opts.ParentRouter.Path("/webhooks/github").Methods(http.MethodPost).HandlerFunc(s.serveHTTP)
+	return s, nil
+}
+
+type repository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+// createEvent is the payload of a create event (see https://docs.github.com/en/webhooks/webhook-events-and-payloads#create).
+type createEvent struct {
+	Ref        string     `json:"ref"`
+	RefType    string     `json:"ref_type"`
+	Repository repository `json:"repository"`
+}
+
+// pushEvent is the payload of a push event (see https://docs.github.com/en/webhooks/webhook-events-and-payloads#push).
+type pushEvent struct {
+	Deleted    bool       `json:"deleted"`
+	Ref        string     `json:"ref"`
+	Repository repository `json:"repository"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !s.verifySignature(req.Header.Get(headerNameSignature), payload) {
+		log.Warnf("rejecting GitHub webhook delivery because its %s header is missing or invalid", headerNameSignature)
+		http.Error(w, fmt.Sprintf("%s header is missing or invalid", headerNameSignature), http.StatusUnauthorized)
+		return
+	}
+	var repo repository
+	var tag string
+	switch eventName := req.Header.Get(headerNameEvent); eventName {
+	case "create":
+		var event createEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			http.Error(w, fmt.Sprintf("error parsing payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		if event.RefType == "tag" {
+			repo, tag = event.Repository, event.Ref
+		}
+	case "push":
+		var event pushEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			http.Error(w, fmt.Sprintf("error parsing payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		if !event.Deleted && strings.HasPrefix(event.Ref, refPrefixTags) {
+			repo, tag = event.Repository, strings.TrimPrefix(event.Ref, refPrefixTags)
+		}
+	default:
+		log.Tracef("ignoring GitHub webhook delivery of event %#v", eventName)
+	}
+	if tag == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	repoPath, err := repositoryModulePath(&repo)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error parsing payload: %v", err), http.StatusBadRequest)
+		return
+	}
+	candidates := tagModuleVersions(repoPath, tag)
+	if len(candidates) == 0 {
+		log.Debugf("ignoring tag %#v of repository %s because it is not a module version tag", tag, repoPath)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// GitHub expects a response within 10 seconds, so index in the background.
+	go s.index(candidates)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) verifySignature(signature string, payload []byte) bool {
+	signatureHex, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return false
+	}
+	signatureBytes, err := hex.DecodeString(signatureHex)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	_, _ = mac.Write(payload)
+	return hmac.Equal(signatureBytes, mac.Sum(nil))
+}
+
+// index indexes the first of candidates that exists.
+func (s *Server) index(candidates []module.Version) {
+	ctx, cancel := context.WithTimeout(context.Background(), indexTimeout)
+	defer cancel()
+	for _, moduleVersion := range candidates {
+		err := s.indexer.IndexModuleVersion(ctx, &moduleVersion)
+		if err == nil {
+			return
+		}
+		if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			log.Errorf("error indexing module version %s: %v", moduleVersion.String(), err)
+			return
+		}
+		log.Debugf("not indexing module version %s: %v", moduleVersion.String(), err)
+	}
+}
+
+// repositoryModulePath returns the module path of the root directory of repo, i.e. "github.com/org/repo".
+func repositoryModulePath(repo *repository) (string, error) {
+	htmlURL, err := url.Parse(repo.HTMLURL)
+	if err != nil || htmlURL.Host == "" {
+		return "", fmt.Errorf(".repository.html_url (%#v) is not a valid absolute URL", repo.HTMLURL)
+	}
+	if strings.Count(repo.FullName, "/") != 1 {
+		return "", fmt.Errorf(".repository.full_name (%#v) is invalid", repo.FullName)
+	}
+	return htmlURL.Host + "/" + repo.FullName, nil
+}
+
+// tagModuleVersions returns the module versions that tag of the repository whose root directory has module path repoPath
+// can be a version of, in order of preference. Tags of modules in subdirectories of a repository are prefixed by the
+// subdirectory (i.e. "dir/v1.2.3"). The module path of a major version v2 or higher has a major version suffix (i.e.
+// "github.com/org/repo/v2"), unless the module has no go.mod file (i.e. "github.com/org/repo@v2.0.0+incompatible").
+// Returns nil if tag is not a module version tag.
+func tagModuleVersions(repoPath, tag string) []module.Version {
+	modulePath := repoPath
+	version := tag
+	if i := strings.LastIndexByte(tag, '/'); i >= 0 {
+		modulePath += "/" + tag[:i]
+		version = tag[i+1:]
+	}
+	if !semver.IsValid(version) || semver.Canonical(version) != version {
+		return nil
+	}
+	if err := module.CheckPath(modulePath); err != nil {
+		return nil
+	}
+	major := semver.Major(version)
+	if major == "v0" || major == "v1" {
+		return []module.Version{{Path: modulePath, Version: version}}
+	}
+	return []module.Version{
+		{Path: modulePath + "/" + major, Version: version},
+		{Path: modulePath, Version: version + "+incompatible"},
+	}
+}
@@ -0,0 +1,90 @@
+package githubwebhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+)
+
+type fakeIndexer struct {
+	exists  map[string]bool
+	indexed chan string
+}
+
+func (f *fakeIndexer) IndexModuleVersion(ctx context.Context, moduleVersion *module.Version) error {
+	if !f.exists[moduleVersion.String()] {
+		return internalErrors.NewError(internalErrors.NotFound, "not found")
+	}
+	f.indexed <- moduleVersion.String()
+	return nil
+}
+
+func Test_Server(t *testing.T) {
+	secret := []byte("secret")
+	indexer := &fakeIndexer{
+		exists:  map[string]bool{"github.com/org/repo/sub/v2@v2.1.0": true},
+		indexed: make(chan string, 1),
+	}
+	router := mux.NewRouter()
+	_, err := NewServer(ServerOptions{
+		Indexer:      indexer,
+		ParentRouter: router,
+		Secret:       secret,
+	})
+	require.NoError(t, err)
+	send := func(event, payload, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(payload))
+		req.Header.Set(headerNameEvent, event)
+		if signature == "" {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(payload))
+			signature = signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+		}
+		req.Header.Set(headerNameSignature, signature)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	repo := `"repository":{"full_name":"org/repo","html_url":"https://github.com/org/repo"}`
+
+	assert.Equal(t, http.StatusUnauthorized, send("push", `{"ref":"refs/tags/v1.0.0",`+repo+`}`, signaturePrefix+"00"))
+	assert.Equal(t, http.StatusNoContent, send("push", `{"ref":"refs/heads/main",`+repo+`}`, ""))
+	assert.Equal(t, http.StatusNoContent, send("create", `{"ref":"main","ref_type":"branch",`+repo+`}`, ""))
+	assert.Equal(t, http.StatusNoContent, send("push", `{"ref":"refs/tags/v1.0.0","deleted":true,`+repo+`}`, ""))
+	assert.Equal(t, http.StatusNoContent, send("ping", `{}`, ""))
+
+	assert.Equal(t, http.StatusAccepted, send("push", `{"ref":"refs/tags/sub/v2.1.0",`+repo+`}`, ""))
+	select {
+	case moduleVersion := <-indexer.indexed:
+		assert.Equal(t, "github.com/org/repo/sub/v2@v2.1.0", moduleVersion)
+	case <-time.After(5 * time.Second):
+		t.Fatal("module version was not indexed")
+	}
+}
+
+func Test_TagModuleVersions(t *testing.T) {
+	assert.Equal(t, []module.Version{{Path: "github.com/org/repo", Version: "v1.2.3"}},
+		tagModuleVersions("github.com/org/repo", "v1.2.3"))
+	assert.Equal(t, []module.Version{{Path: "github.com/org/repo/a/b", Version: "v0.1.0-rc.1"}},
+		tagModuleVersions("github.com/org/repo", "a/b/v0.1.0-rc.1"))
+	assert.Equal(t, []module.Version{
+		{Path: "github.com/org/repo/v3", Version: "v3.0.0"},
+		{Path: "github.com/org/repo", Version: "v3.0.0+incompatible"},
+	}, tagModuleVersions("github.com/org/repo", "v3.0.0"))
+	assert.Nil(t, tagModuleVersions("github.com/org/repo", "v1.2"))
+	assert.Nil(t, tagModuleVersions("github.com/org/repo", "release-1"))
+	assert.Nil(t, tagModuleVersions("github.com/org/repo", "v1.0.0+meta"))
+}
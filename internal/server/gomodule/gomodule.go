@@ -2,6 +2,8 @@ package module
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,18 +18,46 @@ import (
 	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
 	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/server/common"
-	"github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
 	servicegomodule "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
 )
 
 const (
 	endOfModulePathInRequestURIPath = "/@"
+	headerNameCacheControl          = "Cache-Control"
 	headerNameContentType           = "Content-Type"
+	headerNameETag                  = "ETag"
 	contentTypeInfo                 = "application/json"
 	contentTypeText                 = "text/plain; charset=UTF-8"
 	contentTypeZip                  = "application/zip"
 )
 
+// contentETag returns a strong ETag derived from data.
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// zipETag returns a strong ETag for the zip file of a module version. A strong ETag can be derived from the module version
+// alone because the version must be canonical and the zip file of a canonical module version never changes once stored.
+// This avoids having to read the zip file to serve conditional requests.
+func zipETag(moduleVersion *module.Version) string {
+	return contentETag([]byte("zip\n" + moduleVersion.Path + "@" + moduleVersion.Version))
+}
+
+// ifNoneMatchMatches returns true if the If-None-Match header of req has an entity tag that matches etag using the weak
+// comparison (see https://www.rfc-editor.org/rfc/rfc9110#section-13.1.2), like http.ServeContent does. "*" is not
+// considered to match, because it only matches if the resource exists.
+func ifNoneMatchMatches(req *http.Request, etag string) bool {
+	for _, value := range req.Header.Values("If-None-Match") {
+		for _, candidate := range strings.Split(value, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func getVersion(rw http.ResponseWriter, versionRaw string) (string, bool) {
 	version, err := module.UnescapeVersion(versionRaw)
 	if err != nil {
@@ -38,6 +68,19 @@ func getVersion(rw http.ResponseWriter, versionRaw string) (string, bool) {
 	return version, true
 }
 
+// requestsNoCache returns true if req has a Cache-Control header with a no-cache directive, in which case cached not-found
+// outcomes are not used (see servicegomodule.WithBypassCache).
+func requestsNoCache(req *http.Request) bool {
+	for _, value := range req.Header.Values(headerNameCacheControl) {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type ServerOptions struct {
 	AccessControlList    []*config.AccessControlListElement
 	ClientAuthEnabled    bool
@@ -75,27 +118,6 @@ func NewServer(opts ServerOptions) (*Server, error) {
 	return s, nil
 }
 
-func (s *Server) authorize(identity *auth.Identity, modulePath string) config.Access {
-	for _, aclElem := range s.acl {
-		if len(aclElem.Identities) > 0 {
-			found := false
-			for _, identityName := range aclElem.Identities {
-				if identity.Name == identityName {
-					found = true
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		if aclElem.ModuleRegexp.Value != nil && !aclElem.ModuleRegexp.Value.MatchString(modulePath) {
-			continue
-		}
-		return aclElem.Access
-	}
-	return config.AccessDeny
-}
-
 func (s *Server) latest(rw http.ResponseWriter, req *http.Request, modulePath string) {
 	info, err := s.goModuleService.Latest(req.Context(), modulePath)
 	if err != nil {
@@ -163,6 +185,8 @@ func (s *Server) info(rw http.ResponseWriter, req *http.Request, modulePath, ver
 		return
 	}
 	rw.Header().Set(headerNameContentType, contentTypeInfo)
+	// A non-canonical version (such as a branch name) can resolve to a different canonical version later.
+	s.setCacheHeaders(rw, contentETag(infoJSONBytes), info.Version == version)
 	// name can be set to the empty string since it is only used to auto-detect content type.
 	http.ServeContent(rw, req, "", time.Time{}, bytes.NewReader(infoJSONBytes))
 }
@@ -183,15 +207,18 @@ func (s *Server) goMod(rw http.ResponseWriter, req *http.Request, modulePath, ve
 		http.Error(rw, fmt.Sprintf("error while getting mod file for module %s", moduleVersion.String()), http.StatusNotFound)
 		return
 	}
-	defer func() {
-		err := d.Close()
-		if err != nil {
-			log.Errorf("error closing %T: %v", d, err)
-		}
-	}()
+	goModBytes, err := io.ReadAll(d)
+	if err2 := d.Close(); err2 != nil {
+		log.Errorf("error closing %T: %v", d, err2)
+	}
+	if err != nil {
+		log.Errorf("error reading mod file for module %s: %v", moduleVersion.String(), err)
+		common.InternalServerError(rw)
+		return
+	}
 	rw.Header().Set(headerNameContentType, contentTypeText)
-	rw.WriteHeader(http.StatusOK)
-	_, _ = io.Copy(rw, d)
+	s.setCacheHeaders(rw, contentETag(goModBytes), true)
+	http.ServeContent(rw, req, "", time.Time{}, bytes.NewReader(goModBytes))
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -218,12 +245,15 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		if identity == nil {
 			return
 		}
-		access := s.authorize(identity, modulePath)
+		access := common.Authorize(s.acl, identity, modulePath)
 		if access == config.AccessDeny {
 			http.Error(w, "module does not exist, that's all we know.", http.StatusNotFound)
 			return
 		}
 	}
+	if requestsNoCache(req) {
+		req = req.WithContext(servicegomodule.WithBypassCache(req.Context()))
+	}
 	pathRest = pathRest[i+len(endOfModulePathInRequestURIPath):]
 	switch pathRest {
 	case "latest":
@@ -262,13 +292,51 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// setCacheHeaders sets the ETag and Cache-Control headers. If immutable is true then caches may store the response
+// indefinitely, otherwise caches must revalidate the response before using it. Responses are only cacheable by private
+// caches if client authentication is enabled.
+func (s *Server) setCacheHeaders(rw http.ResponseWriter, etag string, immutable bool) {
+	cacheControl := "public"
+	if s.clientAuthEnabled {
+		cacheControl = "private"
+	}
+	if immutable {
+		cacheControl += ", max-age=31536000, immutable"
+	} else {
+		cacheControl += ", no-cache"
+	}
+	rw.Header().Set(headerNameCacheControl, cacheControl)
+	rw.Header().Set(headerNameETag, etag)
+}
+
 func (s *Server) zip(rw http.ResponseWriter, req *http.Request, modulePath, versionRaw string) {
 	version, ok := getVersion(rw, versionRaw)
 	if !ok {
 		return
 	}
 	moduleVersion := module.Version{Path: modulePath, Version: version}
-	d, err := s.goModuleService.Zip(req.Context(), &moduleVersion)
+	etag := zipETag(&moduleVersion)
+	if module.CanonicalVersion(version) == version && ifNoneMatchMatches(req, etag) {
+		// The client has the zip file, which never changes, so neither the zip file has to be read nor the module version
+		// fetched.
+		s.setCacheHeaders(rw, etag, true)
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	// Access has been authorized at this point, so the client can be redirected to download the zip directly from storage.
+	zipURL, err := s.goModuleService.ZipURL(req.Context(), &moduleVersion)
+	if err == nil {
+		// The URL expires, so it must not be cached.
+		rw.Header().Set(headerNameCacheControl, "no-store")
+		http.Redirect(rw, req, zipURL, http.StatusFound)
+		return
+	}
+	if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		log.Errorf("error getting URL of zip file for module %s (serving zip file instead): %v", moduleVersion.String(), err)
+	}
+	// Open the entire zip archive, which also determines the size and whether the module version exists. The reader is
+	// reused by content if the response starts at byte offset 0 (i.e. the request is not a range request).
+	d, size, err := s.goModuleService.ZipRange(req.Context(), &moduleVersion, 0, -1)
 	if err != nil {
 		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 			http.Error(rw, fmt.Sprintf("not found: %v", err), http.StatusNotFound)
@@ -278,13 +346,18 @@ func (s *Server) zip(rw http.ResponseWriter, req *http.Request, modulePath, vers
 		http.Error(rw, fmt.Sprintf("error getting zip file for module %s", moduleVersion.String()), http.StatusNotFound)
 		return
 	}
+	content := newRangeReadSeeker(size, d, func(offset, length int64) (io.ReadCloser, error) {
+		d, _, err := s.goModuleService.ZipRange(req.Context(), &moduleVersion, offset, length)
+		return d, err
+	})
 	defer func() {
-		err := d.Close()
+		err := content.Close()
 		if err != nil {
-			log.Errorf("error closing %T: %v", d, err)
+			log.Errorf("error closing %T: %v", content, err)
 		}
 	}()
 	rw.Header().Set(headerNameContentType, contentTypeZip)
-	rw.WriteHeader(http.StatusOK)
-	_, _ = io.Copy(rw, d)
+	s.setCacheHeaders(rw, etag, true)
+	// http.ServeContent handles Range and If-Range headers.
+	http.ServeContent(rw, req, "", time.Time{}, content)
 }
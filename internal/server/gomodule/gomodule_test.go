@@ -0,0 +1,208 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	servicegomodule "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/memory"
+)
+
+// fakeGoModuleService serves a single module version v1.0.0 of module example.com/m, and resolves version "master" to it.
+type fakeGoModuleService struct {
+	servicegomodule.Service
+	// bypassCache records servicegomodule.BypassCache of the context of the last call of Info.
+	bypassCache bool
+	goMod       []byte
+	zip         []byte
+	zipStorage  *memory.Storage
+	// zipRangeCalls is the number of calls of ZipRange.
+	zipRangeCalls int
+	// zipURLs is used by ZipURL. zipURLs maps "<module path>@<version>" to URL.
+	zipURLs map[string]string
+}
+
+func (f *fakeGoModuleService) GoMod(ctx context.Context, moduleVersion *module.Version) (io.ReadCloser, error) {
+	if *moduleVersion != (module.Version{Path: "example.com/m", Version: "v1.0.0"}) {
+		return nil, internalErrors.NewErrorf(internalErrors.NotFound, "not found")
+	}
+	return io.NopCloser(bytes.NewReader(f.goMod)), nil
+}
+
+func (f *fakeGoModuleService) Info(ctx context.Context, moduleVersion *module.Version) (*servicegomodule.Info, error) {
+	f.bypassCache = servicegomodule.BypassCache(ctx)
+	if moduleVersion.Path != "example.com/m" || (moduleVersion.Version != "v1.0.0" && moduleVersion.Version != "master") {
+		return nil, internalErrors.NewErrorf(internalErrors.NotFound, "not found")
+	}
+	return &servicegomodule.Info{Version: "v1.0.0", Time: time.Unix(1600000000, 0).UTC()}, nil
+}
+
+func (f *fakeGoModuleService) ZipRange(ctx context.Context, moduleVersion *module.Version, offset, length int64) (
+	io.ReadCloser, int64, error) {
+	f.zipRangeCalls++
+	return f.zipStorage.GetObjectRange(ctx, moduleVersion.Path+"@"+moduleVersion.Version, offset, length)
+}
+
+func (f *fakeGoModuleService) ZipURL(ctx context.Context, moduleVersion *module.Version) (string, error) {
+	zipURL, ok := f.zipURLs[moduleVersion.Path+"@"+moduleVersion.Version]
+	if !ok {
+		return "", internalErrors.NewErrorf(internalErrors.NotFound, "not found")
+	}
+	return zipURL, nil
+}
+
+func newTestServer(t *testing.T) (*fakeGoModuleService, http.Handler) {
+	zip := make([]byte, 1000)
+	for i := range zip {
+		zip[i] = byte(i)
+	}
+	zipStorage, err := memory.NewStorage(memory.StorageOptions{})
+	require.NoError(t, err)
+	require.NoError(t, zipStorage.CreateObjectExclusively(context.Background(), "example.com/m@v1.0.0", nil, bytes.NewReader(zip)))
+	goModuleService := &fakeGoModuleService{
+		goMod:      []byte("module example.com/m\n"),
+		zip:        zip,
+		zipStorage: zipStorage,
+	}
+	router := mux.NewRouter().UseEncodedPath().SkipClean(true)
+	_, err = NewServer(ServerOptions{
+		GoModuleService: goModuleService,
+		Router:          router,
+	})
+	require.NoError(t, err)
+	return goModuleService, router
+}
+
+func serve(handler http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_Zip(t *testing.T) {
+	goModuleService, handler := newTestServer(t)
+	const path = "/example.com/m/@v/v1.0.0.zip"
+	rec := serve(handler, path, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, goModuleService.zip, rec.Body.Bytes())
+	assert.Equal(t, 1, goModuleService.zipRangeCalls)
+	assert.Equal(t, contentTypeZip, rec.Header().Get(headerNameContentType))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get(headerNameCacheControl))
+	etag := rec.Header().Get(headerNameETag)
+	assert.Regexp(t, `^"[^"]+"$`, etag)
+
+	rec = serve(handler, path, http.Header{"Range": {"bytes=10-19"}})
+	require.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, goModuleService.zip[10:20], rec.Body.Bytes())
+	assert.Equal(t, "bytes 10-19/1000", rec.Header().Get("Content-Range"))
+
+	rec = serve(handler, path, http.Header{"Range": {"bytes=990-"}})
+	require.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, goModuleService.zip[990:], rec.Body.Bytes())
+
+	rec = serve(handler, path, http.Header{"Range": {"bytes=1000-"}})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+
+	zipRangeCalls := goModuleService.zipRangeCalls
+	rec = serve(handler, path, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+	assert.Equal(t, etag, rec.Header().Get(headerNameETag))
+	rec = serve(handler, path, http.Header{"If-None-Match": {`"other", W/` + etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, zipRangeCalls, goModuleService.zipRangeCalls, "conditional requests must not read the zip file")
+
+	rec = serve(handler, path, http.Header{"If-Range": {etag}, "Range": {"bytes=0-1"}})
+	require.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, goModuleService.zip[:2], rec.Body.Bytes())
+
+	rec = serve(handler, path, http.Header{"If-Range": {`"other"`}, "Range": {"bytes=0-1"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, goModuleService.zip, rec.Body.Bytes())
+
+	rec = serve(handler, "/example.com/m/@v/v1.1.0.zip", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The ETag depends on the module version.
+	require.NoError(t, goModuleService.zipStorage.CreateObjectExclusively(context.Background(), "example.com/m@v1.1.0", nil,
+		bytes.NewReader(goModuleService.zip)))
+	rec = serve(handler, "/example.com/m/@v/v1.1.0.zip", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func Test_Zip_Redirect(t *testing.T) {
+	goModuleService, handler := newTestServer(t)
+	const zipURL = "https://storage.example.com/zip/example.com/m@v1.0.0?signature=x"
+	goModuleService.zipURLs = map[string]string{"example.com/m@v1.0.0": zipURL}
+	rec := serve(handler, "/example.com/m/@v/v1.0.0.zip", nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, zipURL, rec.Header().Get("Location"))
+	assert.Equal(t, "no-store", rec.Header().Get(headerNameCacheControl))
+	assert.Empty(t, rec.Header().Get(headerNameETag))
+
+	// Module versions without URL are served by the proxy.
+	require.NoError(t, goModuleService.zipStorage.CreateObjectExclusively(context.Background(), "example.com/m@v1.1.0", nil,
+		bytes.NewReader(goModuleService.zip)))
+	rec = serve(handler, "/example.com/m/@v/v1.1.0.zip", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, goModuleService.zip, rec.Body.Bytes())
+}
+
+func Test_GoMod(t *testing.T) {
+	goModuleService, handler := newTestServer(t)
+	const path = "/example.com/m/@v/v1.0.0.mod"
+	rec := serve(handler, path, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, goModuleService.goMod, rec.Body.Bytes())
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get(headerNameCacheControl))
+	etag := rec.Header().Get(headerNameETag)
+	assert.NotEmpty(t, etag)
+
+	rec = serve(handler, path, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func Test_Info(t *testing.T) {
+	_, handler := newTestServer(t)
+	rec := serve(handler, "/example.com/m/@v/v1.0.0.info", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get(headerNameCacheControl))
+	etag := rec.Header().Get(headerNameETag)
+	assert.NotEmpty(t, etag)
+
+	// The info of a non-canonical version must be revalidated.
+	rec = serve(handler, "/example.com/m/@v/master.info", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, no-cache", rec.Header().Get(headerNameCacheControl))
+	assert.Equal(t, etag, rec.Header().Get(headerNameETag))
+
+	rec = serve(handler, "/example.com/m/@v/master.info", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func Test_Info_NoCache(t *testing.T) {
+	goModuleService, handler := newTestServer(t)
+	rec := serve(handler, "/example.com/m/@v/v1.0.0.info", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, goModuleService.bypassCache)
+
+	rec = serve(handler, "/example.com/m/@v/v1.0.0.info", http.Header{headerNameCacheControl: {"max-age=0, No-Cache"}})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, goModuleService.bypassCache)
+}
@@ -0,0 +1,83 @@
+package module
+
+import (
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rangeReadSeeker is an io.ReadSeeker of content of a known size that is read lazily using ranged reads. This lets
+// http.ServeContent serve (multi-)range requests without reading the parts of the content that are not requested.
+type rangeReadSeeker struct {
+	open func(offset, length int64) (io.ReadCloser, error)
+	pos  int64
+	r    io.ReadCloser
+	rPos int64
+	size int64
+}
+
+var _ io.ReadSeeker = (*rangeReadSeeker)(nil)
+
+// newRangeReadSeeker returns a *rangeReadSeeker of content of size bytes. r must be nil or a reader of all of the content,
+// which is used for reads from byte offset 0 (and closed by Close). open must return a reader of at most length bytes of
+// the content starting at byte offset.
+func newRangeReadSeeker(size int64, r io.ReadCloser, open func(offset, length int64) (io.ReadCloser, error)) *rangeReadSeeker {
+	return &rangeReadSeeker{
+		open: open,
+		r:    r,
+		size: size,
+	}
+}
+
+func (r *rangeReadSeeker) Close() error {
+	if r.r == nil {
+		return nil
+	}
+	err := r.r.Close()
+	r.r = nil
+	return err
+}
+
+func (r *rangeReadSeeker) Read(p []byte) (n int, err error) {
+	if r.r != nil && r.rPos != r.pos {
+		if err := r.Close(); err != nil {
+			log.Errorf("error closing reader of range: %v", err)
+		}
+	}
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.r == nil {
+		r.r, err = r.open(r.pos, r.size-r.pos)
+		if err != nil {
+			log.Errorf("error opening reader of range starting at byte offset %d: %v", r.pos, err)
+			return
+		}
+		r.rPos = r.pos
+	}
+	n, err = r.r.Read(p)
+	r.pos += int64(n)
+	r.rPos = r.pos
+	if err == io.EOF && r.pos < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+func (r *rangeReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("whence %d is invalid", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("seek to negative position %d", offset)
+	}
+	r.pos = offset
+	return offset, nil
+}
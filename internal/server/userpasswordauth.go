@@ -1,13 +1,13 @@
 package server
 
 import (
-	"crypto/subtle"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
 
 	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
 	servercommon "github.com/go-mod-proxy/go-mod-proxy/internal/server/common"
+	serviceauth "github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
 )
 
@@ -25,6 +25,11 @@ func (s *Server) authenticateUserPassword(w http.ResponseWriter, req *http.Reque
 	authenticatedIdentity, err := s.identityStore.FindByName(reqBody.User)
 	if err != nil {
 		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			// Take as long as verifying a password, so that the duration does not reveal whether the identity exists.
+			if _, err := serviceauth.VerifyPassword(req.Context(), nil, reqBody.Password); err != nil {
+				log.Debugf("error verifying password: %v", err)
+				return
+			}
 			responseUnauthorized(w, s.realm)
 			return
 		}
@@ -32,8 +37,13 @@ func (s *Server) authenticateUserPassword(w http.ResponseWriter, req *http.Reque
 		servercommon.InternalServerError(w)
 		return
 	}
-	if authenticatedIdentity.Password == nil ||
-		subtle.ConstantTimeCompare(authenticatedIdentity.Password.Plaintext, []byte(reqBody.Password)) == 0 {
+	ok, err := serviceauth.VerifyPassword(req.Context(), authenticatedIdentity, reqBody.Password)
+	if err != nil {
+		// The client went away while waiting for its password to be verified.
+		log.Debugf("error verifying password: %v", err)
+		return
+	}
+	if !ok {
 		responseUnauthorized(w, s.realm)
 		return
 	}
@@ -9,10 +9,50 @@ import (
 	servercommon "github.com/go-mod-proxy/go-mod-proxy/internal/server/common"
 )
 
+const authenticationSchemeBasic = "Basic"
+
 func responseUnauthorized(w http.ResponseWriter, realm string) {
+	responseUnauthorizedWithScheme(w, realm, jasperhttp.AuthenticationSchemeBearer)
+}
+
+// basicChallengeResponseWriter adds a challenge with the Basic scheme to 401-responses, so that clients that answer
+// challenges learn that they can send credentials with the Basic scheme (in addition to the scheme challenged by the
+// handler).
+type basicChallengeResponseWriter struct {
+	http.ResponseWriter
+	challenge string
+}
+
+func (w *basicChallengeResponseWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusUnauthorized {
+		w.Header().Add(jasperhttp.HeaderNameWWWAuthenticate, w.challenge)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// formatChallenge formats the value of a WWW-Authenticate header with a challenge with scheme scheme and realm realm.
+func formatChallenge(realm, scheme string) (string, error) {
+	wwwAuthenticateErr, err := jasperhttp.NewWWWAuthenticateError("", []*jasperhttp.Challenge{
+		{
+			Scheme: scheme,
+			Params: []*jasperhttp.Param{
+				{
+					Attribute: "realm",
+					Value:     realm,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return wwwAuthenticateErr.HeaderValue(realm)
+}
+
+func responseUnauthorizedWithScheme(w http.ResponseWriter, realm, scheme string) {
 	wwwAuthenticateErr, err := jasperhttp.NewWWWAuthenticateError("", []*jasperhttp.Challenge{
 		{
-			Scheme: jasperhttp.AuthenticationSchemeBearer,
+			Scheme: scheme,
 			Params: []*jasperhttp.Param{
 				{
 					Attribute: "realm",
@@ -28,8 +68,7 @@ func responseUnauthorized(w http.ResponseWriter, realm string) {
 	}
 	headerValue, err := wwwAuthenticateErr.HeaderValue(realm)
 	if err != nil {
-		log.Errorf("error formatting %s %s response header: %v", jasperhttp.HeaderNameWWWAuthenticate,
-			jasperhttp.AuthenticationSchemeBearer, err)
+		log.Errorf("error formatting %s %s response header: %v", jasperhttp.HeaderNameWWWAuthenticate, scheme, err)
 		servercommon.InternalServerError(w)
 		return
 	}
@@ -0,0 +1,137 @@
+package vanity
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/server/common"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+const (
+	contentTypeHTML = "text/html; charset=UTF-8"
+	queryParamGoGet = "go-get"
+)
+
+var metaTagsTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta name="go-import" content="{{.RepoRoot}} git https://{{.RepoPath}}">
+{{- if .GoSource}}
+<meta name="go-source" content="{{.RepoRoot}} https://{{.RepoPath}} {{.GoSource.Directory}} {{.GoSource.File}}">
+{{- end}}
+</head>
+<body>
+go get {{.ModulePath}}
+</body>
+</html>
+`))
+
+type metaTags struct {
+	GoSource   *config.VanityGoSource
+	ModulePath string
+	RepoPath   string
+	RepoRoot   string
+}
+
+type ServerOptions struct {
+	AccessControlList []*config.AccessControlListElement
+	ClientAuthEnabled bool
+	// UseEncodedPath must have been called on ParentRouter for correct routing.
+	ParentRouter         *mux.Router
+	PrivateModules       []*config.PrivateModulesElement
+	RequestAuthenticator common.RequestAuthenticatorFunc
+}
+
+// Server implements GET /<path>?go-get=1 requests for the vanity import paths of private modules (see config.Vanity),
+// which are made by the Go command to discover the repository of a module (see https://go.dev/ref/mod#vcs-find). The
+// module path is the host of the request followed by <path>. Requests for other paths are responded to with 404.
+type Server struct {
+	acl                  []*config.AccessControlListElement
+	clientAuthEnabled    bool
+	privateModules       []*config.PrivateModulesElement
+	requestAuthenticator common.RequestAuthenticatorFunc
+}
+
+// NewServer is a constructor for Server.
+func NewServer(opts ServerOptions) (*Server, error) {
+	if opts.ClientAuthEnabled && opts.RequestAuthenticator == nil {
+		return nil, fmt.Errorf("if opts.ClientAuthEnabled is true then opts.RequestAuthenticator must not be nil")
+	}
+	s := &Server{
+		acl:                  opts.AccessControlList,
+		clientAuthEnabled:    opts.ClientAuthEnabled,
+		requestAuthenticator: opts.RequestAuthenticator,
+	}
+	for _, privateModulesElement := range opts.PrivateModules {
+		if privateModulesElement.Vanity != nil {
+			s.privateModules = append(s.privateModules, privateModulesElement)
+		}
+	}
+	opts.ParentRouter.Methods(http.MethodGet).Queries(queryParamGoGet, "1").HandlerFunc(s.serveHTTP)
+	return s, nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	modulePath := strings.ToLower(host) + strings.TrimSuffix(req.URL.Path, "/")
+	var privateModulesElement *config.PrivateModulesElement
+	for _, privateModulesElement1 := range s.privateModules {
+		if util.PathIsLexicalDescendant(modulePath, privateModulesElement1.PathPrefix) {
+			privateModulesElement = privateModulesElement1
+			break
+		}
+	}
+	if privateModulesElement == nil {
+		http.Error(w, fmt.Sprintf("%s is not a vanity import path", modulePath), http.StatusNotFound)
+		return
+	}
+	if s.clientAuthEnabled && !privateModulesElement.Vanity.PublicMetaTags {
+		identity := s.requestAuthenticator(w, req)
+		if identity == nil {
+			return
+		}
+		if common.Authorize(s.acl, identity, modulePath) == config.AccessDeny {
+			http.Error(w, "module does not exist, that's all we know.", http.StatusNotFound)
+			return
+		}
+	}
+	repoModulePath, repoRoot, ok := privateModulesElement.RepoModulePath(modulePath)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s is not in a repository", modulePath), http.StatusNotFound)
+		return
+	}
+	data := &metaTags{
+		ModulePath: modulePath,
+		// repoModulePath ends with the part of modulePath after repoRoot.
+		RepoPath: repoModulePath[:len(repoModulePath)-(len(modulePath)-len(repoRoot))],
+		RepoRoot: repoRoot,
+	}
+	if goSource := privateModulesElement.Vanity.GoSource; goSource != nil {
+		name := strings.TrimPrefix(strings.TrimPrefix(repoRoot, privateModulesElement.PathPrefix), "/")
+		data.GoSource = &config.VanityGoSource{
+			Directory: strings.ReplaceAll(goSource.Directory, config.VanityNamePlaceholder, name),
+			File:      strings.ReplaceAll(goSource.File, config.VanityNamePlaceholder, name),
+		}
+	}
+	var buf bytes.Buffer
+	if err := metaTagsTemplate.Execute(&buf, data); err != nil {
+		log.Errorf("error rendering meta tags of vanity import path %s: %v", modulePath, err)
+		common.InternalServerError(w)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeHTML)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
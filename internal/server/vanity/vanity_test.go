@@ -0,0 +1,80 @@
+package vanity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
+)
+
+func Test_Server(t *testing.T) {
+	router := mux.NewRouter().UseEncodedPath()
+	_, err := NewServer(ServerOptions{
+		AccessControlList: []*config.AccessControlListElement{
+			{Access: config.AccessAllow, Identities: []string{"x"}},
+		},
+		ClientAuthEnabled: true,
+		ParentRouter:      router,
+		PrivateModules: []*config.PrivateModulesElement{
+			{PathPrefix: "github.com/corp"},
+			{
+				PathPrefix: "go.corp.example",
+				Vanity: &config.Vanity{
+					GoSource: &config.VanityGoSource{
+						Directory: "https://github.com/corp/go-{name}/tree/main{/dir}",
+						File:      "https://github.com/corp/go-{name}/blob/main{/dir}/{file}#L{line}",
+					},
+					RepoPathTemplate: "github.com/corp/go-{name}",
+				},
+			},
+			{
+				PathPrefix: "tools.corp.example",
+				Vanity: &config.Vanity{
+					PublicMetaTags:   true,
+					RepoPathTemplate: "github.com/corp/tools",
+				},
+			},
+		},
+		RequestAuthenticator: func(w http.ResponseWriter, req *http.Request) *auth.Identity {
+			name := req.Header.Get("X-Identity")
+			if name == "" {
+				http.Error(w, "unauthenticated", http.StatusUnauthorized)
+				return nil
+			}
+			return &auth.Identity{Name: name}
+		},
+	})
+	require.NoError(t, err)
+	get := func(url, identity string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if identity != "" {
+			req.Header.Set("X-Identity", identity)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("https://go.corp.example/foo/sub?go-get=1", "x")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<meta name="go-import" content="go.corp.example/foo git https://github.com/corp/go-foo">`)
+	assert.Contains(t, rec.Body.String(), `<meta name="go-source" content="go.corp.example/foo https://github.com/corp/go-foo `+
+		`https://github.com/corp/go-foo/tree/main{/dir} https://github.com/corp/go-foo/blob/main{/dir}/{file}#L{line}">`)
+	assert.Equal(t, http.StatusUnauthorized, get("https://go.corp.example/foo?go-get=1", "").Code)
+	assert.Equal(t, http.StatusNotFound, get("https://go.corp.example/foo?go-get=1", "y").Code)
+	assert.Equal(t, http.StatusNotFound, get("https://go.corp.example/?go-get=1", "x").Code)
+	assert.Equal(t, http.StatusNotFound, get("https://github.com/corp/foo?go-get=1", "x").Code)
+
+	rec = get("https://tools.corp.example:443/cmd/lint?go-get=1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<meta name="go-import" content="tools.corp.example git https://github.com/corp/tools">`)
+	assert.NotContains(t, rec.Body.String(), "go-source")
+
+	assert.Equal(t, http.StatusNotFound, get("https://go.corp.example/foo", "x").Code)
+}
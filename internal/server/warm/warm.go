@@ -0,0 +1,97 @@
+package warm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/server/common"
+	servicewarm "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/warm"
+)
+
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	// maxRequestBodySize bounds the size of the go.mod, go.sum, go.work or list of module versions of a warm-up.
+	maxRequestBodySize = 10 << 20
+	queryParamFormat   = "format"
+)
+
+type ServerOptions struct {
+	// Identities are the names of the identities that are allowed to warm up caches.
+	Identities []string
+	// UseEncodedPath must have been called on ParentRouter for correct routing.
+	ParentRouter         *mux.Router
+	RequestAuthenticator common.RequestAuthenticatorFunc
+	Warmer               *servicewarm.Warmer
+}
+
+// Server implements the POST /admin/warm endpoint, which warms up caches with the build list of a go.mod, go.sum or
+// go.work file (or a list of module versions) in the request body. The format of the request body is given by query
+// parameter "format" (see "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/warm".Format). The response
+// body is a stream of newline-delimited JSON encoded
+// "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/warm".Event values.
+type Server struct {
+	identities           map[string]struct{}
+	requestAuthenticator common.RequestAuthenticatorFunc
+	warmer               *servicewarm.Warmer
+}
+
+// NewServer is a constructor for Server.
+func NewServer(opts ServerOptions) (*Server, error) {
+	if opts.RequestAuthenticator == nil {
+		return nil, fmt.Errorf("opts.RequestAuthenticator must not be nil")
+	}
+	if opts.Warmer == nil {
+		return nil, fmt.Errorf("opts.Warmer must not be nil")
+	}
+	s := &Server{
+		identities:           make(map[string]struct{}, len(opts.Identities)),
+		requestAuthenticator: opts.RequestAuthenticator,
+		warmer:               opts.Warmer,
+	}
+	for _, identityName := range opts.Identities {
+		s.identities[identityName] = struct{}{}
+	}
+	opts.ParentRouter.Path("/admin/warm").Methods(http.MethodPost).HandlerFunc(s.serveHTTP)
+	return s, nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	identity := s.requestAuthenticator(w, req)
+	if identity == nil {
+		return
+	}
+	if _, ok := s.identities[identity.Name]; !ok {
+		http.Error(w, fmt.Sprintf("identity %#v is not allowed to warm up caches", identity.Name), http.StatusForbidden)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	input, err := servicewarm.ParseInput(servicewarm.Format(req.URL.Query().Get(queryParamFormat)), data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error parsing request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	log.Infof("identity %#v started warm-up of %d module versions", identity.Name, len(input.Roots)+len(input.Mods))
+	w.Header().Set("Content-Type", contentTypeNDJSON)
+	w.WriteHeader(http.StatusOK)
+	responseController := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	report := func(event *servicewarm.Event) {
+		// Errors are ignored because the warm-up stops when the client disconnects (because the request context is
+		// done).
+		_ = encoder.Encode(event)
+		_ = responseController.Flush()
+	}
+	summary := s.warmer.Warm(req.Context(), input, report)
+	report(&servicewarm.Event{Summary: summary})
+	log.Infof("identity %#v finished warm-up (mods = %d, zips = %d, errors = %d)", identity.Name, summary.Mods,
+		summary.Zips, summary.Errors)
+}
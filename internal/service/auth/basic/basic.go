@@ -0,0 +1,111 @@
+package basic
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
+)
+
+// ErrInvalidCredentials is returned by Authenticator.Authenticate if the user or password is invalid.
+var ErrInvalidCredentials = errors.New("invalid user or password")
+
+type cacheEntry struct {
+	expiry time.Time
+	mac    []byte
+}
+
+// Authenticator authenticates identities by name and password, as sent in an Authorization header with the Basic scheme
+// (see https://www.rfc-editor.org/rfc/rfc7617). Successful authentications are cached per identity, so that the password
+// of an identity does not have to be verified on every request. The cache stores a MAC of the password (keyed by a random
+// key) rather than the password.
+type Authenticator struct {
+	cache           map[string]*cacheEntry
+	cacheKey        []byte
+	cacheMutex      sync.Mutex
+	cacheTimeToLive time.Duration
+	identityStore   auth.IdentityStore
+	now             func() time.Time
+}
+
+// NewAuthenticator is a constructor for Authenticator. If cacheTimeToLive is zero then successful authentications are not
+// cached.
+func NewAuthenticator(identityStore auth.IdentityStore, cacheTimeToLive time.Duration) (*Authenticator, error) {
+	if identityStore == nil {
+		return nil, fmt.Errorf("identityStore must not be nil")
+	}
+	if cacheTimeToLive < 0 {
+		return nil, fmt.Errorf("cacheTimeToLive must not be negative")
+	}
+	a := &Authenticator{
+		cacheTimeToLive: cacheTimeToLive,
+		identityStore:   identityStore,
+		now:             time.Now,
+	}
+	if cacheTimeToLive > 0 {
+		a.cache = map[string]*cacheEntry{}
+		a.cacheKey = make([]byte, sha256.Size)
+		if _, err := rand.Read(a.cacheKey); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Authenticate returns the identity named user if password is its password. Returns ErrInvalidCredentials if no such
+// identity exists, the identity has no password or password is not its password. Returns ctx.Err() if ctx is done before
+// password can be verified (see auth.VerifyPassword).
+func (a *Authenticator) Authenticate(ctx context.Context, user, password string) (*auth.Identity, error) {
+	identity, err := a.identityStore.FindByName(user)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			// Take as long as verifying a password, so that the duration does not reveal whether the identity exists.
+			if _, err := auth.VerifyPassword(ctx, nil, password); err != nil {
+				return nil, err
+			}
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if a.cache == nil {
+		return a.verifyPassword(ctx, identity, password)
+	}
+	mac := hmac.New(sha256.New, a.cacheKey)
+	_, _ = mac.Write([]byte(password))
+	passwordMAC := mac.Sum(nil)
+	now := a.now()
+	a.cacheMutex.Lock()
+	entry := a.cache[identity.Name]
+	a.cacheMutex.Unlock()
+	if entry != nil && now.Before(entry.expiry) && hmac.Equal(entry.mac, passwordMAC) {
+		return identity, nil
+	}
+	if _, err := a.verifyPassword(ctx, identity, password); err != nil {
+		return nil, err
+	}
+	a.cacheMutex.Lock()
+	a.cache[identity.Name] = &cacheEntry{
+		expiry: now.Add(a.cacheTimeToLive),
+		mac:    passwordMAC,
+	}
+	a.cacheMutex.Unlock()
+	return identity, nil
+}
+
+func (a *Authenticator) verifyPassword(ctx context.Context, identity *auth.Identity, password string) (*auth.Identity, error) {
+	ok, err := auth.VerifyPassword(ctx, identity, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return identity, nil
+}
@@ -0,0 +1,52 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/auth"
+)
+
+func Test_Authenticator(t *testing.T) {
+	ctx := context.Background()
+	identityStore, err := auth.NewInMemoryIdentityStore()
+	require.NoError(t, err)
+	password := &config.Secret{Plaintext: []byte("secret")}
+	require.NoError(t, identityStore.Add(&auth.Identity{Name: "x", Password: password}))
+	require.NoError(t, identityStore.Add(&auth.Identity{Name: "y"}))
+	for _, cacheTimeToLive := range []time.Duration{0, time.Minute} {
+		a, err := NewAuthenticator(identityStore, cacheTimeToLive)
+		require.NoError(t, err)
+		now := time.Unix(0, 0)
+		a.now = func() time.Time {
+			return now
+		}
+		identity, err := a.Authenticate(ctx, "x", "secret")
+		require.NoError(t, err)
+		assert.Equal(t, "x", identity.Name)
+		// The password is only verified again if the authentication is not cached.
+		password.Plaintext = []byte("changed")
+		_, err = a.Authenticate(ctx, "x", "secret")
+		if cacheTimeToLive > 0 {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+		password.Plaintext = []byte("secret")
+		_, err = a.Authenticate(ctx, "x", "wrong")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = a.Authenticate(ctx, "y", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = a.Authenticate(ctx, "z", "secret")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		now = now.Add(2 * time.Minute)
+		identity, err = a.Authenticate(ctx, "x", "secret")
+		require.NoError(t, err)
+		assert.Equal(t, "x", identity.Name)
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/passwordhash"
+)
+
+// maxConcurrentPasswordHashVerifications bounds the number of password hashes that are verified at the same time, because
+// verifying a password hash uses a lot of memory (i.e. 64MiB for an argon2id hash with the default parameters of the
+// hash-password command) and authentication attempts with wrong passwords are not cached.
+const maxConcurrentPasswordHashVerifications = 4
+
+var passwordHashVerificationSemaphore = make(chan struct{}, maxConcurrentPasswordHashVerifications)
+
+var (
+	dummyPasswordHash     passwordhash.Hash
+	dummyPasswordHashOnce sync.Once
+)
+
+// VerifyPassword returns true if and only if identity has a password (or password hash) and password is that password.
+// If identity is nil or has no password then VerifyPassword verifies password against a dummy password hash and returns
+// false, so that the duration of VerifyPassword does not reveal which identities exist. Only a few password hashes are
+// verified at the same time (per process). If ctx is done before password can be verified then VerifyPassword returns
+// ctx.Err().
+func VerifyPassword(ctx context.Context, identity *Identity, password string) (bool, error) {
+	if identity != nil && identity.PasswordHashParsed == nil && identity.Password != nil {
+		return subtle.ConstantTimeCompare(identity.Password.Plaintext, []byte(password)) == 1, nil
+	}
+	select {
+	case passwordHashVerificationSemaphore <- struct{}{}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	defer func() {
+		<-passwordHashVerificationSemaphore
+	}()
+	if identity != nil && identity.PasswordHashParsed != nil {
+		return identity.PasswordHashParsed.Verify(password), nil
+	}
+	if hash := getDummyPasswordHash(); hash != nil {
+		_ = hash.Verify(password)
+	}
+	return false, nil
+}
+
+func getDummyPasswordHash() passwordhash.Hash {
+	dummyPasswordHashOnce.Do(func() {
+		s, err := passwordhash.GenerateArgon2id("")
+		if err == nil {
+			dummyPasswordHash, err = passwordhash.Parse(s)
+		}
+		if err != nil {
+			log.Errorf("error generating dummy password hash: %v", err)
+		}
+	})
+	return dummyPasswordHash
+}
@@ -7,10 +7,25 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
 )
 
+// goCmdNotFoundPatterns are lower case substrings of errors printed by Go commands that indicate that a module or module
+// version does not exist (as opposed to, for example, a network error).
+var goCmdNotFoundPatterns = []string{
+	"404 not found",
+	"410 gone",
+	"invalid version:",
+	"malformed module path",
+	"no matching versions",
+	"repository not found",
+	"unknown revision",
+}
+
 func (s *Service) runCmd(ctx context.Context, t *tempGoEnv, args []string) (stdout []byte, strLog string, err error) {
 	cmdStdoutStderr := newCmdStdoutStderr()
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
@@ -57,6 +72,25 @@ func (s *Service) runCmd(ctx context.Context, t *tempGoEnv, args []string) (stdo
 	return
 }
 
+// goCmdNotFoundError returns a NotFound error if strLog (see runCmd) shows that a Go command failed because a module or
+// module version does not exist, and returns err otherwise.
+func goCmdNotFoundError(err error, strLog string) error {
+	for _, line := range strings.Split(strLog, "\n") {
+		line, ok := strings.CutPrefix(line, "stderr: ")
+		if !ok {
+			continue
+		}
+		lineLower := strings.ToLower(line)
+		for _, pattern := range goCmdNotFoundPatterns {
+			if strings.Contains(lineLower, pattern) {
+				log.Debug(err)
+				return internalErrors.NewErrorf(internalErrors.NotFound, "%s", line)
+			}
+		}
+	}
+	return err
+}
+
 type cmdStdoutStderr struct {
 	perFDStates []*perFDState
 	log         bytes.Buffer
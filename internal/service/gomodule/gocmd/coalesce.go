@@ -0,0 +1,185 @@
+package gocmd
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	module "golang.org/x/mod/module"
+
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+// fetchTimeout bounds the duration of a fetch. A fetch is shared by all callers that wait for it, so it is not cancelled
+// if the context of a caller is done.
+const fetchTimeout = 10 * time.Minute
+
+// fetchCall is a fetch of a module version that is shared by concurrent callers. The exported fields are set once the
+// fetch completes successfully. The files of the fetch (see DownloadInfo) are removed once all callers called release.
+type fetchCall struct {
+	// DownloadInfo is nil if only the .info and the go.mod file were fetched (see getGoModAndIndexIfNeeded).
+	DownloadInfo *goModuleInfo
+	FSeeStorage  bool
+	// GoMod is only set if FSeeStorage is false.
+	GoMod     []byte
+	Info      *gomoduleservice.Info
+	TempGoEnv *tempGoEnv
+
+	coalescer *fetchCoalescer
+	done      chan struct{}
+	err       error
+	refs      int
+}
+
+func (f *fetchCall) release() {
+	f.coalescer.mu.Lock()
+	f.refs--
+	refs := f.refs
+	f.coalescer.mu.Unlock()
+	if refs == 0 && f.TempGoEnv != nil {
+		if err := f.TempGoEnv.removeRef(); err != nil {
+			log.Errorf("error removing tmpDir %#v of *tempGoEnv: %v", f.TempGoEnv.TmpDir, err)
+		}
+	}
+}
+
+// fetchCoalescer coalesces concurrent fetches with the same key, so that concurrent requests of a module version share
+// one download, one *tempGoEnv and one slot of maxParallelismResourcePool.
+type fetchCoalescer struct {
+	calls        map[string]*fetchCall
+	mu           sync.Mutex
+	newTempGoEnv func() (*tempGoEnv, error)
+}
+
+func newFetchCoalescer(newTempGoEnv func() (*tempGoEnv, error)) *fetchCoalescer {
+	return &fetchCoalescer{
+		calls:        map[string]*fetchCall{},
+		newTempGoEnv: newTempGoEnv,
+	}
+}
+
+// do calls fetch in a new goroutine unless a call of fetch with the same key is in progress, and waits for it to complete.
+// If ctx is done before then do returns ctx.Err() but the fetch continues for other callers. If do returns a nil error
+// then the caller must call release on the returned *fetchCall.
+func (c *fetchCoalescer) do(ctx context.Context, key string, fetch func(ctx context.Context, f *fetchCall) error) (*fetchCall,
+	error) {
+	c.mu.Lock()
+	f, ok := c.calls[key]
+	if ok {
+		f.refs++
+	} else {
+		// One reference for the caller and one for the goroutine that fetches.
+		f = &fetchCall{
+			coalescer: c,
+			done:      make(chan struct{}),
+			refs:      2,
+		}
+		c.calls[key] = f
+		go c.run(key, f, fetch)
+	}
+	c.mu.Unlock()
+	if ok {
+		log.Tracef("waiting for fetch %#v started by another caller", key)
+	}
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		f.release()
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		f.release()
+		return nil, f.err
+	}
+	return f, nil
+}
+
+func (c *fetchCoalescer) run(key string, f *fetchCall, fetch func(ctx context.Context, f *fetchCall) error) {
+	defer f.release()
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	tempGoEnv, err := c.newTempGoEnv()
+	if err == nil {
+		f.TempGoEnv = tempGoEnv
+		err = fetch(ctx, f)
+	}
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	f.err = err
+	close(f.done)
+}
+
+// fetchGoModule fetches moduleVersion (see getGoModuleAndIndexIfNeeded), coalescing concurrent calls.
+func (s *Service) fetchGoModule(ctx context.Context, moduleVersion *module.Version) (f *fetchCall, err error) {
+	err = s.withNegativeCache(ctx, moduleVersion.Path, negativeCacheKeyPrefixZip+moduleVersion.String(), func() (err error) {
+		f, err = s.fetchGoModuleCoalesced(ctx, moduleVersion)
+		return
+	})
+	return
+}
+
+func (s *Service) fetchGoModuleCoalesced(ctx context.Context, moduleVersion *module.Version) (*fetchCall, error) {
+	moduleVersionCopy := *moduleVersion
+	return s.fetchCoalescer.do(ctx, "zip:"+moduleVersion.String(), func(ctx context.Context, f *fetchCall) (err error) {
+		release, err := s.acquireDownloadLeaseForFetch(ctx, &moduleVersionCopy, true, f)
+		if err != nil || f.FSeeStorage {
+			return
+		}
+		defer release()
+		f.FSeeStorage, f.Info, f.DownloadInfo, err = s.getGoModuleAndIndexIfNeeded(ctx, f.TempGoEnv, &moduleVersionCopy)
+		if err == nil && !f.FSeeStorage {
+			f.GoMod, err = os.ReadFile(f.DownloadInfo.GoMod)
+		}
+		return
+	})
+}
+
+// fetchGoMod fetches the .info and the go.mod file of moduleVersion, coalescing concurrent calls. If s.fetchZipsLazily is
+// false then fetchGoMod is the same as fetchGoModule.
+func (s *Service) fetchGoMod(ctx context.Context, moduleVersion *module.Version) (f *fetchCall, err error) {
+	if !s.fetchZipsLazily {
+		return s.fetchGoModule(ctx, moduleVersion)
+	}
+	err = s.withNegativeCache(ctx, moduleVersion.Path, negativeCacheKeyPrefixMod+moduleVersion.String(), func() (err error) {
+		f, err = s.fetchGoModCoalesced(ctx, moduleVersion)
+		return
+	})
+	return
+}
+
+func (s *Service) fetchGoModCoalesced(ctx context.Context, moduleVersion *module.Version) (*fetchCall, error) {
+	moduleVersionCopy := *moduleVersion
+	return s.fetchCoalescer.do(ctx, "mod:"+moduleVersion.String(), func(ctx context.Context, f *fetchCall) (err error) {
+		release, err := s.acquireDownloadLeaseForFetch(ctx, &moduleVersionCopy, false, f)
+		if err != nil || f.FSeeStorage {
+			return
+		}
+		defer release()
+		f.FSeeStorage, f.Info, f.GoMod, err = s.getGoModAndIndexIfNeeded(ctx, f.TempGoEnv, &moduleVersionCopy)
+		return
+	})
+}
+
+// acquireDownloadLeaseForFetch is like acquireDownloadLease, but sets f.FSeeStorage and f.Info if moduleVersion has been
+// stored by another replica. Unless f.FSeeStorage is true, release must be called once the fetch completes.
+func (s *Service) acquireDownloadLeaseForFetch(ctx context.Context, moduleVersion *module.Version, zip bool, f *fetchCall) (
+	release func(), err error) {
+	l, fSeeStorage, err := s.acquireDownloadLease(ctx, moduleVersion, zip)
+	if err != nil {
+		return
+	}
+	if fSeeStorage {
+		f.FSeeStorage = true
+		f.Info = &gomoduleservice.Info{Version: moduleVersion.Version}
+		return
+	}
+	if l == nil {
+		release = func() {}
+		return
+	}
+	release = s.renewLeaseUntilReleased(l)
+	return
+}
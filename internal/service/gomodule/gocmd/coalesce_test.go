@@ -0,0 +1,80 @@
+package gocmd
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FetchCoalescer(t *testing.T) {
+	scratchDir := t.TempDir()
+	c := newFetchCoalescer(func() (*tempGoEnv, error) {
+		return newTempGoEnv(scratchDir, getTempGoEnvBaseEnviron())
+	})
+	var fetches int32
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	fetch := func(ctx context.Context, f *fetchCall) error {
+		atomic.AddInt32(&fetches, 1)
+		close(started)
+		<-unblock
+		// A waiter whose context is done must not cancel the fetch.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f.GoMod = []byte("module example.com/m\n")
+		return os.WriteFile(f.TempGoEnv.TmpDir+"/go.mod", f.GoMod, 0600)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelledDone := make(chan error)
+	go func() {
+		_, err := c.do(ctx, "k", fetch)
+		cancelledDone <- err
+	}()
+	<-started
+	var wg sync.WaitGroup
+	calls := make([]*fetchCall, 5)
+	for i := range calls {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := c.do(context.Background(), "k", fetch)
+			assert.NoError(t, err)
+			calls[i] = f
+		}()
+	}
+	// Wait until all callers wait for the fetch (one reference per caller and one for the fetch).
+	for {
+		c.mu.Lock()
+		refs := c.calls["k"].refs
+		c.mu.Unlock()
+		if refs == len(calls)+2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.ErrorIs(t, <-cancelledDone, context.Canceled)
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	tmpDir := calls[0].TempGoEnv.TmpDir
+	for _, f := range calls {
+		require.NotNil(t, f)
+		assert.Equal(t, "module example.com/m\n", string(f.GoMod))
+		_, err := os.Stat(tmpDir + "/go.mod")
+		assert.NoError(t, err, "files must exist until all callers released the fetch")
+		f.release()
+	}
+	_, err := os.Stat(tmpDir)
+	assert.True(t, os.IsNotExist(err), "%v", err)
+}
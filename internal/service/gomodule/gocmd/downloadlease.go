@@ -0,0 +1,111 @@
+package gocmd
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	module "golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/lease"
+)
+
+// defaultDownloadLeasePollInterval is the interval at which a replica that waits for a download of another replica checks
+// whether the module version has been stored.
+const defaultDownloadLeasePollInterval = time.Second
+
+// acquireDownloadLease acquires the lease for downloading moduleVersion, so that replicas do not download the same module
+// version at the same time. If the lease is held by another replica then acquireDownloadLease waits until the module
+// version has been stored (in which case fSeeStorage is true), or until the lease is released or expires (i.e. because the
+// other replica died). l is nil if download leases are disabled or moduleVersion.Version is not canonical. If zip is true
+// then the module version must be stored with its zip file.
+func (s *Service) acquireDownloadLease(ctx context.Context, moduleVersion *module.Version, zip bool) (l *lease.Lease,
+	fSeeStorage bool, err error) {
+	if s.downloadLeaseTimeToLive <= 0 || moduleVersion.Version != module.CanonicalVersion(moduleVersion.Version) {
+		return
+	}
+	suffix := moduleVersion.Path + "@" + moduleVersion.Version
+	// Objects that indicate that the module version has been stored.
+	objNames := []string{storageConcatObjNamePrefix + suffix}
+	var leaseName string
+	if zip && s.fetchZipsLazily {
+		leaseName = storageLeaseObjNamePrefix + "download-zip/" + suffix
+		objNames = append(objNames, storageZipObjNamePrefix+suffix)
+	} else {
+		leaseName = storageLeaseObjNamePrefix + "download/" + suffix
+		objNames = append(objNames, storageGoModObjNamePrefix+suffix)
+	}
+	for {
+		l, err = lease.Acquire(ctx, s.storage, leaseName, s.leaseHolder, s.downloadLeaseTimeToLive)
+		if err != nil && !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return
+		}
+		if err != nil {
+			log.Debugf("waiting for download of %s by another replica: %v", moduleVersion.String(), err)
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			case <-time.After(s.downloadLeasePollInterval):
+			}
+		}
+		// The module version may have been stored before the lease was acquired.
+		fSeeStorage, err = s.anyObjectExists(ctx, objNames)
+		if err != nil || fSeeStorage {
+			if l != nil {
+				s.releaseLease(l)
+				l = nil
+			}
+			return
+		}
+		if l != nil {
+			return
+		}
+	}
+}
+
+func (s *Service) anyObjectExists(ctx context.Context, names []string) (bool, error) {
+	for _, name := range names {
+		_, err := s.storage.GetObjectMetadata(ctx, name)
+		if err == nil {
+			return true, nil
+		}
+		if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// renewLeaseUntilReleased renews l until the returned function is called, which releases l.
+func (s *Service) renewLeaseUntilReleased(l *lease.Lease) (release func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.downloadLeaseTimeToLive / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := l.Renew(ctx); err != nil {
+				// Losing the lease only risks a duplicate download.
+				if ctx.Err() == nil {
+					log.Warnf("error renewing lease: %v", err)
+				}
+				if internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		s.releaseLease(l)
+	}
+}
@@ -0,0 +1,86 @@
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/lease"
+)
+
+func withTestDownloadLease(s *Service) {
+	s.downloadLeasePollInterval = time.Millisecond
+	s.downloadLeaseTimeToLive = time.Minute
+}
+
+func Test_AcquireDownloadLease(t *testing.T) {
+	ctx := context.Background()
+	moduleVersion := &module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	leaseName := "lease/download/example.com/m@v1.0.0"
+
+	t.Run("StoredByOtherReplica", func(t *testing.T) {
+		s, storage := newTestService(t, withTestDownloadLease)
+		_, err := lease.Acquire(ctx, storage, leaseName, "b", time.Minute)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			assert.NoError(t, storage.CreateObjectExclusively(ctx, "concat/example.com/m@v1.0.0", nil, bytes.NewReader(nil)))
+		}()
+		l, fSeeStorage, err := s.acquireDownloadLease(ctx, moduleVersion, true)
+		require.NoError(t, err)
+		assert.True(t, fSeeStorage)
+		assert.Nil(t, l)
+	})
+
+	t.Run("Released", func(t *testing.T) {
+		s, storage := newTestService(t, withTestDownloadLease)
+		other, err := lease.Acquire(ctx, storage, leaseName, "b", time.Minute)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			assert.NoError(t, other.Release(ctx))
+		}()
+		l, fSeeStorage, err := s.acquireDownloadLease(ctx, moduleVersion, true)
+		require.NoError(t, err)
+		assert.False(t, fSeeStorage)
+		require.NotNil(t, l)
+		s.releaseLease(l)
+		_, err = storage.GetObjectMetadata(ctx, leaseName)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		s, storage := newTestService(t, withTestDownloadLease)
+		_, err := lease.Acquire(ctx, storage, leaseName, "b", time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+		l, fSeeStorage, err := s.acquireDownloadLease(ctx, moduleVersion, true)
+		require.NoError(t, err)
+		assert.False(t, fSeeStorage)
+		assert.NotNil(t, l)
+	})
+
+	t.Run("ContextDone", func(t *testing.T) {
+		s, storage := newTestService(t, withTestDownloadLease)
+		_, err := lease.Acquire(ctx, storage, leaseName, "b", time.Minute)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, _, err = s.acquireDownloadLease(ctx, moduleVersion, true)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("NonCanonicalVersion", func(t *testing.T) {
+		s, _ := newTestService(t, withTestDownloadLease)
+		l, fSeeStorage, err := s.acquireDownloadLease(ctx, &module.Version{Path: "example.com/m", Version: "master"}, true)
+		require.NoError(t, err)
+		assert.False(t, fSeeStorage)
+		assert.Nil(t, l)
+	})
+}
@@ -0,0 +1,110 @@
+package gocmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	module "golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+	modzip "golang.org/x/mod/zip"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/modproxyclient"
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+// fetchPublicGoModule downloads the public module version moduleVersion into tempGoEnv from the parent proxy, without
+// running a Go command. Like "go mod download", fetchPublicGoModule validates the zip file and verifies the hashes of the
+// go.mod file and the zip file against the checksum database.
+// Unlike "go mod download" (with GOPROXY=<parent proxy>,direct), fetchPublicGoModule does not fall back to downloading
+// directly from the version control system if the parent proxy does not have moduleVersion.
+func (s *Service) fetchPublicGoModule(ctx context.Context, tempGoEnv *tempGoEnv, moduleVersion *module.Version) (
+	info *gomoduleservice.Info, downloadInfo *goModuleInfo, err error) {
+	info, goMod, err := s.fetchPublicInfoAndGoMod(ctx, moduleVersion)
+	if err != nil {
+		return
+	}
+	moduleVersionCanonical := module.Version{Path: moduleVersion.Path, Version: info.Version}
+	downloadInfo = &goModuleInfo{
+		GoMod:   filepath.Join(tempGoEnv.TmpDir, "download.mod"),
+		Path:    moduleVersionCanonical.Path,
+		Time:    info.Time,
+		Version: info.Version,
+		Zip:     filepath.Join(tempGoEnv.TmpDir, "download.zip"),
+	}
+	if err = os.WriteFile(downloadInfo.GoMod, goMod, 0600); err != nil {
+		return
+	}
+	zipFD, err := os.OpenFile(downloadInfo.Zip, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	_, err = modproxyclient.Zip(ctx, s.parentProxyURL, s.httpClient, &moduleVersionCanonical, zipFD)
+	err2 := zipFD.Close()
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		return
+	}
+	checkedFiles, err := modzip.CheckZip(moduleVersionCanonical, downloadInfo.Zip)
+	if err == nil {
+		err = checkedFiles.Err()
+	}
+	if err != nil {
+		err = fmt.Errorf("zip of %s from parent proxy is invalid: %w", moduleVersionCanonical.String(), err)
+		return
+	}
+	downloadInfo.Sum, err = dirhash.HashZip(downloadInfo.Zip, dirhash.Hash1)
+	if err != nil {
+		return
+	}
+	downloadInfo.GoModSum, err = hashGoMod(goMod)
+	if err != nil {
+		return
+	}
+	err = s.publicModulesSumDBClient.Verify(moduleVersionCanonical, downloadInfo.Sum, downloadInfo.GoModSum)
+	return
+}
+
+// fetchPublicGoMod is like fetchPublicGoModule but only fetches the .info and the go.mod file of moduleVersion (and not
+// the zip file). The hash of the go.mod file is verified against the checksum database.
+func (s *Service) fetchPublicGoMod(ctx context.Context, moduleVersion *module.Version) (info *gomoduleservice.Info,
+	goMod []byte, goModSum string, err error) {
+	info, goMod, err = s.fetchPublicInfoAndGoMod(ctx, moduleVersion)
+	if err != nil {
+		return
+	}
+	goModSum, err = hashGoMod(goMod)
+	if err != nil {
+		return
+	}
+	err = s.publicModulesSumDBClient.VerifyGoMod(module.Version{Path: moduleVersion.Path, Version: info.Version}, goModSum)
+	return
+}
+
+func (s *Service) fetchPublicInfoAndGoMod(ctx context.Context, moduleVersion *module.Version) (info *gomoduleservice.Info,
+	goMod []byte, err error) {
+	info, err = modproxyclient.Info(ctx, s.parentProxyURL, s.httpClient, moduleVersion)
+	if err != nil {
+		return
+	}
+	if info.Time == (time.Time{}) {
+		err = fmt.Errorf("parent proxy responded with .info of %s that does not set .Time", moduleVersion.String())
+		return
+	}
+	// Do not index non-canonical versions.
+	if info.Version == "" || module.CanonicalVersion(info.Version) != info.Version {
+		err = fmt.Errorf("parent proxy responded with .info of %s that has a non-canonical .Version %#v", moduleVersion.String(),
+			info.Version)
+		return
+	}
+	moduleVersionCanonical := module.Version{Path: moduleVersion.Path, Version: info.Version}
+	if err = module.Check(moduleVersionCanonical.Path, moduleVersionCanonical.Version); err != nil {
+		return
+	}
+	goMod, err = modproxyclient.GoMod(ctx, s.parentProxyURL, s.httpClient, &moduleVersionCanonical)
+	return
+}
@@ -0,0 +1,152 @@
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/mod/sumdb/note"
+	modzip "golang.org/x/mod/zip"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/sumdbclient"
+)
+
+// testPublicModule is the module version example.com/m@v1.0.0 served by a fake parent proxy and a checksum database.
+type testPublicModule struct {
+	GoMod       []byte
+	GoModSum    string
+	Info        string
+	SumDBClient *sumdbclient.Client
+	Zip         []byte
+	ZipSum      string
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newTestPublicModule(t *testing.T) *testPublicModule {
+	moduleVersion := module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	m := &testPublicModule{
+		GoMod:    []byte("module example.com/m\n"),
+		Info:     `{"Version":"v1.0.0","Time":"2020-01-02T03:04:05Z"}`,
+		requests: map[string]int{},
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), m.GoMod, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte("package m\n"), 0600))
+	var zip bytes.Buffer
+	require.NoError(t, modzip.CreateFromDir(&zip, moduleVersion, dir))
+	m.Zip = zip.Bytes()
+	zipFile := filepath.Join(t.TempDir(), "m.zip")
+	require.NoError(t, os.WriteFile(zipFile, m.Zip, 0600))
+	var err error
+	m.ZipSum, err = dirhash.HashZip(zipFile, dirhash.Hash1)
+	require.NoError(t, err)
+	m.GoModSum, err = hashGoMod(m.GoMod)
+	require.NoError(t, err)
+
+	sumDBName := "sum.example.com"
+	signerKey, verifierKey, err := note.GenerateKey(rand.Reader, sumDBName)
+	require.NoError(t, err)
+	sumDBServer := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(signerKey, func(path, version string) ([]byte, error) {
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod %s\n", path, version, m.ZipSum, path, version, m.GoModSum)), nil
+	})))
+	t.Cleanup(sumDBServer.Close)
+	sumDBURL, err := url.Parse(sumDBServer.URL)
+	require.NoError(t, err)
+	m.SumDBClient, err = sumdbclient.NewClient(sumdbclient.ClientOptions{
+		HTTPClient: http.DefaultClient,
+		Name:       sumDBName,
+		PublicKey:  strings.TrimPrefix(verifierKey, sumDBName+"+"),
+		URL:        sumDBURL,
+	})
+	require.NoError(t, err)
+	return m
+}
+
+// newParentProxy starts a fake parent proxy that serves m and returns its URL (with a trailing slash).
+func (m *testPublicModule) newParentProxy(t *testing.T) string {
+	parentProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.requests[req.URL.Path]++
+		switch req.URL.Path {
+		case "/example.com/m/@v/v1.0.0.info":
+			_, _ = w.Write([]byte(m.Info))
+		case "/example.com/m/@v/v1.0.0.mod":
+			_, _ = w.Write(m.GoMod)
+		case "/example.com/m/@v/v1.0.0.zip":
+			_, _ = w.Write(m.Zip)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(parentProxy.Close)
+	return parentProxy.URL + "/"
+}
+
+func (m *testPublicModule) zipRequests() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests["/example.com/m/@v/v1.0.0.zip"]
+}
+
+func Test_FetchPublicGoModule(t *testing.T) {
+	ctx := context.Background()
+	moduleVersion := module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	for _, c := range []struct {
+		name          string
+		goMod         []byte
+		expectedError string
+	}{
+		{"Success", nil, ""},
+		{"Tampered", []byte("module example.com/m\n\ngo 1.20\n"), "SECURITY ERROR"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestPublicModule(t)
+			s := &Service{
+				httpClient:               http.DefaultClient,
+				parentProxyURL:           m.newParentProxy(t),
+				publicModulesSumDBClient: m.SumDBClient,
+			}
+			goMod, zip := m.GoMod, m.Zip
+			if c.goMod != nil {
+				m.mu.Lock()
+				m.GoMod = c.goMod
+				m.mu.Unlock()
+			}
+			info, downloadInfo, err := s.fetchPublicGoModule(ctx, &tempGoEnv{TmpDir: t.TempDir()}, &moduleVersion)
+			if c.expectedError != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), c.expectedError)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), info.Time.UTC())
+			assert.Equal(t, m.ZipSum, downloadInfo.Sum)
+			assert.Equal(t, m.GoModSum, downloadInfo.GoModSum)
+			downloadedGoMod, err := os.ReadFile(downloadInfo.GoMod)
+			require.NoError(t, err)
+			assert.Equal(t, goMod, downloadedGoMod)
+			downloadedZip, err := os.ReadFile(downloadInfo.Zip)
+			require.NoError(t, err)
+			assert.Equal(t, zip, downloadedZip)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package gocmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// hashGoMod returns the h1: hash of a go.mod file as reported by "go mod download -json" (.GoModSum) and as recorded in
+// go.sum files.
+func hashGoMod(goMod []byte) (string, error) {
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(goMod)), nil
+	})
+}
+
+// verifyGoMod returns an error if goMod does not have the h1: hash wantSum. description identifies goMod in the error.
+func verifyGoMod(goMod []byte, wantSum, description string) error {
+	sum, err := hashGoMod(goMod)
+	if err != nil {
+		return err
+	}
+	if sum != wantSum {
+		err = fmt.Errorf("%s is corrupt: expected hash %s but got %s", description, wantSum, sum)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// hashVerifyingReader computes the SHA-256 hash of the data read from r and compares it with an expected hash once r
+// returns io.EOF. The last byte read from r is held back until then, so that a reader that knows the size of the data
+// (i.e. io.CopyN) does not receive all data unless the data is intact.
+type hashVerifyingReader struct {
+	description string
+	eof         bool
+	err         error
+	hash        hash.Hash
+	hasLast     bool
+	last        byte
+	one         [1]byte
+	r           io.ReadCloser
+	wantSHA256  string
+}
+
+// newHashVerifyingReader returns an io.ReadCloser that reads r and fails with an error if the data of r does not have
+// the hex-encoded SHA-256 hash wantSHA256. description identifies the data in the error.
+func newHashVerifyingReader(r io.ReadCloser, wantSHA256, description string) *hashVerifyingReader {
+	return &hashVerifyingReader{
+		description: description,
+		hash:        sha256.New(),
+		r:           r,
+		wantSHA256:  wantSHA256,
+	}
+}
+
+func (h *hashVerifyingReader) Read(p []byte) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if h.eof {
+			if h.hasLast {
+				p[0] = h.last
+				h.hasLast = false
+				return 1, nil
+			}
+			return 0, io.EOF
+		}
+		var m, n int
+		var err error
+		switch {
+		case !h.hasLast:
+			n, err = h.r.Read(p)
+			h.hash.Write(p[:n])
+			if n > 0 {
+				h.last = p[n-1]
+				h.hasLast = true
+				m = n - 1
+			}
+		case len(p) == 1:
+			n, err = h.r.Read(h.one[:])
+			h.hash.Write(h.one[:n])
+			if n > 0 {
+				p[0] = h.last
+				h.last = h.one[0]
+				m = 1
+			}
+		default:
+			n, err = h.r.Read(p[1:])
+			h.hash.Write(p[1 : 1+n])
+			if n > 0 {
+				p[0] = h.last
+				h.last = p[n]
+				m = n
+			}
+		}
+		if err == io.EOF {
+			h.eof = true
+			if sha256Hex := hex.EncodeToString(h.hash.Sum(nil)); sha256Hex != h.wantSHA256 {
+				h.err = fmt.Errorf("%s is corrupt: expected SHA-256 hash %s but got %s", h.description, h.wantSHA256, sha256Hex)
+				log.Error(h.err)
+				return m, h.err
+			}
+		} else if err != nil {
+			h.err = err
+			return m, err
+		}
+		if m > 0 {
+			return m, nil
+		}
+	}
+}
+
+func (h *hashVerifyingReader) Close() error {
+	return h.r.Close()
+}
@@ -0,0 +1,40 @@
+package gocmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HashVerifyingReader(t *testing.T) {
+	data := []byte("0123456789")
+	sum := sha256.Sum256(data)
+	sumHex := hex.EncodeToString(sum[:])
+	identity := func(r io.Reader) io.Reader { return r }
+	for _, c := range []struct {
+		Name  string
+		Inner func(io.Reader) io.Reader
+		Outer func(io.Reader) io.Reader
+	}{
+		{"Default", identity, identity},
+		{"OneByte", iotest.OneByteReader, iotest.OneByteReader},
+		{"DataErr", iotest.DataErrReader, identity},
+	} {
+		t.Run(c.Name, func(t *testing.T) {
+			r := newHashVerifyingReader(io.NopCloser(c.Inner(bytes.NewReader(data))), sumHex, "data")
+			actual, err := io.ReadAll(c.Outer(r))
+			assert.NoError(t, err)
+			assert.Equal(t, data, actual)
+
+			r = newHashVerifyingReader(io.NopCloser(c.Inner(bytes.NewReader(data[1:]))), sumHex, "data")
+			actual, err = io.ReadAll(c.Outer(r))
+			assert.Error(t, err)
+			assert.Equal(t, data[1:len(data)-1], actual, "last byte must be held back")
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package gocmd
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	module "golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+)
+
+// IndexModuleVersion stores moduleVersion of a private module (including its zip file) unless it has already been stored,
+// so that it does not have to be fetched when it is first requested (i.e. right after a tag has been pushed). Cached
+// outcomes of lookups of the module are invalidated, so that the new version is listed right away. Results cached in
+// memory are only invalidated in this process. Returns an error e such that
+// "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound) is true if the module is not private or
+// the module version does not exist.
+func (s *Service) IndexModuleVersion(ctx context.Context, moduleVersion *module.Version) error {
+	if s.getPrivateModulesElement(moduleVersion.Path) == nil {
+		return internalErrors.NewErrorf(internalErrors.NotFound, "module %s is not a private module", moduleVersion.Path)
+	}
+	if _, err := s.versionPreamble(moduleVersion.Version, false); err != nil {
+		return err
+	}
+	defer s.invalidateModule(ctx, moduleVersion)
+	suffix := moduleVersion.Path + "@" + moduleVersion.Version
+	fSeeStorage, err := s.anyObjectExists(ctx, []string{storageConcatObjNamePrefix + suffix, storageZipObjNamePrefix + suffix})
+	if err != nil || fSeeStorage {
+		return err
+	}
+	f, err := s.fetchGoModuleCoalesced(ctx, moduleVersion)
+	if err != nil {
+		return err
+	}
+	f.release()
+	log.Infof("indexed module version %s", moduleVersion.String())
+	return nil
+}
+
+// invalidateModule removes cached outcomes of lookups of the module of moduleVersion that may have changed because
+// moduleVersion was created.
+func (s *Service) invalidateModule(ctx context.Context, moduleVersion *module.Version) {
+	s.listCache.memory.Remove(listCacheKeyPrefixLatest + moduleVersion.Path)
+	s.listCache.memory.Remove(listCacheKeyPrefixList + moduleVersion.Path)
+	if s.negativeCache == nil {
+		return
+	}
+	for _, key := range []string{
+		negativeCacheKeyPrefixLatest + moduleVersion.Path,
+		negativeCacheKeyPrefixList + moduleVersion.Path,
+		negativeCacheKeyPrefixMod + moduleVersion.String(),
+		negativeCacheKeyPrefixZip + moduleVersion.String(),
+	} {
+		s.negativeCacheRemove(ctx, key)
+	}
+}
@@ -0,0 +1,37 @@
+package gocmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+)
+
+func Test_IndexModuleVersion(t *testing.T) {
+	ctx := context.Background()
+	s, storage := newTestService(t, withTestNegativeCache, func(s *Service) {
+		s.listCache = newListCache()
+	})
+
+	err := s.IndexModuleVersion(ctx, &module.Version{Path: "example.com/m", Version: "v1.0.0"})
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+
+	// Cached outcomes of lookups of the module are invalidated even if the module version has already been stored.
+	moduleVersion := &module.Version{Path: "private.example.com/m", Version: "v1.0.0"}
+	err = storage.CreateObjectExclusively(ctx, storageConcatObjNamePrefix+moduleVersion.String(), nil, strings.NewReader(""))
+	require.NoError(t, err)
+	s.listCache.memory.Add(listCacheKeyPrefixList+moduleVersion.Path, &listCacheEntry{}, 1)
+	s.negativeCacheAdd(ctx, negativeCacheKeyPrefixZip+moduleVersion.String(), &negativeCacheEntry{})
+	require.NoError(t, s.IndexModuleVersion(ctx, moduleVersion))
+	_, ok := s.listCache.memory.Get(listCacheKeyPrefixList + moduleVersion.Path)
+	assert.False(t, ok)
+	_, ok = s.negativeCache.memory.Get(negativeCacheKeyPrefixZip + moduleVersion.String())
+	assert.False(t, ok)
+	_, err = storage.GetObjectMetadata(ctx, storageNotFoundObjNamePrefix+negativeCacheKeyPrefixZip+moduleVersion.String())
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+}
@@ -0,0 +1,236 @@
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	module "golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+// getGoModAndIndexIfNeeded fetches the .info and the go.mod file of moduleVersion (but not the zip file) and stores them
+// unless they have already been stored, in which case fSeeStorage is true. getGoModAndIndexIfNeeded must only be called
+// if s.fetchZipsLazily is true (see storeGoModObj).
+func (s *Service) getGoModAndIndexIfNeeded(ctx context.Context, tempGoEnv *tempGoEnv, moduleVersion *module.Version) (
+	fSeeStorage bool, info *gomoduleservice.Info, goMod []byte, err error) {
+	var goModSum string
+	if s.publicModulesSumDBClient != nil && s.getPrivateModulesElement(moduleVersion.Path) == nil {
+		info, goMod, goModSum, err = s.fetchPublicGoMod(ctx, moduleVersion)
+	} else {
+		info, goMod, goModSum, err = s.listGoModule(ctx, tempGoEnv, moduleVersion)
+	}
+	if err != nil {
+		return
+	}
+	fSeeStorage, err = s.storeGoModObj(ctx, module.Version{Path: moduleVersion.Path, Version: info.Version}, info.Time,
+		goMod, goModSum)
+	return
+}
+
+// listGoModule fetches the .info and the go.mod file of moduleVersion using a "go list -m" command, which (unlike "go mod
+// download") does not download the zip file. The Go command verifies the go.mod file against the checksum database (if
+// moduleVersion is public) or fetches it directly from the version control system (if moduleVersion is private).
+func (s *Service) listGoModule(ctx context.Context, tempGoEnv *tempGoEnv, moduleVersion *module.Version) (
+	info *gomoduleservice.Info, goMod []byte, goModSum string, err error) {
+	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
+	if err != nil {
+		return
+	}
+	defer runCmdResource.release()
+	releaseVCSCache, err := s.initializeTempGoEnvForModule(ctx, tempGoEnv, moduleVersion.Path)
+	if err != nil {
+		return
+	}
+	defer releaseVCSCache()
+	args := []string{s.goBinFile, "list", "-m", "-json", moduleVersion.Path + "@" + moduleVersion.Version}
+	stdout, strLog, err := s.runCmd(ctx, tempGoEnv, args)
+	if err != nil {
+		err = goCmdNotFoundError(err, strLog)
+		return
+	}
+	releaseVCSCache()
+	runCmdResource.release()
+	listInfo := &goModuleInfo{}
+	err = util.UnmarshalJSON(bytes.NewReader(stdout), listInfo, false)
+	if err != nil {
+		err = fmt.Errorf("command %s succeeded but got unexpected stderr/stdout:\n%s", formatArgs(args), strLog)
+		return
+	}
+	if listInfo.Error != nil {
+		err = fmt.Errorf("command %s succeeded but got unexpected error loading module:\n%s", formatArgs(args), strLog)
+		return
+	}
+	if listInfo.Time == (time.Time{}) {
+		err = fmt.Errorf("command %s succeeded but output does not set .Time or sets .Time to an invalid value", formatArgs(args))
+		return
+	}
+	// Do not index non-canonical versions (see downloadGoModule).
+	if listInfo.Version == "" || module.CanonicalVersion(listInfo.Version) != listInfo.Version {
+		err = fmt.Errorf("command %s succeeded but output has non-canonical .Version %#v", formatArgs(args), listInfo.Version)
+		return
+	}
+	if listInfo.GoMod == "" {
+		err = fmt.Errorf("command %s succeeded but output does not set .GoMod", formatArgs(args))
+		return
+	}
+	goMod, err = os.ReadFile(listInfo.GoMod)
+	if err != nil {
+		err = fmt.Errorf(`unexpected error reading .mod file created by %s command: %w`, formatArgs(args), err)
+		return
+	}
+	goModSum, err = hashGoMod(goMod)
+	if err != nil {
+		return
+	}
+	info = &gomoduleservice.Info{
+		Time:    listInfo.Time,
+		Version: listInfo.Version,
+	}
+	return
+}
+
+// storeGoModObj creates the goMod obj of moduleVersion (without a zip obj) unless moduleVersion has already been stored,
+// in which case fSeeStorage is true. If s.fetchZipsLazily is true then the goMod obj is the object that determines the
+// copy of moduleVersion that is served, and a zip obj is only created if it has the same copy (see storeGoModuleLazily).
+func (s *Service) storeGoModObj(ctx context.Context, moduleVersion module.Version, commitTime time.Time, goMod []byte,
+	goModSum string) (fSeeStorage bool, err error) {
+	// A concat obj determines the copy of moduleVersion if it was stored while s.fetchZipsLazily was false.
+	_, err = s.storage.GetObjectMetadata(ctx, storageConcatObjNamePrefix+moduleVersion.Path+"@"+moduleVersion.Version)
+	if err == nil {
+		fSeeStorage = true
+		return
+	}
+	if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		return
+	}
+	name := storageGoModObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	metadata := storage.ObjectMetadata{
+		storageGoModObjCommitTimeMetadataKey: commitTime.UTC().Format(time.RFC3339),
+		storageGoModObjSumMetadataKey:        goModSum,
+	}
+	err = s.storage.CreateObjectExclusively(ctx, name, metadata, bytes.NewReader(goMod))
+	if err != nil {
+		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return
+		}
+		err = nil
+		fSeeStorage = true
+	} else {
+		log.Infof("stored object %#v", name)
+	}
+	return
+}
+
+// storeGoModuleLazily is the counterpart of storeGoModule if s.fetchZipsLazily is true. It stores the goMod obj of the
+// module version downloaded into tempGoEnv (see storeGoModObj) and then stores its zip obj. If the goMod obj had already
+// been stored then the zip obj is only stored if the downloaded module version is the same copy (i.e. has the same commit
+// time and go.mod file), so that .info, .mod and .zip never reflect different copies.
+func (s *Service) storeGoModuleLazily(ctx context.Context, modulePath string, info *gomoduleservice.Info,
+	downloadInfo *goModuleInfo) (fSeeStorage bool, err error) {
+	moduleVersion := module.Version{Path: modulePath, Version: info.Version}
+	goMod, err := os.ReadFile(downloadInfo.GoMod)
+	if err != nil {
+		err = fmt.Errorf(`unexpected error reading .mod file %#v: %w`, downloadInfo.GoMod, err)
+		return
+	}
+	fSeeStorage, err = s.storeGoModObj(ctx, moduleVersion, info.Time, goMod, downloadInfo.GoModSum)
+	if err != nil {
+		return
+	}
+	if fSeeStorage {
+		var isConcatObj bool
+		isConcatObj, err = s.checkGoModObjIsSameCopy(ctx, moduleVersion, info.Time, downloadInfo.GoModSum)
+		if err != nil || isConcatObj {
+			return
+		}
+	}
+	zipFD, err := os.Open(downloadInfo.Zip)
+	if err != nil {
+		err = fmt.Errorf(`unexpected error opening .zip file %#v: %w`, downloadInfo.Zip, err)
+		return
+	}
+	defer func() {
+		if err2 := zipFD.Close(); err2 != nil {
+			log.Errorf("error closing %#v: %v", zipFD.Name(), err2)
+		}
+	}()
+	zipHash := sha256.New()
+	if _, err = io.Copy(zipHash, zipFD); err != nil {
+		err = fmt.Errorf(`unexpected error reading .zip file %#v: %w`, downloadInfo.Zip, err)
+		return
+	}
+	if err = fdSeekToStart(zipFD); err != nil {
+		return
+	}
+	name := storageZipObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	metadata := storage.ObjectMetadata{
+		storageZipObjSHA256MetadataKey: hex.EncodeToString(zipHash.Sum(nil)),
+		storageZipObjSumMetadataKey:    downloadInfo.Sum,
+	}
+	err = s.storage.CreateObjectExclusively(ctx, name, metadata, zipFD)
+	if err != nil {
+		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return
+		}
+		err = nil
+		fSeeStorage = true
+	} else {
+		log.Infof("stored object %#v", name)
+		fSeeStorage = false
+	}
+	return
+}
+
+// checkGoModObjIsSameCopy returns an error if the stored goMod obj of moduleVersion does not have commit time commitTime
+// and hash goModSum. If moduleVersion has been stored as a concat obj instead then isConcatObj is true.
+func (s *Service) checkGoModObjIsSameCopy(ctx context.Context, moduleVersion module.Version, commitTime time.Time,
+	goModSum string) (isConcatObj bool, err error) {
+	name := storageGoModObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	metadata, err := s.storage.GetObjectMetadata(ctx, name)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			return true, nil
+		}
+		return
+	}
+	storedGoModSum := metadata[storageGoModObjSumMetadataKey]
+	if storedGoModSum == "" {
+		var data io.ReadCloser
+		data, err = s.storage.GetObject(ctx, name)
+		if err != nil {
+			return
+		}
+		var goMod []byte
+		goMod, err = io.ReadAll(data)
+		err2 := data.Close()
+		if err == nil {
+			err = err2
+		}
+		if err != nil {
+			return
+		}
+		storedGoModSum, err = hashGoMod(goMod)
+		if err != nil {
+			return
+		}
+	}
+	storedCommitTime := metadata[storageGoModObjCommitTimeMetadataKey]
+	if storedGoModSum != goModSum || storedCommitTime != commitTime.UTC().Format(time.RFC3339) {
+		err = fmt.Errorf("not storing zip of %s because it was downloaded from a different copy (commit time %s, go.mod "+
+			"hash %s) than the stored copy (commit time %s, go.mod hash %s)", moduleVersion.String(),
+			commitTime.UTC().Format(time.RFC3339), goModSum, storedCommitTime, storedGoModSum)
+		log.Error(err)
+	}
+	return
+}
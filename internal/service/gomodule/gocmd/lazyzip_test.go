@@ -0,0 +1,90 @@
+package gocmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+)
+
+// withTestFetchZipsLazily returns an option of newTestService that makes the Service fetch zips of public modules lazily
+// from a fake parent proxy that serves m.
+func withTestFetchZipsLazily(t *testing.T, m *testPublicModule) func(s *Service) {
+	return func(s *Service) {
+		s.fetchZipsLazily = true
+		s.httpClient = http.DefaultClient
+		s.parentProxyURL = m.newParentProxy(t)
+		s.publicModulesSumDBClient = m.SumDBClient
+		s.tempGoEnvBaseEnviron = getTempGoEnvBaseEnviron()
+		s.fetchCoalescer = newFetchCoalescer(s.newTempGoEnv)
+	}
+}
+
+func Test_FetchZipsLazily(t *testing.T) {
+	ctx := context.Background()
+	m := newTestPublicModule(t)
+	s, storage := newTestService(t, withTestFetchZipsLazily(t, m))
+	moduleVersion := &module.Version{Path: "example.com/m", Version: "v1.0.0"}
+
+	data, err := s.GoMod(ctx, moduleVersion)
+	require.NoError(t, err)
+	goMod, err := io.ReadAll(data)
+	require.NoError(t, err)
+	assert.NoError(t, data.Close())
+	assert.Equal(t, m.GoMod, goMod)
+	assert.Equal(t, 0, m.zipRequests())
+	metadata, err := storage.GetObjectMetadata(ctx, "gomod/example.com/m@v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-01-02T03:04:05Z", metadata[storageGoModObjCommitTimeMetadataKey])
+	assert.Equal(t, m.GoModSum, metadata[storageGoModObjSumMetadataKey])
+	_, err = storage.GetObjectMetadata(ctx, "zip/example.com/m@v1.0.0")
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+
+	info, err := s.Info(ctx, moduleVersion)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), info.Time.UTC())
+	assert.Equal(t, 0, m.zipRequests())
+
+	for i := 0; i < 2; i++ {
+		data, size, err := s.ZipRange(ctx, moduleVersion, 0, -1)
+		require.NoError(t, err)
+		zip, err := io.ReadAll(data)
+		require.NoError(t, err)
+		assert.NoError(t, data.Close())
+		assert.Equal(t, m.Zip, zip)
+		assert.Equal(t, int64(len(m.Zip)), size)
+	}
+	assert.Equal(t, 1, m.zipRequests())
+	metadata, err = storage.GetObjectMetadata(ctx, "zip/example.com/m@v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, m.ZipSum, metadata[storageZipObjSumMetadataKey])
+}
+
+func Test_FetchZipsLazily_DifferentCopy(t *testing.T) {
+	ctx := context.Background()
+	m := newTestPublicModule(t)
+	s, storage := newTestService(t, withTestFetchZipsLazily(t, m))
+	moduleVersion := &module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	data, err := s.GoMod(ctx, moduleVersion)
+	require.NoError(t, err)
+	assert.NoError(t, data.Close())
+
+	// The parent proxy now serves a different copy of the module version.
+	m.mu.Lock()
+	m.Info = `{"Version":"v1.0.0","Time":"2021-01-02T03:04:05Z"}`
+	m.mu.Unlock()
+	_, _, err = s.ZipRange(ctx, moduleVersion, 0, -1)
+	assert.Error(t, err)
+	_, err = storage.GetObjectMetadata(ctx, "zip/example.com/m@v1.0.0")
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+	info, err := s.Info(ctx, moduleVersion)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), info.Time.UTC())
+}
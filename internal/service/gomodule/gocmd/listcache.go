@@ -0,0 +1,116 @@
+package gocmd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/lru"
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+// listCacheMaxEntries bounds the number of results cached by listCache.
+const listCacheMaxEntries = 10000
+
+// Keys of listCache are prefixed by the kind of lookup.
+const (
+	listCacheKeyPrefixLatest = "latest/"
+	listCacheKeyPrefixList   = "list/"
+)
+
+type listCacheEntry struct {
+	fetched time.Time
+	value   any
+}
+
+// listCache caches results of lookups that run Go commands to list the versions of a module or to determine the latest
+// version of a module. Stale results are used while they are revalidated in the background (see config.ListCache).
+//
+// listCache must only cache results that are not required to be consistent with storage, such as the versions listed by
+// a "go list" command. The versions of stored module versions must be listed regardless of listCache, so that list after
+// read remains consistent.
+type listCache struct {
+	memory       *lru.Cache
+	mu           sync.Mutex
+	revalidating map[string]struct{}
+}
+
+func newListCache() *listCache {
+	return &listCache{
+		memory:       lru.New(listCacheMaxEntries, nil),
+		revalidating: map[string]struct{}{},
+	}
+}
+
+// withListCache returns the result of lookup identified by key, or a cached result of it. If cfg is nil then the result
+// is not cached. If ctx was returned by "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule".WithBypassCache
+// then lookup is always called (and its result is cached).
+func (s *Service) withListCache(ctx context.Context, key string, cfg *config.ListCache,
+	lookup func(ctx context.Context) (any, error)) (any, error) {
+	if cfg == nil {
+		return lookup(ctx)
+	}
+	if !gomoduleservice.BypassCache(ctx) {
+		if value, ok := s.listCache.memory.Get(key); ok {
+			entry := value.(*listCacheEntry)
+			age := time.Since(entry.fetched)
+			if age < cfg.TimeToLive {
+				return entry.value, nil
+			}
+			if age < cfg.TimeToLive+cfg.StaleWhileRevalidate {
+				s.revalidateListCacheEntry(key, lookup)
+				return entry.value, nil
+			}
+		}
+	}
+	value, err := lookup(ctx)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			s.listCache.memory.Remove(key)
+		}
+		return nil, err
+	}
+	s.listCache.memory.Add(key, &listCacheEntry{
+		fetched: time.Now(),
+		value:   value,
+	}, 1)
+	return value, nil
+}
+
+// revalidateListCacheEntry calls lookup in a new goroutine and caches its result, unless the entry of key is already being
+// revalidated.
+func (s *Service) revalidateListCacheEntry(key string, lookup func(ctx context.Context) (any, error)) {
+	s.listCache.mu.Lock()
+	if _, ok := s.listCache.revalidating[key]; ok {
+		s.listCache.mu.Unlock()
+		return
+	}
+	s.listCache.revalidating[key] = struct{}{}
+	s.listCache.mu.Unlock()
+	go func() {
+		defer func() {
+			s.listCache.mu.Lock()
+			delete(s.listCache.revalidating, key)
+			s.listCache.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+		defer cancel()
+		value, err := lookup(ctx)
+		if err != nil {
+			if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+				s.listCache.memory.Remove(key)
+			} else {
+				log.Errorf("error revalidating cached result %#v: %v", key, err)
+			}
+			return
+		}
+		s.listCache.memory.Add(key, &listCacheEntry{
+			fetched: time.Now(),
+			value:   value,
+		}, 1)
+	}()
+}
@@ -0,0 +1,86 @@
+package gocmd
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+func Test_WithListCache(t *testing.T) {
+	ctx := context.Background()
+	key := listCacheKeyPrefixList + "example.com/m"
+	newLookup := func(calls *int32) func(ctx context.Context) (any, error) {
+		return func(ctx context.Context) (any, error) {
+			n := atomic.AddInt32(calls, 1)
+			return []string{"v1.0." + string(rune('0'+n))}, nil
+		}
+	}
+
+	t.Run("Fresh", func(t *testing.T) {
+		s := &Service{listCache: newListCache()}
+		cfg := &config.ListCache{TimeToLive: time.Minute}
+		var calls int32
+		value, err := s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.0.1"}, value)
+		value, err = s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.0.1"}, value)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		value, err = s.withListCache(gomoduleservice.WithBypassCache(ctx), key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.0.2"}, value)
+	})
+
+	t.Run("StaleWhileRevalidate", func(t *testing.T) {
+		s := &Service{listCache: newListCache()}
+		cfg := &config.ListCache{TimeToLive: time.Millisecond, StaleWhileRevalidate: time.Minute}
+		var calls int32
+		_, err := s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+		value, err := s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.0.1"}, value)
+		require.Eventually(t, func() bool {
+			value, ok := s.listCache.memory.Get(key)
+			return ok && value.(*listCacheEntry).value.([]string)[0] == "v1.0.2"
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		s := &Service{listCache: newListCache()}
+		cfg := &config.ListCache{TimeToLive: time.Millisecond}
+		var calls int32
+		_, err := s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+		value, err := s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.0.2"}, value)
+	})
+
+	t.Run("NotFoundRemoves", func(t *testing.T) {
+		s := &Service{listCache: newListCache()}
+		cfg := &config.ListCache{TimeToLive: time.Millisecond}
+		var calls int32
+		_, err := s.withListCache(ctx, key, cfg, newLookup(&calls))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+		_, err = s.withListCache(ctx, key, cfg, func(ctx context.Context) (any, error) {
+			return nil, internalErrors.NewError(internalErrors.NotFound, "not found")
+		})
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+		_, ok := s.listCache.memory.Get(key)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,166 @@
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/lru"
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+// Keys of the negative cache are prefixed by the kind of lookup, because for example the zip file of a module version
+// can be missing while its go.mod file exists.
+const (
+	negativeCacheKeyPrefixLatest = "latest/"
+	negativeCacheKeyPrefixList   = "list/"
+	negativeCacheKeyPrefixMod    = "mod/"
+	negativeCacheKeyPrefixZip    = "zip/"
+)
+
+// negativeCacheEntry is a cached NotFound outcome. If shared via storage then negativeCacheEntry is JSON-marshalled as the
+// data of an object.
+type negativeCacheEntry struct {
+	Expires time.Time `json:"expires"`
+	Message string    `json:"message"`
+}
+
+// negativeCache caches NotFound outcomes of lookups (such as a module version that does not exist), so that repeating a
+// lookup does not run a Go command or send a request to the parent proxy until the outcome expires.
+type negativeCache struct {
+	memory                   *lru.Cache
+	privateModulesTimeToLive time.Duration
+	publicModulesTimeToLive  time.Duration
+	shareViaStorage          bool
+}
+
+func newNegativeCache(cfg *config.NegativeCache) *negativeCache {
+	return &negativeCache{
+		memory:                   lru.New(int64(cfg.MaxEntries), nil),
+		privateModulesTimeToLive: cfg.PrivateModulesTimeToLive,
+		publicModulesTimeToLive:  cfg.PublicModulesTimeToLive,
+		shareViaStorage:          cfg.ShareViaStorage,
+	}
+}
+
+// withNegativeCache calls f unless a NotFound outcome of the lookup identified by key is cached, in which case a NotFound
+// error is returned. If f returns a NotFound error then the outcome is cached. If ctx was returned by
+// "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule".WithBypassCache then f is always called.
+func (s *Service) withNegativeCache(ctx context.Context, modulePath, key string, f func() error) error {
+	if s.negativeCache == nil {
+		return f()
+	}
+	ttl := s.negativeCache.publicModulesTimeToLive
+	if s.getPrivateModulesElement(modulePath) != nil {
+		ttl = s.negativeCache.privateModulesTimeToLive
+	}
+	if ttl <= 0 {
+		return f()
+	}
+	bypassCache := gomoduleservice.BypassCache(ctx)
+	if !bypassCache {
+		if entry := s.negativeCacheGet(ctx, key); entry != nil {
+			log.Debugf("using cached not found outcome of %#v (expires %s)", key, entry.Expires.UTC().Format(time.RFC3339))
+			return internalErrors.NewError(internalErrors.NotFound, entry.Message)
+		}
+	}
+	err := f()
+	if err == nil {
+		if bypassCache {
+			s.negativeCacheRemove(ctx, key)
+		}
+	} else if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		s.negativeCacheAdd(ctx, key, &negativeCacheEntry{
+			Expires: time.Now().Add(ttl),
+			Message: err.Error(),
+		})
+	}
+	return err
+}
+
+// negativeCacheGet returns the unexpired entry of key, or nil if there is none. Errors of the storage are logged, because
+// the negative cache is an optimization.
+func (s *Service) negativeCacheGet(ctx context.Context, key string) *negativeCacheEntry {
+	if value, ok := s.negativeCache.memory.Get(key); ok {
+		entry := value.(*negativeCacheEntry)
+		if time.Now().Before(entry.Expires) {
+			return entry
+		}
+		s.negativeCache.memory.Remove(key)
+	}
+	if !s.negativeCache.shareViaStorage {
+		return nil
+	}
+	name := storageNotFoundObjNamePrefix + key
+	data, err := s.storage.GetObject(ctx, name)
+	if err != nil {
+		if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			log.Warnf("error reading object %#v: %v", name, err)
+		}
+		return nil
+	}
+	entry := &negativeCacheEntry{}
+	err = util.UnmarshalJSON(data, entry, true)
+	_ = data.Close()
+	if err != nil {
+		log.Warnf("error JSON-unmarshalling data of object %#v: %v", name, err)
+		return nil
+	}
+	if !time.Now().Before(entry.Expires) {
+		if err := s.storage.DeleteObject(ctx, name); err != nil && !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			log.Warnf("error deleting expired object %#v: %v", name, err)
+		}
+		return nil
+	}
+	s.negativeCache.memory.Add(key, entry, 1)
+	return entry
+}
+
+func (s *Service) negativeCacheAdd(ctx context.Context, key string, entry *negativeCacheEntry) {
+	s.negativeCache.memory.Add(key, entry, 1)
+	if !s.negativeCache.shareViaStorage {
+		return
+	}
+	name := storageNotFoundObjNamePrefix + key
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Errorf("error JSON-marshalling %T: %v", entry, err)
+		return
+	}
+	// Two attempts: the second attempt follows deleting an entry that expired or that was created by a request that did
+	// not bypass the cache.
+	for attempt := 0; attempt < 2; attempt++ {
+		err = s.storage.CreateObjectExclusively(ctx, name, nil, bytes.NewReader(data))
+		if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			break
+		}
+		if attempt == 0 {
+			if err = s.storage.DeleteObject(ctx, name); err != nil && !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+				break
+			}
+		} else {
+			// Created by another replica in the meantime.
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Warnf("error storing object %#v: %v", name, err)
+	}
+}
+
+func (s *Service) negativeCacheRemove(ctx context.Context, key string) {
+	s.negativeCache.memory.Remove(key)
+	if !s.negativeCache.shareViaStorage {
+		return
+	}
+	name := storageNotFoundObjNamePrefix + key
+	if err := s.storage.DeleteObject(ctx, name); err != nil && !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		log.Warnf("error deleting object %#v: %v", name, err)
+	}
+}
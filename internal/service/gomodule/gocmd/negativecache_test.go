@@ -0,0 +1,117 @@
+package gocmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+func withTestNegativeCache(s *Service) {
+	s.negativeCache = newNegativeCache(&config.NegativeCache{
+		MaxEntries:               10,
+		PrivateModulesTimeToLive: 0,
+		PublicModulesTimeToLive:  time.Minute,
+		ShareViaStorage:          true,
+	})
+	s.privateModules = []*config.PrivateModulesElement{
+		{PathPrefix: "private.example.com"},
+	}
+}
+
+func Test_WithNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	key := negativeCacheKeyPrefixMod + "example.com/m@v1.0.0"
+	notFound := func() error {
+		return internalErrors.NewErrorf(internalErrors.NotFound, "unknown revision v1.0.0")
+	}
+	mustNotBeCalled := func() error {
+		t.Fatal("lookup was not served from the negative cache")
+		return nil
+	}
+
+	t.Run("Cached", func(t *testing.T) {
+		s, storage := newTestService(t, withTestNegativeCache)
+		err := s.withNegativeCache(ctx, "example.com/m", key, notFound)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+		err = s.withNegativeCache(ctx, "example.com/m", key, mustNotBeCalled)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+		assert.EqualError(t, err, "unknown revision v1.0.0")
+
+		// Shared with another replica through the storage.
+		s2, _ := newTestService(t, withTestNegativeCache, func(s2 *Service) {
+			s2.storage = storage
+		})
+		err = s2.withNegativeCache(ctx, "example.com/m", key, mustNotBeCalled)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+	})
+
+	t.Run("OtherErrorsNotCached", func(t *testing.T) {
+		s, _ := newTestService(t, withTestNegativeCache)
+		err := s.withNegativeCache(ctx, "example.com/m", key, func() error {
+			return fmt.Errorf("connection reset by peer")
+		})
+		assert.Error(t, err)
+		called := false
+		err = s.withNegativeCache(ctx, "example.com/m", key, func() error {
+			called = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		s, storage := newTestService(t, withTestNegativeCache)
+		s.negativeCache.publicModulesTimeToLive = time.Millisecond
+		_ = s.withNegativeCache(ctx, "example.com/m", key, notFound)
+		time.Sleep(2 * time.Millisecond)
+		called := false
+		err := s.withNegativeCache(ctx, "example.com/m", key, func() error {
+			called = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, called)
+		_, err = storage.GetObjectMetadata(ctx, storageNotFoundObjNamePrefix+key)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+	})
+
+	t.Run("Bypass", func(t *testing.T) {
+		s, storage := newTestService(t, withTestNegativeCache)
+		_ = s.withNegativeCache(ctx, "example.com/m", key, notFound)
+		called := false
+		err := s.withNegativeCache(gomoduleservice.WithBypassCache(ctx), "example.com/m", key, func() error {
+			called = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, called)
+		// The fresh outcome replaced the cached outcome.
+		err = s.withNegativeCache(ctx, "example.com/m", key, func() error {
+			return nil
+		})
+		assert.NoError(t, err)
+		_, err = storage.GetObjectMetadata(ctx, storageNotFoundObjNamePrefix+key)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+	})
+
+	t.Run("DisabledForModuleClass", func(t *testing.T) {
+		s, _ := newTestService(t, withTestNegativeCache)
+		privateKey := negativeCacheKeyPrefixMod + "private.example.com/m@v1.0.0"
+		_ = s.withNegativeCache(ctx, "private.example.com/m", privateKey, notFound)
+		called := false
+		err := s.withNegativeCache(ctx, "private.example.com/m", privateKey, func() error {
+			called = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+}
@@ -0,0 +1,237 @@
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/lease"
+)
+
+// concatObjLeaseTimeToLive bounds how long a concat obj can take to be split into a goMod obj and a zip obj. If a holder
+// of a lease dies then no other replica splits the concat obj until the lease expires.
+const concatObjLeaseTimeToLive = 10 * time.Minute
+
+// newLeaseHolder returns a name that identifies this process as holder of leases.
+func newLeaseHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	var arr [8]byte
+	if _, err := rand.Read(arr[:]); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(arr[:]))
+}
+
+func (s *Service) acquireConcatObjLease(ctx context.Context, concatObjName string) (*lease.Lease, error) {
+	return lease.Acquire(ctx, s.storage, storageLeaseObjNamePrefix+concatObjName, s.leaseHolder, concatObjLeaseTimeToLive)
+}
+
+func (s *Service) releaseLease(l *lease.Lease) {
+	if err := l.Release(context.Background()); err != nil {
+		log.Errorf("error releasing lease: %v", err)
+	}
+}
+
+// RunReconciler completes the indexing of concat objs that was interrupted (i.e. because a process died while indexing),
+// so that reads do not need to parse concat objs. RunReconciler reconciles immediately and then every interval until
+// ctx is done.
+func (s *Service) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.reconcileConcatObjs(ctx); err != nil {
+			log.Errorf("error reconciling concat objs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) reconcileConcatObjs(ctx context.Context) error {
+	opts := storage.ObjectListOptions{NamePrefix: storageConcatObjNamePrefix}
+	n := 0
+	for {
+		objList, err := s.storage.ListObjects(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, name := range objList.Names {
+			if err := s.reconcileConcatObj(ctx, name); err != nil {
+				if internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+					log.Debugf("not reconciling concat obj %#v: %v", name, err)
+				} else {
+					log.Errorf("error reconciling concat obj %#v: %v", name, err)
+				}
+				continue
+			}
+			n++
+		}
+		if objList.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = objList.NextPageToken
+	}
+	if n > 0 {
+		log.Infof("reconciled %d concat obj(s)", n)
+	}
+	return nil
+}
+
+// reconcileConcatObj splits the concat obj named name into a goMod obj and a zip obj using the data of the concat obj, and
+// deletes the concat obj. The hashes of the go.mod file and the zip file are computed, so that they are also recorded
+// for version 1 concat objs. If the concat obj records hashes that do not match then the concat obj is left alone.
+func (s *Service) reconcileConcatObj(ctx context.Context, name string) (err error) {
+	moduleVersionStr := strings.TrimPrefix(name, storageConcatObjNamePrefix)
+	i := strings.LastIndexByte(moduleVersionStr, '@')
+	if i < 0 {
+		return fmt.Errorf("name of concat obj is invalid")
+	}
+	moduleVersion := module.Version{Path: moduleVersionStr[:i], Version: moduleVersionStr[i+1:]}
+	l, err := s.acquireConcatObjLease(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer s.releaseLease(l)
+	data, err := s.storage.GetObject(ctx, name)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			// Indexed in the meantime.
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		if err2 := data.Close(); err2 != nil {
+			log.Errorf("error closing %T: %v", data, err2)
+		}
+	}()
+	header, goModPrefix, goModToRead, zipPrefix, err := parseConcatObjCommon(data)
+	if err != nil {
+		return fmt.Errorf("error parsing concat obj's data: %w", err)
+	}
+	goMod := make([]byte, len(goModPrefix)+goModToRead)
+	copy(goMod, goModPrefix)
+	if _, err := io.ReadFull(data, goMod[len(goModPrefix):]); err != nil {
+		return fmt.Errorf("error reading concat obj's data: %w", err)
+	}
+	// The zip is buffered in a file because CreateObjectExclusively needs an io.ReadSeeker.
+	zipFD, err := os.CreateTemp(s.scratchDir, "concat-zip-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = zipFD.Close()
+		if err2 := os.Remove(zipFD.Name()); err2 != nil {
+			log.Errorf("error removing temporary file %#v: %v", zipFD.Name(), err2)
+		}
+	}()
+	zipHash := sha256.New()
+	zipWriter := io.MultiWriter(zipFD, zipHash)
+	if _, err := zipWriter.Write(zipPrefix); err != nil {
+		return err
+	}
+	if _, err := io.Copy(zipWriter, data); err != nil {
+		return fmt.Errorf("error reading concat obj's data: %w", err)
+	}
+	goModSum, err := hashGoMod(goMod)
+	if err != nil {
+		return err
+	}
+	zipSum, err := dirhash.HashZip(zipFD.Name(), dirhash.Hash1)
+	if err != nil {
+		return fmt.Errorf("error hashing zip of concat obj: %w", err)
+	}
+	zipSHA256 := hex.EncodeToString(zipHash.Sum(nil))
+	for _, x := range []struct{ name, want, got string }{
+		{"go.mod h1", header.GoModSum, goModSum},
+		{"zip h1", header.ZipSum, zipSum},
+		{"zip SHA-256", header.ZipSHA256, zipSHA256},
+	} {
+		if x.want != "" && x.want != x.got {
+			return fmt.Errorf("concat obj is corrupt: expected %s hash %s but got %s", x.name, x.want, x.got)
+		}
+	}
+	header.GoModSum = goModSum
+	header.ZipSum = zipSum
+	header.ZipSHA256 = zipSHA256
+	if err := fdSeekToStart(zipFD); err != nil {
+		return err
+	}
+	return s.splitConcatObj(ctx, moduleVersion, header, bytes.NewReader(goMod), zipFD)
+}
+
+// splitConcatObj creates the goMod obj and the zip obj of moduleVersion (unless they exist) and then deletes the
+// concat obj of moduleVersion. The hashes of header are recorded as metadata of the goMod obj and the zip obj.
+func (s *Service) splitConcatObj(ctx context.Context, moduleVersion module.Version, header *concatObjHeader, goMod,
+	zip io.ReadSeeker) error {
+	name := storageGoModObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	goModMetadata := storage.ObjectMetadata{
+		storageGoModObjCommitTimeMetadataKey: header.CommitTime.UTC().Format(time.RFC3339),
+	}
+	if header.GoModSum != "" {
+		goModMetadata[storageGoModObjSumMetadataKey] = header.GoModSum
+	}
+	err := s.storage.CreateObjectExclusively(ctx, name, goModMetadata, goMod)
+	if err != nil {
+		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return err
+		}
+		// The goMod obj may have been stored on its own (see storeGoModObj) by a replica that fetches zips lazily. Do not
+		// store a zip obj of a different copy.
+		metadata, err := s.storage.GetObjectMetadata(ctx, name)
+		if err != nil {
+			return err
+		}
+		if sum := metadata[storageGoModObjSumMetadataKey]; sum != "" && header.GoModSum != "" && sum != header.GoModSum {
+			return fmt.Errorf("goMod obj %#v has hash %s, which conflicts with hash %s of concat obj", name, sum,
+				header.GoModSum)
+		}
+	} else {
+		log.Infof("stored object %#v", name)
+	}
+	name = storageZipObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	zipMetadata := storage.ObjectMetadata{}
+	if header.ZipSum != "" {
+		zipMetadata[storageZipObjSumMetadataKey] = header.ZipSum
+	}
+	if header.ZipSHA256 != "" {
+		zipMetadata[storageZipObjSHA256MetadataKey] = header.ZipSHA256
+	}
+	err = s.storage.CreateObjectExclusively(ctx, name, zipMetadata, zip)
+	if err != nil {
+		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return err
+		}
+	} else {
+		log.Infof("stored object %#v", name)
+	}
+	name = storageConcatObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	err = s.storage.DeleteObject(ctx, name)
+	if err != nil {
+		if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			return err
+		}
+	} else {
+		log.Infof("deleted object %#v", name)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package gocmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	storagepkg "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/lease"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/memory"
+)
+
+// withTestPaginatedStorage returns an option of newTestService that replaces the storage by one that lists one object
+// per page, which tests pagination.
+func withTestPaginatedStorage(t *testing.T) func(s *Service) {
+	return func(s *Service) {
+		storage, err := memory.NewStorage(memory.StorageOptions{MaxPageSize: 1})
+		require.NoError(t, err)
+		s.storage = storage
+	}
+}
+
+func Test_ReconcileConcatObjs(t *testing.T) {
+	ctx := context.Background()
+	s, storage := newTestService(t, withTestPaginatedStorage(t))
+	commitTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	goMod := []byte("module example.com/m\n")
+	zip := newTestZip(t, "example.com/m@v1.0.0/go.mod", goMod)
+	for _, name := range []string{"concat/example.com/m@v1.0.0", "concat/example.com/m@v1.1.0"} {
+		require.NoError(t, storage.CreateObjectExclusively(ctx, name, nil, newTestConcatObj(t, commitTime, goMod, zip)))
+	}
+	// The goMod obj of v1.1.0 already exists, as if the process died after creating it.
+	require.NoError(t, storage.CreateObjectExclusively(ctx, "gomod/example.com/m@v1.1.0", nil, bytes.NewReader(goMod)))
+
+	require.NoError(t, s.reconcileConcatObjs(ctx))
+
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		_, err := storage.GetObjectMetadata(ctx, storageConcatObjNamePrefix+"example.com/m@"+version)
+		assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+		assert.Equal(t, goMod, readTestObject(t, storage, storageGoModObjNamePrefix+"example.com/m@"+version))
+		assert.Equal(t, zip, readTestObject(t, storage, storageZipObjNamePrefix+"example.com/m@"+version))
+	}
+	metadata, err := storage.GetObjectMetadata(ctx, "gomod/example.com/m@v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-01-02T03:04:05Z", metadata[storageGoModObjCommitTimeMetadataKey])
+	goModSum, err := hashGoMod(goMod)
+	require.NoError(t, err)
+	assert.Equal(t, goModSum, metadata[storageGoModObjSumMetadataKey])
+	metadata, err = storage.GetObjectMetadata(ctx, "zip/example.com/m@v1.0.0")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(metadata[storageZipObjSumMetadataKey], "h1:"), "%#v", metadata)
+	zipSHA256 := sha256.Sum256(zip)
+	assert.Equal(t, hex.EncodeToString(zipSHA256[:]), metadata[storageZipObjSHA256MetadataKey])
+}
+
+func Test_ReconcileConcatObjs_Corrupt(t *testing.T) {
+	ctx := context.Background()
+	s, storage := newTestService(t, withTestPaginatedStorage(t))
+	goMod := []byte("module example.com/m\n")
+	concatObj, err := io.ReadAll(newTestConcatObj(t, time.Now(), goMod, newTestZip(t, "example.com/m@v1.0.0/go.mod", goMod)))
+	require.NoError(t, err)
+	concatObj[len(concatObj)-1]++
+	name := "concat/example.com/m@v1.0.0"
+	require.NoError(t, storage.CreateObjectExclusively(ctx, name, nil, bytes.NewReader(concatObj)))
+
+	require.NoError(t, s.reconcileConcatObjs(ctx))
+	_, err = storage.GetObjectMetadata(ctx, name)
+	assert.NoError(t, err, "corrupt concat obj must not be reconciled")
+	_, err = storage.GetObjectMetadata(ctx, "zip/example.com/m@v1.0.0")
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+}
+
+func Test_ReconcileConcatObjs_Leased(t *testing.T) {
+	ctx := context.Background()
+	s, storage := newTestService(t, withTestPaginatedStorage(t))
+	name := "concat/example.com/m@v1.0.0"
+	require.NoError(t, storage.CreateObjectExclusively(ctx, name, nil, newTestConcatObj(t, time.Now(), []byte("a"), newTestZip(t, "a", []byte("b")))))
+	l, err := lease.Acquire(ctx, storage, storageLeaseObjNamePrefix+name, "other", time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, s.reconcileConcatObjs(ctx))
+	_, err = storage.GetObjectMetadata(ctx, name)
+	assert.NoError(t, err, "concat obj leased by another holder must not be reconciled")
+
+	require.NoError(t, l.Release(ctx))
+	require.NoError(t, s.reconcileConcatObjs(ctx))
+	_, err = storage.GetObjectMetadata(ctx, name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+	_, err = storage.GetObjectMetadata(ctx, storageLeaseObjNamePrefix+name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "lease must be released: %v", err)
+}
+
+// newTestZip returns a zip file that contains a single file.
+func newTestZip(t *testing.T, name string, data []byte) []byte {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	w, err := zipWriter.Create(name)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+	return buf.Bytes()
+}
+
+func readTestObject(t *testing.T, s storagepkg.Storage, name string) []byte {
+	data, err := s.GetObject(context.Background(), name)
+	require.NoError(t, err)
+	defer data.Close()
+	dataBytes, err := io.ReadAll(data)
+	require.NoError(t, err)
+	return dataBytes
+}
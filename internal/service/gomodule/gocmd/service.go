@@ -3,6 +3,8 @@ package gocmd
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -23,14 +25,26 @@ import (
 	"github.com/go-mod-proxy/go-mod-proxy/internal/modproxyclient"
 	gomoduleservice "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/sumdbclient"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
 )
 
 const (
 	storageConcatObjNamePrefix           = "concat/"
 	storageGoModObjNamePrefix            = "gomod/"
+	storageLeaseObjNamePrefix            = "lease/"
+	storageNotFoundObjNamePrefix         = "notfound/"
 	storageZipObjNamePrefix              = "zip/"
 	storageGoModObjCommitTimeMetadataKey = "gomod-commit-time"
+	storageGoModObjSumMetadataKey        = "gomod-h1"
+	storageZipObjSHA256MetadataKey       = "zip-sha256"
+	storageZipObjSumMetadataKey          = "zip-h1"
+)
+
+// The checksum database that the Go toolchain uses by default.
+const (
+	defaultSumDatabaseName      = "sum.golang.org"
+	defaultSumDatabasePublicKey = "033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8"
 )
 
 type goModuleInfo struct {
@@ -53,30 +67,67 @@ type goModuleInfo struct {
 }
 
 type ServiceOptions struct {
+	// DownloadLeaseTimeToLive enables download leases if positive. A replica that downloads a module version holds a
+	// lease (renewed while downloading), and other replicas wait for the module version to be stored instead of
+	// downloading it themselves. If the replica dies then another replica takes over once the lease expires after
+	// DownloadLeaseTimeToLive.
+	DownloadLeaseTimeToLive time.Duration
+	// FetchPublicModulesInProcess enables fetching public module versions from ParentProxy without running Go commands
+	// (so that fetching public modules is not limited by MaxParallelCommands). Hashes are verified against
+	// PublicModules.SumDatabase (or sum.golang.org if nil).
+	FetchPublicModulesInProcess bool
+	// FetchZipsLazily enables storing the go.mod file of a module version without its zip file, so that .info and .mod
+	// requests do not download zip files. The zip file is fetched when it is first requested and is only stored if it
+	// belongs to the same copy of the module version as the stored go.mod file.
+	FetchZipsLazily          bool
 	GitCredentialHelperShell string
 	HTTPProxyInfo            *config.HTTPProxyInfo
 	HTTPTransport            http.RoundTripper
 	MaxParallelCommands      int
-	ParentProxy              *url.URL
-	PrivateModules           []*config.PrivateModulesElement
-	PublicModules            *config.PublicModules
-	ScratchDir               string
-	Storage                  storage.Storage
+	// NegativeCache enables caching NotFound outcomes if non-nil (see withNegativeCache).
+	NegativeCache  *config.NegativeCache
+	ParentProxy    *url.URL
+	PrivateModules []*config.PrivateModulesElement
+	PublicModules  *config.PublicModules
+	ScratchDir     string
+	Storage        storage.Storage
+	// VCSCacheMaxBytes enables a cache of the version control system repositories of private modules in ScratchDir if
+	// positive (see vcsCache). The cache uses at most VCSCacheMaxBytes bytes of disk space.
+	VCSCacheMaxBytes int64
+	// VerifyHashesOnRead enables verifying the hashes of go.mod files and zip files read from storage. Hashes are only
+	// verified if they were recorded when the module version was stored.
+	VerifyHashesOnRead bool
+	// ZipURLTimeToLive enables ZipURL if positive, in which case Storage (or a storage wrapped by Storage) must implement
+	// "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".ObjectURLSigner. The URLs returned by ZipURL
+	// expire after ZipURLTimeToLive.
+	ZipURLTimeToLive time.Duration
 }
 
 type Service struct {
+	downloadLeasePollInterval  time.Duration
+	downloadLeaseTimeToLive    time.Duration
 	envGoProxy                 string
+	fetchCoalescer             *fetchCoalescer
+	fetchZipsLazily            bool
 	gitCredentialHelperShell   string
 	goBinFile                  string
 	httpClient                 *http.Client
 	httpProxyInfo              *config.HTTPProxyInfo
+	leaseHolder                string
+	listCache                  *listCache
+	negativeCache              *negativeCache
 	parentProxyURL             string
 	privateModules             []*config.PrivateModulesElement
 	publicModulesGoSumDBEnvVar string
+	publicModulesSumDBClient   *sumdbclient.Client
 	runCmdResourcePool         *maxParallelismResourcePool
 	scratchDir                 string
 	storage                    storage.Storage
 	tempGoEnvBaseEnviron       *util.Environ
+	vcsCache                   *vcsCache
+	verifyHashesOnRead         bool
+	zipURLSigner               storage.ObjectURLSigner
+	zipURLTimeToLive           time.Duration
 }
 
 var _ gomoduleservice.Service = (*Service)(nil)
@@ -103,6 +154,19 @@ func NewService(opts ServiceOptions) (s *Service, err error) {
 	if opts.Storage == nil {
 		return nil, fmt.Errorf("opts.Storage must not be nil")
 	}
+	if opts.DownloadLeaseTimeToLive != 0 && opts.DownloadLeaseTimeToLive < time.Second {
+		return nil, fmt.Errorf("opts.DownloadLeaseTimeToLive must be zero or at least 1s")
+	}
+	var zipURLSigner storage.ObjectURLSigner
+	if opts.ZipURLTimeToLive > 0 {
+		var ok bool
+		zipURLSigner, ok = storage.AsObjectURLSigner(opts.Storage)
+		if !ok {
+			return nil, fmt.Errorf("opts.ZipURLTimeToLive is positive but opts.Storage does not support signing URLs")
+		}
+	} else if opts.ZipURLTimeToLive < 0 {
+		return nil, fmt.Errorf("opts.ZipURLTimeToLive must be non-negative")
+	}
 	scratchDir1, err := filepath.Abs(opts.ScratchDir)
 	if err != nil {
 		return nil, fmt.Errorf("error making file name %#v absolute: %w", opts.ScratchDir, err)
@@ -134,18 +198,38 @@ func NewService(opts ServiceOptions) (s *Service, err error) {
 		publicModulesGoSumDBEnvVar = opts.PublicModules.SumDatabase.FormatGoSumDBEnvVar()
 	}
 	ss := &Service{
-		gitCredentialHelperShell: opts.GitCredentialHelperShell,
-		goBinFile:                goBinFile2,
+		downloadLeasePollInterval: defaultDownloadLeasePollInterval,
+		downloadLeaseTimeToLive:   opts.DownloadLeaseTimeToLive,
+		fetchZipsLazily:           opts.FetchZipsLazily,
+		gitCredentialHelperShell:  opts.GitCredentialHelperShell,
+		goBinFile:                 goBinFile2,
 		httpClient: &http.Client{
 			Transport: opts.HTTPTransport,
 		},
 		httpProxyInfo:              opts.HTTPProxyInfo,
+		leaseHolder:                newLeaseHolder(),
+		listCache:                  newListCache(),
 		privateModules:             opts.PrivateModules,
 		publicModulesGoSumDBEnvVar: publicModulesGoSumDBEnvVar,
 		scratchDir:                 scratchDir2,
 		runCmdResourcePool:         runCmdResourcePool,
 		storage:                    opts.Storage,
 		tempGoEnvBaseEnviron:       getTempGoEnvBaseEnviron(),
+		verifyHashesOnRead:         opts.VerifyHashesOnRead,
+		zipURLSigner:               zipURLSigner,
+		zipURLTimeToLive:           opts.ZipURLTimeToLive,
+	}
+	ss.fetchCoalescer = newFetchCoalescer(ss.newTempGoEnv)
+	if opts.NegativeCache != nil {
+		ss.negativeCache = newNegativeCache(opts.NegativeCache)
+	}
+	if opts.VCSCacheMaxBytes > 0 {
+		ss.vcsCache, err = newVCSCache(filepath.Join(scratchDir2, vcsCacheDirName), opts.VCSCacheMaxBytes)
+		if err != nil {
+			return
+		}
+	} else if opts.VCSCacheMaxBytes < 0 {
+		return nil, fmt.Errorf("opts.VCSCacheMaxBytes must be non-negative")
 	}
 	parentProxyStr := opts.ParentProxy.String()
 	// , is valid in URLs, but illegal in GOPROXY environment variable
@@ -154,6 +238,24 @@ func NewService(opts ServiceOptions) (s *Service, err error) {
 	}
 	ss.envGoProxy = parentProxyStr + ",direct"
 	ss.parentProxyURL = parentProxyStr + "/"
+	if opts.FetchPublicModulesInProcess {
+		sumDBClientOpts := sumdbclient.ClientOptions{
+			HTTPClient:  ss.httpClient,
+			Name:        defaultSumDatabaseName,
+			ParentProxy: opts.ParentProxy,
+			PublicKey:   defaultSumDatabasePublicKey,
+			URL:         &url.URL{Scheme: "https", Host: defaultSumDatabaseName},
+		}
+		if sumDB := opts.PublicModules.SumDatabase; sumDB != nil {
+			sumDBClientOpts.Name = sumDB.Name
+			sumDBClientOpts.PublicKey = sumDB.PublicKey
+			sumDBClientOpts.URL = sumDB.URLParsed
+		}
+		ss.publicModulesSumDBClient, err = sumdbclient.NewClient(sumDBClientOpts)
+		if err != nil {
+			return
+		}
+	}
 	// Sanity check to see if s.scratchDir is not within a Go module (otherwise this can interfere)
 	args := []string{ss.goBinFile, "mod", "download"}
 	t, err := ss.newTempGoEnv()
@@ -185,18 +287,46 @@ func NewService(opts ServiceOptions) (s *Service, err error) {
 	return
 }
 
+// getGoModuleAndIndexIfNeeded downloads moduleVersion into tempGoEnv and stores it unless it has already been stored, in
+// which case fSeeStorage is true.
 func (s *Service) getGoModuleAndIndexIfNeeded(ctx context.Context, tempGoEnv *tempGoEnv,
-	moduleVersion *module.Version, runCmdResource resource) (fSeeStorage bool, info *gomoduleservice.Info, downloadInfo *goModuleInfo, err error) {
+	moduleVersion *module.Version) (fSeeStorage bool, info *gomoduleservice.Info, downloadInfo *goModuleInfo, err error) {
+	if s.publicModulesSumDBClient != nil && s.getPrivateModulesElement(moduleVersion.Path) == nil {
+		info, downloadInfo, err = s.fetchPublicGoModule(ctx, tempGoEnv, moduleVersion)
+	} else {
+		info, downloadInfo, err = s.downloadGoModule(ctx, tempGoEnv, moduleVersion)
+	}
+	if err != nil {
+		return
+	}
+	if s.fetchZipsLazily {
+		fSeeStorage, err = s.storeGoModuleLazily(ctx, moduleVersion.Path, info, downloadInfo)
+		return
+	}
+	fSeeStorage, err = s.storeGoModule(ctx, tempGoEnv, moduleVersion.Path, info, downloadInfo)
+	return
+}
+
+// downloadGoModule downloads moduleVersion into tempGoEnv using a "go mod download" command.
+func (s *Service) downloadGoModule(ctx context.Context, tempGoEnv *tempGoEnv, moduleVersion *module.Version) (
+	info *gomoduleservice.Info, downloadInfo *goModuleInfo, err error) {
+	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
+	if err != nil {
+		return
+	}
 	defer runCmdResource.release()
-	err = s.initializeTempGoEnvForModule(ctx, tempGoEnv, moduleVersion.Path)
+	releaseVCSCache, err := s.initializeTempGoEnvForModule(ctx, tempGoEnv, moduleVersion.Path)
 	if err != nil {
 		return
 	}
+	defer releaseVCSCache()
 	args := []string{s.goBinFile, "mod", "download", "-json", moduleVersion.Path + "@" + moduleVersion.Version}
 	stdout, strLog, err := s.runCmd(ctx, tempGoEnv, args)
 	if err != nil {
+		err = goCmdNotFoundError(err, strLog)
 		return
 	}
+	releaseVCSCache()
 	runCmdResource.release()
 	downloadInfo = &goModuleInfo{}
 	err = util.UnmarshalJSON(bytes.NewReader(stdout), downloadInfo, true)
@@ -235,12 +365,17 @@ func (s *Service) getGoModuleAndIndexIfNeeded(ctx context.Context, tempGoEnv *te
 	} else if infoVersionCanonical := module.CanonicalVersion(info.Version); infoVersionCanonical != info.Version {
 		err = fmt.Errorf(".info file created by %s command contains JSON object with .Version = %#v that is not canonical (%#v)",
 			formatArgs(args), info.Version, infoVersionCanonical)
-		return
 	}
+	return
+}
 
+// storeGoModule stores the module version downloaded into tempGoEnv (see downloadInfo) unless it has already been stored,
+// in which case fSeeStorage is true.
+func (s *Service) storeGoModule(ctx context.Context, tempGoEnv *tempGoEnv, modulePath string, info *gomoduleservice.Info,
+	downloadInfo *goModuleInfo) (fSeeStorage bool, err error) {
 	goModFD, err := newSharedFDOpen(downloadInfo.GoMod)
 	if err != nil {
-		err = fmt.Errorf(`unexpected error opening .mod file created by %s command: %w`, formatArgs(args), err)
+		err = fmt.Errorf(`unexpected error opening .mod file %#v: %w`, downloadInfo.GoMod, err)
 		return
 	}
 	defer func() {
@@ -255,7 +390,7 @@ func (s *Service) getGoModuleAndIndexIfNeeded(ctx context.Context, tempGoEnv *te
 	}()
 	zipFD, err := newSharedFDOpen(downloadInfo.Zip)
 	if err != nil {
-		err = fmt.Errorf(`unexpected error opening .zip file created by %s command: %w`, formatArgs(args), err)
+		err = fmt.Errorf(`unexpected error opening .zip file %#v: %w`, downloadInfo.Zip, err)
 		return
 	}
 	defer func() {
@@ -268,11 +403,25 @@ func (s *Service) getGoModuleAndIndexIfNeeded(ctx context.Context, tempGoEnv *te
 			}
 		}
 	}()
-	readerForConcatObj, err := newReaderForCreateConcatObj(info.Time.UTC(), goModFD.FD, zipFD.FD)
+	zipHash := sha256.New()
+	if _, err = io.Copy(zipHash, zipFD.FD); err != nil {
+		err = fmt.Errorf(`unexpected error reading .zip file %#v: %w`, downloadInfo.Zip, err)
+		return
+	}
+	if err = fdSeekToStart(zipFD.FD); err != nil {
+		return
+	}
+	header := &concatObjHeader{
+		CommitTime: info.Time.UTC(),
+		GoModSum:   downloadInfo.GoModSum,
+		ZipSHA256:  hex.EncodeToString(zipHash.Sum(nil)),
+		ZipSum:     downloadInfo.Sum,
+	}
+	readerForConcatObj, err := newReaderForCreateConcatObj(*header, goModFD.FD, zipFD.FD)
 	if err != nil {
 		return
 	}
-	name := storageConcatObjNamePrefix + moduleVersion.Path + "@" + info.Version
+	name := storageConcatObjNamePrefix + modulePath + "@" + info.Version
 	err = s.storage.CreateObjectExclusively(ctx, name, nil, readerForConcatObj)
 	if err != nil {
 		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
@@ -285,8 +434,8 @@ func (s *Service) getGoModuleAndIndexIfNeeded(ctx context.Context, tempGoEnv *te
 		goModFD.addRef()
 		zipFD.addRef()
 		tempGoEnv.addRef()
-		go s.indexGoModule(tempGoEnv, info.Time, goModFD, zipFD, module.Version{
-			Path:    moduleVersion.Path,
+		go s.indexGoModule(tempGoEnv, header, goModFD, zipFD, module.Version{
+			Path:    modulePath,
 			Version: info.Version,
 		})
 	}
@@ -311,7 +460,7 @@ func (s *Service) GoMod(ctx context.Context, moduleVersion *module.Version) (dat
 	if err != nil {
 		return
 	}
-	data, err = s.storage.GetObject(ctx, storageGoModObjNamePrefix+moduleVersion.Path+"@"+moduleVersion.Version)
+	data, err = s.goModFromGoModObj(ctx, moduleVersion)
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 		return
 	}
@@ -319,44 +468,60 @@ func (s *Service) GoMod(ctx context.Context, moduleVersion *module.Version) (dat
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 		return
 	}
-	tempGoEnv, err := s.newTempGoEnv()
-	if err != nil {
-		return
-	}
-	defer func() {
-		err2 := tempGoEnv.removeRef()
-		if err2 != nil {
-			if err == nil {
-				err = err2
-			} else {
-				log.Errorf("error removing tmpDir %#v of *tempGoEnv: %v", tempGoEnv.TmpDir, err2)
-			}
-		}
-	}()
-	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
-	if err != nil {
-		return
-	}
-	defer runCmdResource.release()
-	fSeeStorage, info, downloadInfo, err := s.getGoModuleAndIndexIfNeeded(ctx, tempGoEnv, moduleVersion, runCmdResource)
+	f, err := s.fetchGoMod(ctx, moduleVersion)
 	if err != nil {
 		return
 	}
-	if !fSeeStorage {
-		data, err = tempGoEnv.open(downloadInfo.GoMod)
+	defer f.release()
+	if !f.FSeeStorage {
+		data = io.NopCloser(bytes.NewReader(f.GoMod))
 		return
 	}
 	data, err = s.goModFromConcatObj(ctx, &module.Version{
 		Path:    moduleVersion.Path,
-		Version: info.Version,
+		Version: f.Info.Version,
 	})
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 		return
 	}
-	data, err = s.storage.GetObject(ctx, storageGoModObjNamePrefix+moduleVersion.Path+"@"+info.Version)
+	data, err = s.goModFromGoModObj(ctx, &module.Version{
+		Path:    moduleVersion.Path,
+		Version: f.Info.Version,
+	})
 	return
 }
 
+// goModFromGoModObj reads the goMod obj of moduleVersion. If s.verifyHashesOnRead is true and the goMod obj has a
+// recorded hash then the data is verified before it is returned.
+func (s *Service) goModFromGoModObj(ctx context.Context, moduleVersion *module.Version) (io.ReadCloser, error) {
+	name := storageGoModObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	if !s.verifyHashesOnRead {
+		return s.storage.GetObject(ctx, name)
+	}
+	metadata, err := s.storage.GetObjectMetadata(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.storage.GetObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	goMod, err := io.ReadAll(data)
+	err2 := data.Close()
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sum := metadata[storageGoModObjSumMetadataKey]; sum != "" {
+		if err := verifyGoMod(goMod, sum, fmt.Sprintf("object %#v", name)); err != nil {
+			return nil, err
+		}
+	}
+	return io.NopCloser(bytes.NewReader(goMod)), nil
+}
+
 func (s *Service) goModFromConcatObj(ctx context.Context, moduleVersion *module.Version) (d io.ReadCloser, err error) {
 	data, err := s.storage.GetObject(ctx, storageConcatObjNamePrefix+moduleVersion.Path+"@"+moduleVersion.Version)
 	if err != nil {
@@ -376,12 +541,28 @@ func (s *Service) goModFromConcatObj(ctx context.Context, moduleVersion *module.
 			}
 		}
 	}()
-	_, goModPrefix, goModToRead, _, err := parseConcatObjCommon(data)
+	header, goModPrefix, goModToRead, _, err := parseConcatObjCommon(data)
 	if err != nil {
 		err = fmt.Errorf("error parsing concat obj's data: %w", err)
 		didPanic = false
 		return
 	}
+	if s.verifyHashesOnRead && header.GoModSum != "" {
+		closeDataAlways = true
+		goMod := make([]byte, len(goModPrefix)+goModToRead)
+		copy(goMod, goModPrefix)
+		if _, err = io.ReadFull(data, goMod[len(goModPrefix):]); err != nil {
+			err = fmt.Errorf("error reading concat obj's data: %w", err)
+			didPanic = false
+			return
+		}
+		err = verifyGoMod(goMod, header.GoModSum, fmt.Sprintf("go.mod of concat obj of %s@%s", moduleVersion.Path, moduleVersion.Version))
+		if err == nil {
+			d = io.NopCloser(bytes.NewReader(goMod))
+		}
+		didPanic = false
+		return
+	}
 	if goModToRead > 0 {
 		goModSuffixReader := io.LimitReader(data, int64(goModToRead))
 		d = util.NewConcatReader(goModPrefix, goModSuffixReader, nil, data.Close)
@@ -413,36 +594,18 @@ func (s *Service) Info(ctx context.Context, moduleVersion *module.Version) (info
 			return
 		}
 	}
-	tempGoEnv, err := s.newTempGoEnv()
+	f, err := s.fetchGoMod(ctx, moduleVersion)
 	if err != nil {
 		return
 	}
-	defer func() {
-		err2 := tempGoEnv.removeRef()
-		if err2 != nil {
-			if err == nil {
-				err = err2
-			} else {
-				log.Errorf("error removing tmpDir %#v of *tempGoEnv: %v", tempGoEnv.TmpDir, err2)
-			}
-		}
-	}()
-	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
-	if err != nil {
-		return
-	}
-	defer runCmdResource.release()
-	fSeeStorage, info2, _, err := s.getGoModuleAndIndexIfNeeded(ctx, tempGoEnv, moduleVersion, runCmdResource)
-	if err != nil {
-		return
-	}
-	if !fSeeStorage {
-		info = info2
+	defer f.release()
+	if !f.FSeeStorage {
+		info = f.Info
 		return
 	}
 	moduleVersionCanonical := &module.Version{
 		Path:    moduleVersion.Path,
-		Version: info2.Version,
+		Version: f.Info.Version,
 	}
 	info, err = s.infoFromConcatObj(ctx, moduleVersionCanonical)
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
@@ -465,14 +628,14 @@ func (s *Service) infoFromConcatObj(ctx context.Context, moduleVersion *module.V
 				}
 			}
 		}()
-		var commitTime time.Time
-		commitTime, _, _, _, err = parseConcatObjCommon(data)
+		var header *concatObjHeader
+		header, _, _, _, err = parseConcatObjCommon(data)
 		if err != nil {
 			err = fmt.Errorf("error parsing concat obj's data: %w", err)
 			return
 		}
 		i = &gomoduleservice.Info{
-			Time:    commitTime,
+			Time:    header.CommitTime,
 			Version: moduleVersion.Version,
 		}
 	}
@@ -495,7 +658,7 @@ func (s *Service) infoFromGoModObj(ctx context.Context, moduleVersion *module.Ve
 	return nil, err
 }
 
-func (s *Service) indexGoModule(tempGoEnv *tempGoEnv, commitTime time.Time, goModFD, zipFD *sharedFD, moduleVersion module.Version) {
+func (s *Service) indexGoModule(tempGoEnv *tempGoEnv, header *concatObjHeader, goModFD, zipFD *sharedFD, moduleVersion module.Version) {
 	defer func() {
 		err := goModFD.removeRef()
 		if err != nil {
@@ -511,64 +674,40 @@ func (s *Service) indexGoModule(tempGoEnv *tempGoEnv, commitTime time.Time, goMo
 		}
 	}()
 	ctx := context.Background()
-	err := fdSeekToStart(goModFD.FD)
+	name := storageConcatObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	lease, err := s.acquireConcatObjLease(ctx, name)
 	if err != nil {
-		log.Errorf("indexGoModule failed: %v", err)
-		return
-	}
-	name := storageGoModObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
-	err = s.storage.CreateObjectExclusively(ctx, name,
-		storage.ObjectMetadata{
-			storageGoModObjCommitTimeMetadataKey: commitTime.UTC().Format(time.RFC3339),
-		}, goModFD.FD)
-	if err != nil {
-		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+		if internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			log.Infof("not indexing object %#v because it is being indexed elsewhere: %v", name, err)
+		} else {
 			log.Errorf("indexGoModule failed: %v", err)
-			return
 		}
-	} else {
-		log.Infof("stored object %#v", name)
-	}
-	err = fdSeekToStart(zipFD.FD)
-	if err != nil {
-		log.Errorf("indexGoModule failed: %v", err)
 		return
 	}
-	name = storageZipObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
-	err = s.storage.CreateObjectExclusively(ctx, name, nil, zipFD.FD)
-	if err != nil {
-		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
-			log.Errorf("indexGoModule failed: %v", err)
-			return
-		}
-	} else {
-		log.Infof("stored object %#v", name)
-	}
-	name = storageConcatObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
-	err = s.storage.DeleteObject(ctx, name)
-	if err != nil {
-		if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
-			log.Errorf("indexGoModule failed: %v", err)
-			return
-		}
-	} else {
-		log.Infof("deleted object %#v", name)
+	defer s.releaseLease(lease)
+	if err := s.splitConcatObj(ctx, moduleVersion, header, goModFD.FD, zipFD.FD); err != nil {
+		log.Errorf("indexGoModule failed: %v", err)
 	}
 }
 
-func (s *Service) initializeTempGoEnvForModule(ctx context.Context, tempGoEnv *tempGoEnv, modulePath string) error {
+// initializeTempGoEnvForModule configures tempGoEnv for running Go commands that fetch modulePath. releaseVCSCache must be
+// called once the Go commands completed (see useVCSCache).
+func (s *Service) initializeTempGoEnvForModule(ctx context.Context, tempGoEnv *tempGoEnv, modulePath string) (
+	releaseVCSCache func(), err error) {
 	gitConfig := git.Config{}
-	if privateModulesElement := s.getPrivateModulesElement(modulePath); privateModulesElement != nil {
-		if privateModulesElement.Auth.GitHubApp != nil {
-			// This indicates the module is private and hosted on github.com or another GitHub instance
-			// ...so we configure git credential helper.
+	privateModulesElement := s.getPrivateModulesElement(modulePath)
+	if privateModulesElement != nil {
+		if privateModulesElement.Auth.SSH != nil {
+			if err = configureSSH(tempGoEnv, gitConfig, privateModulesElement); err != nil {
+				return
+			}
+		} else {
+			// The git credential helper server hands out credentials of the GitHub App or the static credentials
+			// configured for the module.
 			gitConfig["credential"] = []git.KeyValuePair{
 				{Key: "helper", Value: fmt.Sprintf("!%s --go-module-path=%s", s.gitCredentialHelperShell, shellescape.Quote(modulePath))},
 				{Key: "useHttpPath", Value: "true"},
 			}
-		} else {
-			// Return this error as a reminder
-			return fmt.Errorf("TODO configure auth for private modules that do not use GitHub App credentials")
 		}
 		tempGoEnv.Environ.Set("GOPROXY", "direct")
 		tempGoEnv.Environ.Set("GOSUMDB", "off")
@@ -582,11 +721,16 @@ func (s *Service) initializeTempGoEnvForModule(ctx context.Context, tempGoEnv *t
 	}
 	tempGoEnv.Environ.Set("no_proxy", s.httpProxyInfo.LibcurlNoProxy)
 	tempGoEnv.Environ.Set("https_proxy", s.httpProxyInfo.LibcurlHTTPSProxy)
-	err := git.WriteConfigFile(filepath.Join(tempGoEnv.HomeDir, ".gitconfig"), gitConfig)
+	err = git.WriteConfigFile(filepath.Join(tempGoEnv.HomeDir, ".gitconfig"), gitConfig)
 	if err != nil {
-		return err
+		return
 	}
-	return nil
+	if privateModulesElement == nil {
+		// Public modules are fetched from the parent proxy (unless the parent proxy does not have them).
+		releaseVCSCache = func() {}
+		return
+	}
+	return s.useVCSCache(tempGoEnv, privateModulesElement, modulePath)
 }
 
 func (s *Service) Latest(ctx context.Context, modulePath string) (info *gomoduleservice.Info, err error) {
@@ -594,13 +738,16 @@ func (s *Service) Latest(ctx context.Context, modulePath string) (info *gomodule
 	if err != nil {
 		return
 	}
-	versionForGoCmd := "latest"
-	if privateModulesElement := s.getPrivateModulesElement(modulePath); privateModulesElement == nil {
+	privateModulesElement := s.getPrivateModulesElement(modulePath)
+	if privateModulesElement == nil {
 		// Optimization heuristic for public modules:
 		// Get latest from parent proxy instead of doing the HTTP request via a Go command and return the version from the cache
 		// if it is already cached. This does not work well if the latest version changes a lot, because then we waste more work
 		// checking the cache than we gain.
-		info, err = modproxyclient.Latest(ctx, s.parentProxyURL, s.httpClient, modulePath)
+		err = s.withNegativeCache(ctx, modulePath, negativeCacheKeyPrefixLatest+modulePath, func() (err error) {
+			info, err = modproxyclient.Latest(ctx, s.parentProxyURL, s.httpClient, modulePath)
+			return
+		})
 		if err != nil {
 			return
 		}
@@ -617,41 +764,36 @@ func (s *Service) Latest(ctx context.Context, modulePath string) (info *gomodule
 		if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 			return
 		}
-		versionForGoCmd = moduleVersion.Version
-	}
-	tempGoEnv, err := s.newTempGoEnv()
-	if err != nil {
-		return
+		return s.latestFetch(ctx, modulePath, moduleVersion.Version)
 	}
-	defer func() {
-		err2 := tempGoEnv.removeRef()
-		if err2 != nil {
-			if err == nil {
-				err = err2
-			} else {
-				log.Errorf("error removing tmpDir %#v of *tempGoEnv: %v", tempGoEnv.TmpDir, err2)
-			}
-		}
-	}()
-	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
+	value, err := s.withListCache(ctx, listCacheKeyPrefixLatest+modulePath, privateModulesElement.ListCache,
+		func(ctx context.Context) (any, error) {
+			return s.latestFetch(ctx, modulePath, "latest")
+		})
 	if err != nil {
 		return
 	}
-	defer runCmdResource.release()
-	fSeeStorage, info2, _, err := s.getGoModuleAndIndexIfNeeded(ctx, tempGoEnv, &module.Version{
+	info = value.(*gomoduleservice.Info)
+	return
+}
+
+// latestFetch fetches the version version of the module modulePath (see fetchGoMod) and returns its info.
+func (s *Service) latestFetch(ctx context.Context, modulePath, version string) (info *gomoduleservice.Info, err error) {
+	f, err := s.fetchGoMod(ctx, &module.Version{
 		Path:    modulePath,
-		Version: versionForGoCmd,
-	}, runCmdResource)
+		Version: version,
+	})
 	if err != nil {
 		return
 	}
-	if !fSeeStorage {
-		info = info2
+	defer f.release()
+	if !f.FSeeStorage {
+		info = f.Info
 		return
 	}
 	moduleVersion := &module.Version{
 		Path:    modulePath,
-		Version: info2.Version,
+		Version: f.Info.Version,
 	}
 	info, err = s.infoFromConcatObj(ctx, moduleVersion)
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
@@ -709,6 +851,16 @@ func (s *Service) versionPreamble(version string, allowNonCanonicalVersion bool)
 }
 
 func (s *Service) Zip(ctx context.Context, moduleVersion *module.Version) (data io.ReadCloser, err error) {
+	data, _, err = s.ZipRange(ctx, moduleVersion, 0, -1)
+	return
+}
+
+func (s *Service) ZipRange(ctx context.Context, moduleVersion *module.Version, offset, length int64) (data io.ReadCloser,
+	size int64, err error) {
+	if offset < 0 {
+		err = fmt.Errorf("offset must be non-negative")
+		return
+	}
 	err = s.notFoundOptimizations(moduleVersion.Path)
 	if err != nil {
 		return
@@ -717,83 +869,117 @@ func (s *Service) Zip(ctx context.Context, moduleVersion *module.Version) (data
 	if err != nil {
 		return
 	}
-	data, err = s.storage.GetObject(ctx, storageZipObjNamePrefix+moduleVersion.Path+"@"+moduleVersion.Version)
+	data, size, err = s.zipRangeFromZipObj(ctx, moduleVersion, offset, length)
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 		return
 	}
-	data, err = s.zipFromConcatObj(ctx, moduleVersion)
+	data, size, err = s.zipRangeFromConcatObj(ctx, moduleVersion, offset, length)
 	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 		return
 	}
-	tempGoEnv, err := s.newTempGoEnv()
+	f, err := s.fetchGoModule(ctx, moduleVersion)
 	if err != nil {
 		return
 	}
-	defer func() {
-		err2 := tempGoEnv.removeRef()
-		if err2 != nil {
-			if err == nil {
-				err = err2
-			} else {
-				log.Errorf("error removing tmpDir %#v of *tempGoEnv: %v", tempGoEnv.TmpDir, err2)
-			}
-		}
-	}()
-	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
-	if err != nil {
+	defer f.release()
+	if !f.FSeeStorage {
+		data, size, err = f.TempGoEnv.openRange(f.DownloadInfo.Zip, offset, length)
 		return
 	}
-	defer runCmdResource.release()
-	fSeeStorage, info, downloadInfo, err := s.getGoModuleAndIndexIfNeeded(ctx, tempGoEnv, moduleVersion, runCmdResource)
-	if err != nil {
+	data, size, err = s.zipRangeFromConcatObj(ctx, &module.Version{
+		Path:    moduleVersion.Path,
+		Version: f.Info.Version,
+	}, offset, length)
+	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
 		return
 	}
-	if !fSeeStorage {
-		data, err = tempGoEnv.open(downloadInfo.Zip)
+	data, size, err = s.zipRangeFromZipObj(ctx, &module.Version{
+		Path:    moduleVersion.Path,
+		Version: f.Info.Version,
+	}, offset, length)
+	return
+}
+
+func (s *Service) ZipURL(ctx context.Context, moduleVersion *module.Version) (string, error) {
+	if s.zipURLSigner == nil {
+		return "", internalErrors.NewError(internalErrors.NotFound, "downloading zips directly from storage is not enabled")
+	}
+	if err := s.notFoundOptimizations(moduleVersion.Path); err != nil {
+		return "", err
+	}
+	if _, err := s.versionPreamble(moduleVersion.Version, false); err != nil {
+		return "", err
+	}
+	// Only sign URLs of zip objs, which exist once the module version has been indexed.
+	name := storageZipObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	if _, err := s.storage.GetObjectMetadata(ctx, name); err != nil {
+		return "", err
+	}
+	return s.zipURLSigner.SignObjectURL(ctx, name, s.zipURLTimeToLive)
+}
+
+// isFullRange returns true if the range of length length at offset offset covers all size bytes of some data.
+func isFullRange(offset, length, size int64) bool {
+	return offset == 0 && (length < 0 || length >= size)
+}
+
+// zipRangeFromZipObj reads a range of the zip obj of moduleVersion. If s.verifyHashesOnRead is true, the range covers the
+// entire zip and the zip obj has a recorded hash then the data is verified while it is read.
+func (s *Service) zipRangeFromZipObj(ctx context.Context, moduleVersion *module.Version, offset, length int64) (
+	d io.ReadCloser, size int64, err error) {
+	name := storageZipObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	d, size, err = s.storage.GetObjectRange(ctx, name, offset, length)
+	if err != nil || !s.verifyHashesOnRead || !isFullRange(offset, length, size) {
 		return
 	}
-	data, err = s.zipFromConcatObj(ctx, &module.Version{
-		Path:    moduleVersion.Path,
-		Version: info.Version,
-	})
-	if err == nil || !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+	metadata, err := s.storage.GetObjectMetadata(ctx, name)
+	if err != nil {
+		_ = d.Close()
+		d = nil
 		return
 	}
-	data, err = s.storage.GetObject(ctx, storageZipObjNamePrefix+moduleVersion.Path+"@"+info.Version)
+	if wantSHA256 := metadata[storageZipObjSHA256MetadataKey]; wantSHA256 != "" {
+		d = newHashVerifyingReader(d, wantSHA256, fmt.Sprintf("object %#v", name))
+	}
 	return
 }
 
-func (s *Service) zipFromConcatObj(ctx context.Context, moduleVersion *module.Version) (d io.ReadCloser, err error) {
-	didPanic := true
-	data, err := s.storage.GetObject(ctx, storageConcatObjNamePrefix+moduleVersion.Path+"@"+moduleVersion.Version)
+// zipRangeFromConcatObj reads the header of the concat obj to determine where the zip starts, so that only the requested
+// range of the zip has to be read from storage.
+func (s *Service) zipRangeFromConcatObj(ctx context.Context, moduleVersion *module.Version, offset, length int64) (
+	d io.ReadCloser, size int64, err error) {
+	name := storageConcatObjNamePrefix + moduleVersion.Path + "@" + moduleVersion.Version
+	header, _, err := s.storage.GetObjectRange(ctx, name, 0, int64(concatObjMaxHeaderLength))
 	if err != nil {
 		return
 	}
-	defer func() {
-		if didPanic || err != nil {
-			err2 := data.Close()
-			if err2 != nil {
-				log.Errorf("error closing %T: %v", data, err2)
-			}
-		}
-	}()
-	_, _, goModToRead, zipPrefix, err := parseConcatObjCommon(data)
+	headerBytes, err := io.ReadAll(header)
+	err2 := header.Close()
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		return
+	}
+	h, err := parseConcatObjHeader(headerBytes)
 	if err != nil {
 		err = fmt.Errorf("error parsing concat obj's data: %w", err)
-		didPanic = false
 		return
 	}
-	if goModToRead > 0 {
-		_, err = io.CopyN(io.Discard, data, int64(goModToRead))
-		if err != nil {
-			didPanic = false
-			return
-		}
-		d = data
-		didPanic = false
+	zipOffset := int64(h.Length) + h.GoModLength
+	d, size, err = s.storage.GetObjectRange(ctx, name, zipOffset+offset, length)
+	if err != nil {
 		return
 	}
-	d = util.NewConcatReader(zipPrefix, data, nil, data.Close)
-	didPanic = false
+	size -= zipOffset
+	if size < 0 {
+		_ = d.Close()
+		d = nil
+		err = fmt.Errorf("error parsing concat obj's data: data is shorter than its header implies")
+		return
+	}
+	if s.verifyHashesOnRead && h.ZipSHA256 != "" && isFullRange(offset, length, size) {
+		d = newHashVerifyingReader(d, h.ZipSHA256, fmt.Sprintf("zip of object %#v", name))
+	}
 	return
 }
@@ -0,0 +1,147 @@
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/memory"
+)
+
+// newTestService returns a Service that uses a new in-memory storage, which is also returned. opts are applied to the
+// Service in order, so that tests set only the fields they need (and can replace the storage).
+func newTestService(t *testing.T, opts ...func(s *Service)) (*Service, *memory.Storage) {
+	storage, err := memory.NewStorage(memory.StorageOptions{})
+	require.NoError(t, err)
+	s := &Service{
+		leaseHolder: "test",
+		scratchDir:  t.TempDir(),
+		storage:     storage,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, s.storage.(*memory.Storage)
+}
+
+func Test_ZipRange(t *testing.T) {
+	ctx := context.Background()
+	goMod := []byte("module example.com/m\n")
+	zip := []byte("0123456789")
+	for _, objNamePrefix := range []string{storageConcatObjNamePrefix, storageZipObjNamePrefix} {
+		t.Run(objNamePrefix, func(t *testing.T) {
+			s, storage := newTestService(t)
+			name := objNamePrefix + "example.com/m@v1.0.0"
+			if objNamePrefix == storageConcatObjNamePrefix {
+				data := newTestConcatObj(t, time.Unix(1600000000, 0), goMod, zip)
+				require.NoError(t, storage.CreateObjectExclusively(ctx, name, nil, data))
+			} else {
+				require.NoError(t, storage.CreateObjectExclusively(ctx, name, nil, bytes.NewReader(zip)))
+			}
+			moduleVersion := &module.Version{Path: "example.com/m", Version: "v1.0.0"}
+			for _, c := range []struct {
+				Offset   int64
+				Length   int64
+				Expected string
+			}{
+				{0, -1, "0123456789"},
+				{0, 0, ""},
+				{2, 3, "234"},
+				{8, 100, "89"},
+				{10, -1, ""},
+			} {
+				t.Run(fmt.Sprintf("%d,%d", c.Offset, c.Length), func(t *testing.T) {
+					data, size, err := s.ZipRange(ctx, moduleVersion, c.Offset, c.Length)
+					require.NoError(t, err)
+					dataBytes, err := io.ReadAll(data)
+					assert.NoError(t, err)
+					assert.NoError(t, data.Close())
+					assert.Equal(t, c.Expected, string(dataBytes))
+					assert.Equal(t, int64(len(zip)), size)
+				})
+			}
+		})
+	}
+}
+
+func Test_VerifyHashesOnRead(t *testing.T) {
+	ctx := context.Background()
+	goMod := []byte("module example.com/m\n")
+	zip := []byte("0123456789")
+	moduleVersion := &module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	concatObj, err := io.ReadAll(newTestConcatObj(t, time.Unix(1600000000, 0), goMod, zip))
+	require.NoError(t, err)
+	for _, c := range []struct {
+		Name    string
+		Corrupt func([]byte) []byte
+		Err     bool
+	}{
+		{"intact", func(b []byte) []byte { return b }, false},
+		{"corrupt", func(b []byte) []byte {
+			b = bytes.Clone(b)
+			b[len(b)-1]++
+			return b
+		}, true},
+	} {
+		t.Run(c.Name, func(t *testing.T) {
+			s, storage := newTestService(t, func(s *Service) {
+				s.verifyHashesOnRead = true
+			})
+			require.NoError(t, storage.CreateObjectExclusively(ctx, storageConcatObjNamePrefix+"example.com/m@v1.0.0", nil,
+				bytes.NewReader(c.Corrupt(concatObj))))
+			data, err := s.Zip(ctx, moduleVersion)
+			require.NoError(t, err)
+			dataBytes, err := io.ReadAll(data)
+			assert.NoError(t, data.Close())
+			if c.Err {
+				assert.Error(t, err)
+				assert.Less(t, len(dataBytes), len(zip), "corrupt data must not be read entirely")
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, zip, dataBytes)
+			}
+
+			// Only the zip is corrupted, so the go.mod file can be read either way.
+			data, err = s.GoMod(ctx, moduleVersion)
+			require.NoError(t, err)
+			dataBytes, err = io.ReadAll(data)
+			require.NoError(t, err)
+			assert.NoError(t, data.Close())
+			assert.Equal(t, goMod, dataBytes)
+		})
+	}
+}
+
+func newTestConcatObj(t *testing.T, commitTime time.Time, goMod, zip []byte) io.ReadSeeker {
+	tmpDir := t.TempDir()
+	openFile := func(name string, data []byte) *os.File {
+		file := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(file, data, 0600))
+		fd, err := os.Open(file)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = fd.Close() })
+		return fd
+	}
+	goModSum, err := hashGoMod(goMod)
+	require.NoError(t, err)
+	zipSHA256 := sha256.Sum256(zip)
+	header := concatObjHeader{
+		CommitTime: commitTime,
+		GoModSum:   goModSum,
+		ZipSHA256:  hex.EncodeToString(zipSHA256[:]),
+	}
+	data, err := newReaderForCreateConcatObj(header, openFile("go.mod", goMod), openFile("zip", zip))
+	require.NoError(t, err)
+	return data
+}
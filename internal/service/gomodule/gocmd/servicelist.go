@@ -11,6 +11,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	module "golang.org/x/mod/module"
 
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
 	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/modproxyclient"
 	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
@@ -23,7 +24,11 @@ func (s *Service) List(ctx context.Context, modulePath string) (io.ReadCloser, e
 	}
 	privateModulesElement := s.getPrivateModulesElement(modulePath)
 	if privateModulesElement == nil {
-		goListVersions, err := s.listViaParentProxy(ctx, modulePath)
+		var goListVersions []string
+		err := s.withNegativeCache(ctx, modulePath, negativeCacheKeyPrefixList+modulePath, func() (err error) {
+			goListVersions, err = s.listViaParentProxy(ctx, modulePath)
+			return
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -47,7 +52,7 @@ func (s *Service) List(ctx context.Context, modulePath string) (io.ReadCloser, e
 	var goListVersions []string
 	go func() {
 		var err error
-		goListVersions, err = s.listGoCmd(ctx, modulePath)
+		goListVersions, err = s.listGoCmdCached(ctx, modulePath, privateModulesElement.ListCache)
 		errChan <- err
 	}()
 	var err error
@@ -112,6 +117,22 @@ func (s *Service) listAddObjectNames(ctx context.Context, errChan chan<- error,
 	errChan <- nil
 }
 
+// listGoCmdCached is like listGoCmd, but uses a cached result if listCache is non-nil (see withListCache).
+func (s *Service) listGoCmdCached(ctx context.Context, modulePath string, listCache *config.ListCache) ([]string, error) {
+	value, err := s.withListCache(ctx, listCacheKeyPrefixList+modulePath, listCache, func(ctx context.Context) (any, error) {
+		var goListVersions []string
+		err := s.withNegativeCache(ctx, modulePath, negativeCacheKeyPrefixList+modulePath, func() (err error) {
+			goListVersions, err = s.listGoCmd(ctx, modulePath)
+			return
+		})
+		return goListVersions, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]string), nil
+}
+
 func (s *Service) listGoCmd(ctx context.Context, modulePath string) (goListVersions []string, err error) {
 	tempGoEnv, err := s.newTempGoEnv()
 	if err != nil {
@@ -132,15 +153,18 @@ func (s *Service) listGoCmd(ctx context.Context, modulePath string) (goListVersi
 		return
 	}
 	defer runCmdResource.release()
-	err = s.initializeTempGoEnvForModule(ctx, tempGoEnv, modulePath)
+	releaseVCSCache, err := s.initializeTempGoEnvForModule(ctx, tempGoEnv, modulePath)
 	if err != nil {
 		return
 	}
+	defer releaseVCSCache()
 	args := []string{s.goBinFile, "list", "-m", "-versions", "-json", modulePath}
 	stdout, strLog, err := s.runCmd(ctx, tempGoEnv, args)
 	if err != nil {
+		err = goCmdNotFoundError(err, strLog)
 		return
 	}
+	releaseVCSCache()
 	runCmdResource.release()
 	var goListInfo goModuleInfo
 	err = util.UnmarshalJSON(bytes.NewReader(stdout), &goListInfo, true)
@@ -174,24 +198,10 @@ func (s *Service) listGoCmdIndex(moduleVersion *module.Version) {
 		log.Errorf(`error checking if latest version (%#v) of module %#v discovered through a \"go list\" command is already cached: %v`,
 			moduleVersion.Version, moduleVersion.Path, err)
 	}
-	runCmdResource, err := s.runCmdResourcePool.acquire(ctx)
-	if err != nil {
-		panic(err)
-	}
-	defer runCmdResource.release()
-	tempGoEnv, err := s.newTempGoEnv()
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	defer func() {
-		err := tempGoEnv.removeRef()
-		if err != nil {
-			log.Errorf("error removing tmpDir %#v of *tempGoEnv: %v", tempGoEnv.TmpDir, err)
-		}
-	}()
-	_, _, _, err = s.getGoModuleAndIndexIfNeeded(ctx, tempGoEnv, moduleVersion, runCmdResource)
-	if err != nil {
+	f, err := s.fetchGoMod(ctx, moduleVersion)
+	if err == nil {
+		f.release()
+	} else {
 		log.Errorf(`error caching latest version (%#v) of module %#v discovered through a \"go list\" command: %v`,
 			moduleVersion.Version, moduleVersion.Path, err)
 	}
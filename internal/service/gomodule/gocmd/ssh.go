@@ -0,0 +1,61 @@
+package gocmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alessio/shellescape"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/git"
+)
+
+// configureSSH configures Go commands run in tempGoEnv to fetch the repositories of privateModulesElement via SSH (see
+// privateModulesElement.Auth.SSH). Go commands fetch repositories of modules via HTTPS URLs, so gitConfig is made to
+// rewrite these to SSH URLs. The private key is written to tempGoEnv and is wiped when tempGoEnv is removed.
+func configureSSH(tempGoEnv *tempGoEnv, gitConfig git.Config, privateModulesElement *config.PrivateModulesElement) error {
+	sshAuth := privateModulesElement.Auth.SSH
+	sshDir := filepath.Join(tempGoEnv.HomeDir, ".ssh")
+	if err := os.Mkdir(sshDir, 0700); err != nil {
+		return err
+	}
+	identityFile := filepath.Join(sshDir, "id")
+	privateKey := sshAuth.PrivateKey.Plaintext
+	if !bytes.HasSuffix(privateKey, []byte("\n")) {
+		// ssh(1) fails to load private keys that do not end with a line feed.
+		privateKey = append(append(make([]byte, 0, len(privateKey)+1), privateKey...), '\n')
+	}
+	if err := tempGoEnv.writeSecretFile(identityFile, privateKey); err != nil {
+		return err
+	}
+	knownHostsFile := filepath.Join(sshDir, "known_hosts")
+	if err := os.WriteFile(knownHostsFile, []byte(strings.Join(sshAuth.KnownHosts, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	tempGoEnv.Environ.Set("GIT_ALLOW_PROTOCOL", "git:https:ssh")
+	tempGoEnv.Environ.Set("GIT_SSH_COMMAND", sshCommand(identityFile, knownHostsFile))
+	gitConfig[fmt.Sprintf("url.ssh://%s@%s:%d/", sshAuth.User, privateModulesElement.RepoHost, sshAuth.Port)] =
+		[]git.KeyValuePair{
+			{Key: "insteadOf", Value: "https://" + privateModulesElement.RepoHost + "/"},
+		}
+	return nil
+}
+
+// sshCommand returns the value of GIT_SSH_COMMAND that makes git authenticate with only the private key in identityFile,
+// and accept only the host keys in knownHostsFile. Configuration files and agents of the environment are ignored.
+func sshCommand(identityFile, knownHostsFile string) string {
+	return strings.Join([]string{
+		"ssh",
+		"-F", "none",
+		"-i", shellescape.Quote(identityFile),
+		"-o", "BatchMode=yes",
+		"-o", "GlobalKnownHostsFile=none",
+		"-o", "IdentitiesOnly=yes",
+		"-o", "IdentityAgent=none",
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "UserKnownHostsFile=" + shellescape.Quote(knownHostsFile),
+	}, " ")
+}
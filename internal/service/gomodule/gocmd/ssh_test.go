@@ -0,0 +1,55 @@
+package gocmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/git"
+)
+
+func Test_ConfigureSSH(t *testing.T) {
+	tempGoEnv, err := newTempGoEnv(t.TempDir(), getTempGoEnvBaseEnviron())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, tempGoEnv.removeRef())
+	}()
+	privateModulesElement := &config.PrivateModulesElement{
+		Auth: config.PrivateModulesElementAuth{
+			SSH: &config.SSHAuth{
+				KnownHosts: []string{"git.example.com ssh-ed25519 AAAA"},
+				Port:       2222,
+				PrivateKey: &config.Secret{Plaintext: []byte("private key")},
+				User:       "git",
+			},
+		},
+		PathPrefix:     "git.example.com/org",
+		PathPrefixHost: "git.example.com",
+		RepoHost:       "git.example.com",
+	}
+	gitConfig := git.Config{}
+	require.NoError(t, configureSSH(tempGoEnv, gitConfig, privateModulesElement))
+	assert.Equal(t, git.Config{
+		"url.ssh://git@git.example.com:2222/": {{Key: "insteadOf", Value: "https://git.example.com/"}},
+	}, gitConfig)
+	assert.Equal(t, "git:https:ssh", tempGoEnv.Environ.Get("GIT_ALLOW_PROTOCOL"))
+	identityFile := filepath.Join(tempGoEnv.HomeDir, ".ssh", "id")
+	assert.True(t, strings.Contains(tempGoEnv.Environ.Get("GIT_SSH_COMMAND"), identityFile))
+	data, err := os.ReadFile(identityFile)
+	require.NoError(t, err)
+	assert.Equal(t, "private key\n", string(data))
+	data, err = os.ReadFile(filepath.Join(tempGoEnv.HomeDir, ".ssh", "known_hosts"))
+	require.NoError(t, err)
+	assert.Equal(t, "git.example.com ssh-ed25519 AAAA\n", string(data))
+
+	tempGoEnv.wipeSecretFiles()
+	data, err = os.ReadFile(identityFile)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0}, len("private key\n")), data)
+}
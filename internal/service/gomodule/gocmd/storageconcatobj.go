@@ -21,26 +21,25 @@ type readerForCreateConcatObj struct {
 
 var _ io.ReadSeeker = (*readerForCreateConcatObj)(nil)
 
-// newReaderForCreateConcatObj calls Stat on goModFD to determine it's size.
+// newReaderForCreateConcatObj calls Stat on goModFD to determine it's size. The header's GoModLength is set accordingly
+// and the header is encoded in the latest concat obj format.
 // The size of goModFD and zipFD must not change while the *readerForCreateConcatObj is used.
-func newReaderForCreateConcatObj(commitTime time.Time, goModFD, zipFD *os.File) (c *readerForCreateConcatObj, err error) {
+func newReaderForCreateConcatObj(header concatObjHeader, goModFD, zipFD *os.File) (c *readerForCreateConcatObj, err error) {
 	if zipFD == nil {
 		err = fmt.Errorf("zipFD must not be nil")
 		return
 	}
-	var arr [binary.MaxVarintLen64]byte
-	n := binary.PutVarint(arr[:], commitTime.Unix())
-	var buf bytes.Buffer
-	buf.Write(arr[:n])
 	goModStat, err := goModFD.Stat()
 	if err != nil {
 		return
 	}
-	goModFileSize := goModStat.Size()
-	n = binary.PutUvarint(arr[:], uint64(goModFileSize))
-	buf.Write(arr[:n])
+	header.GoModLength = goModStat.Size()
+	prefix, err := header.encode()
+	if err != nil {
+		return
+	}
 	c = &readerForCreateConcatObj{
-		prefix:  buf.Bytes(),
+		prefix:  prefix,
 		goModFD: goModFD,
 		zipFD:   zipFD,
 	}
@@ -95,37 +94,117 @@ func (c *readerForCreateConcatObj) Seek(offset int64, whence int) (int64, error)
 	return 0, nil
 }
 
-func parseConcatObjCommon(data io.Reader) (commitTime time.Time, goModPrefix []byte, goModToRead int, zipPrefix []byte, err error) {
-	var buffer [binary.MaxVarintLen64 * 2]byte
-	n, err := data.Read(buffer[:])
-	if err != nil && err != io.EOF {
-		return
+// Concat objs consist of a header followed by the go.mod file and the zip file of a module version.
+//
+// The header of a version 1 concat obj consists of the commit time (as a varint of unix seconds) and the length of the
+// go.mod file (as a uvarint).
+//
+// The header of a version 2 concat obj consists of concatObjMagic, the version (as a uvarint), the commit time and
+// the length of the go.mod file (as in version 1), followed by the h1: hash of the go.mod file, the h1: hash of the zip
+// file and the hex-encoded SHA-256 hash of the zip file (each as a uvarint length followed by the bytes of the string).
+// A version 1 header can only start with concatObjMagic if the commit time is the unix epoch, which does not happen in
+// practice.
+const (
+	concatObjMagic           = "\x00GMPC"
+	concatObjVersion         = 2
+	concatObjMaxStringLength = 128
+)
+
+// concatObjMaxHeaderLength is the maximum length of the header of a concat obj.
+const concatObjMaxHeaderLength = len(concatObjMagic) + binary.MaxVarintLen64*3 + (binary.MaxVarintLen64+concatObjMaxStringLength)*3
+
+type concatObjHeader struct {
+	CommitTime  time.Time
+	GoModLength int64
+	// GoModSum is the h1: hash of the go.mod file. GoModSum is empty for version 1 concat objs.
+	GoModSum string
+	// Length is the length of the header in bytes. Length is only set by parseConcatObjHeader.
+	Length  int
+	Version int
+	// ZipSHA256 is the hex-encoded SHA-256 hash of the zip file. ZipSHA256 is empty for version 1 concat objs.
+	ZipSHA256 string
+	// ZipSum is the h1: hash of the zip file. ZipSum is empty for version 1 concat objs.
+	ZipSum string
+}
+
+// encode encodes h in the latest format.
+func (h *concatObjHeader) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	var arr [binary.MaxVarintLen64]byte
+	buf.WriteString(concatObjMagic)
+	buf.Write(arr[:binary.PutUvarint(arr[:], concatObjVersion)])
+	buf.Write(arr[:binary.PutVarint(arr[:], h.CommitTime.Unix())])
+	buf.Write(arr[:binary.PutUvarint(arr[:], uint64(h.GoModLength))])
+	for _, str := range []string{h.GoModSum, h.ZipSum, h.ZipSHA256} {
+		if len(str) > concatObjMaxStringLength {
+			return nil, fmt.Errorf("hash %#v is too long", str)
+		}
+		buf.Write(arr[:binary.PutUvarint(arr[:], uint64(len(str)))])
+		buf.WriteString(str)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseConcatObjHeader parses the header at the start of data. data need not contain more than the header.
+func parseConcatObjHeader(data []byte) (*concatObjHeader, error) {
+	h := &concatObjHeader{Version: 1}
+	bufferReader := bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte(concatObjMagic)) {
+		bufferReader.Reset(data[len(concatObjMagic):])
+		version, err := binary.ReadUvarint(bufferReader)
+		if err != nil {
+			return nil, fmt.Errorf("data does not have a valid version")
+		}
+		if version != concatObjVersion {
+			return nil, fmt.Errorf("data has version %d, which is not supported", version)
+		}
+		h.Version = int(version)
 	}
-	bufferReader := bytes.NewReader(buffer[:n])
 	commitTimeUnix, err := binary.ReadVarint(bufferReader)
 	if err != nil {
-		err = fmt.Errorf("data does not start with a valid 64-bit varint")
-		return
+		return nil, fmt.Errorf("data does not start with a valid 64-bit varint")
 	}
-	commitTime = time.Unix(commitTimeUnix, 0)
+	h.CommitTime = time.Unix(commitTimeUnix, 0)
 	goModLengthUint64, err := binary.ReadUvarint(bufferReader)
 	if err != nil {
-		err = fmt.Errorf("data unexpectedly does not start with two valid 64-bit varints")
-		return
+		return nil, fmt.Errorf("data unexpectedly does not start with two valid 64-bit varints")
 	}
 	if goModLengthUint64 > uint64(math.MaxInt) {
-		err = fmt.Errorf("data's second 64-bit varint (as uint) is too large")
+		return nil, fmt.Errorf("data's second 64-bit varint (as uint) is too large")
+	}
+	h.GoModLength = int64(goModLengthUint64)
+	if h.Version >= 2 {
+		for _, str := range []*string{&h.GoModSum, &h.ZipSum, &h.ZipSHA256} {
+			length, err := binary.ReadUvarint(bufferReader)
+			if err != nil || length > concatObjMaxStringLength || length > uint64(bufferReader.Len()) {
+				return nil, fmt.Errorf("data has an invalid or truncated header")
+			}
+			strBytes := make([]byte, length)
+			_, _ = bufferReader.Read(strBytes)
+			*str = string(strBytes)
+		}
+	}
+	h.Length = len(data) - bufferReader.Len()
+	return h, nil
+}
+
+func parseConcatObjCommon(data io.Reader) (header *concatObjHeader, goModPrefix []byte, goModToRead int, zipPrefix []byte, err error) {
+	buffer := make([]byte, concatObjMaxHeaderLength)
+	n, err := io.ReadFull(data, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return
+	}
+	header, err = parseConcatObjHeader(buffer[:n])
+	if err != nil {
 		return
 	}
-	goModLength := int(goModLengthUint64)
-	if goModLength <= bufferReader.Len() {
-		goModPrefix = make([]byte, goModLength)
-		_, _ = bufferReader.Read(goModPrefix)
-		zipPrefix = make([]byte, -bufferReader.Len())
-		_, _ = bufferReader.Read(zipPrefix)
+	goModLength := int(header.GoModLength)
+	rest := buffer[header.Length:n]
+	if goModLength <= len(rest) {
+		goModPrefix = rest[:goModLength]
+		zipPrefix = rest[goModLength:]
 	} else {
-		goModPrefix = make([]byte, bufferReader.Len())
-		_, _ = bufferReader.Read(goModPrefix)
+		goModPrefix = rest
 		goModToRead = goModLength - len(goModPrefix)
 	}
 	return
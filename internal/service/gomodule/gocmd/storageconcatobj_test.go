@@ -2,10 +2,17 @@ package gocmd
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testResources struct {
@@ -142,3 +149,50 @@ func Test_ReaderForCreateConcatObj_Success2(t *testing.T) {
 		return
 	}
 }
+
+func Test_ParseConcatObjCommon_ZipPrefix(t *testing.T) {
+	commitTime := time.Unix(1600000000, 0)
+	data, err := io.ReadAll(newTestConcatObj(t, commitTime, []byte{1, 2, 3}, []byte{4, 5, 6}))
+	require.NoError(t, err)
+	header, goModPrefix, goModToRead, zipPrefix, err := parseConcatObjCommon(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.True(t, commitTime.Equal(header.CommitTime))
+	assert.Equal(t, concatObjVersion, header.Version)
+	assert.Equal(t, int64(3), header.GoModLength)
+	zipSHA256 := sha256.Sum256([]byte{4, 5, 6})
+	assert.Equal(t, hex.EncodeToString(zipSHA256[:]), header.ZipSHA256)
+	goModSum, err := hashGoMod([]byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, goModSum, header.GoModSum)
+	assert.Equal(t, []byte{1, 2, 3}, goModPrefix)
+	assert.Equal(t, 0, goModToRead)
+	assert.Equal(t, []byte{4, 5, 6}, zipPrefix)
+}
+
+func Test_ParseConcatObjCommon_Version1(t *testing.T) {
+	commitTime := time.Unix(1600000000, 0)
+	var data []byte
+	data = binary.AppendVarint(data, commitTime.Unix())
+	data = binary.AppendUvarint(data, 3)
+	data = append(data, 1, 2, 3, 4, 5, 6)
+	header, goModPrefix, goModToRead, zipPrefix, err := parseConcatObjCommon(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 1, header.Version)
+	assert.True(t, commitTime.Equal(header.CommitTime))
+	assert.Equal(t, "", header.GoModSum)
+	assert.Equal(t, "", header.ZipSHA256)
+	assert.Equal(t, []byte{1, 2, 3}, goModPrefix)
+	assert.Equal(t, 0, goModToRead)
+	assert.Equal(t, []byte{4, 5, 6}, zipPrefix)
+}
+
+func Test_ParseConcatObjHeader_Invalid(t *testing.T) {
+	header := concatObjHeader{CommitTime: time.Unix(1600000000, 0), GoModSum: "h1:abc"}
+	data, err := header.encode()
+	require.NoError(t, err)
+	_, err = parseConcatObjHeader(data[:len(data)-2])
+	assert.Error(t, err, "truncated header")
+	data[len(concatObjMagic)] = concatObjVersion + 1
+	_, err = parseConcatObjHeader(data)
+	assert.Error(t, err, "unsupported version")
+}
@@ -50,7 +50,9 @@ type tempGoEnv struct {
 	HomeDir    string
 	TmpDir     string
 	refs       int32
-	WorkDir    string
+	// secretFiles are the names of files written by writeSecretFile.
+	secretFiles []string
+	WorkDir     string
 }
 
 func newTempGoEnv(scratchDir string, baseEnviron *util.Environ) (t2 *tempGoEnv, err error) {
@@ -122,6 +124,28 @@ func (t *tempGoEnv) open(name string) (*tempGoEnvFD, error) {
 	return newTempGoEnvFD(fd, name, t)
 }
 
+// openRange opens the file named name and returns a reader of at most length bytes of the file starting at byte offset,
+// and the size of the file. If length is negative then the reader reads until the end of the file.
+func (t *tempGoEnv) openRange(name string, offset, length int64) (io.ReadCloser, int64, error) {
+	fd, err := t.open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	fileInfo, err := fd.FD.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return nil, 0, err
+	}
+	size := fileInfo.Size()
+	if offset > size {
+		offset = size
+	}
+	if length < 0 || length > size-offset {
+		length = size - offset
+	}
+	return util.NewConcatReader(nil, io.NewSectionReader(fd.FD, offset, length), nil, fd.Close), size, nil
+}
+
 func (t *tempGoEnv) removeRef() error {
 	if atomic.AddInt32(&t.refs, -1) == 0 {
 		runtime.SetFinalizer(t, nil)
@@ -131,6 +155,7 @@ func (t *tempGoEnv) removeRef() error {
 }
 
 func (t *tempGoEnv) removeTmpDir() error {
+	t.wipeSecretFiles()
 	err := filepath.Walk(t.TmpDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -213,3 +238,52 @@ func (t *tempGoEnvFD) Read(p []byte) (n int, err error) {
 func (t *tempGoEnvFD) Seek(offset int64, whence int) (int64, error) {
 	return t.FD.Seek(offset, whence)
 }
+
+// wipeSecretFiles overwrites the files written by writeSecretFile with zeros, so that their contents can not be recovered
+// after they are removed (as far as the file system allows).
+func (t *tempGoEnv) wipeSecretFiles() {
+	for _, name := range t.secretFiles {
+		if err := wipeFile(name); err != nil && !os.IsNotExist(err) {
+			log.Errorf("error wiping secret file %#v of *tempGoEnv: %v", name, err)
+		}
+	}
+	t.secretFiles = nil
+}
+
+// writeSecretFile writes data to the file named name, which must be in t.TmpDir and is only readable by the current user.
+// The file is wiped before t.TmpDir is removed.
+func (t *tempGoEnv) writeSecretFile(name string, data []byte) error {
+	if !strings.HasPrefix(name, t.TmpDir) || !filepath.IsAbs(name) {
+		return fmt.Errorf("name is invalid")
+	}
+	t.secretFiles = append(t.secretFiles, name)
+	return os.WriteFile(name, data, 0600)
+}
+
+func wipeFile(name string) error {
+	fd, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := fd.Stat()
+	if err == nil {
+		_, err = io.CopyN(fd, zeroReader{}, fileInfo.Size())
+	}
+	if err == nil {
+		err = fd.Sync()
+	}
+	err2 := fd.Close()
+	if err == nil {
+		err = err2
+	}
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,282 @@
+package gocmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/lru"
+)
+
+const (
+	// vcsCacheDirName is the name of the directory in the scratch dir that contains the VCS cache.
+	vcsCacheDirName = "vcs-cache"
+	// vcsCacheEvictedDirNamePrefix prefixes the names of directories that evicted repos are moved to before they are
+	// removed.
+	vcsCacheEvictedDirNamePrefix = "evicted-"
+)
+
+// vcsCache is a cache of the "cache/vcs" directories of Go module caches (see "go help modules"). Each repo of vcsCache is
+// a directory that is used as the "cache/vcs" directory of Go commands, which lock the repositories within it, so a repo
+// can be used by concurrent Go commands. vcsCache is bounded by the disk space used by its repos and evicts least
+// recently used repos that are not in use.
+type vcsCache struct {
+	dir string
+	lru *lru.Cache
+	// mu guards refs and trash, and must be held when calling lru.Add (because of onEvict).
+	mu sync.Mutex
+	// refs maps the key of each repo that is in use to the number of its users.
+	refs  map[string]int
+	trash []string
+}
+
+func newVCSCache(dir string, maxBytes int64) (*vcsCache, error) {
+	c := &vcsCache{
+		dir:  dir,
+		refs: map[string]int{},
+	}
+	c.lru = lru.New(maxBytes, c.onEvict)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load adds the repos of a previous process to the LRU, using their modification times as last use times. Evicted repos
+// are left behind if the process crashes while removing them.
+func (c *vcsCache) load() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type repo struct {
+		key     string
+		modTime time.Time
+		size    int64
+	}
+	var repos []repo
+	for _, dirEntry := range dirEntries {
+		if strings.HasPrefix(dirEntry.Name(), vcsCacheEvictedDirNamePrefix) {
+			c.removeDir(filepath.Join(c.dir, dirEntry.Name()))
+			continue
+		}
+		if !dirEntry.IsDir() {
+			continue
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		size, err := dirSize(filepath.Join(c.dir, dirEntry.Name()))
+		if err != nil {
+			return err
+		}
+		repos = append(repos, repo{
+			key:     dirEntry.Name(),
+			modTime: fileInfo.ModTime(),
+			size:    size,
+		})
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].modTime.Before(repos[j].modTime)
+	})
+	c.mu.Lock()
+	for _, repo := range repos {
+		if !c.lru.Add(repo.key, nil, repo.size) {
+			c.trashLocked(repo.key)
+		}
+	}
+	trash := c.takeTrashLocked()
+	c.mu.Unlock()
+	c.removeDirs(trash)
+	return nil
+}
+
+// acquire returns the directory of the repo with key key, which is created if needed. The repo is not evicted until
+// release is called.
+func (c *vcsCache) acquire(key string) (dir string, release func(), err error) {
+	c.mu.Lock()
+	c.refs[key]++
+	c.mu.Unlock()
+	dir = filepath.Join(c.dir, key)
+	released := false
+	release = func() {
+		if released {
+			return
+		}
+		released = true
+		c.release(key, dir)
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		release()
+		return "", nil, err
+	}
+	return
+}
+
+func (c *vcsCache) release(key, dir string) {
+	size, err := dirSize(dir)
+	if err != nil {
+		log.Errorf("error determining size of VCS cache directory %#v: %v", dir, err)
+	}
+	// Persist the last use time, so that the LRU order survives restarts.
+	now := time.Now()
+	if err := os.Chtimes(dir, now, now); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Errorf("error updating times of VCS cache directory %#v: %v", dir, err)
+	}
+	c.mu.Lock()
+	c.refs[key]--
+	if c.refs[key] == 0 {
+		delete(c.refs, key)
+	}
+	// A repo that was evicted while in use is added again.
+	if !c.lru.Add(key, nil, size) && c.refs[key] == 0 {
+		c.trashLocked(key)
+	}
+	trash := c.takeTrashLocked()
+	c.mu.Unlock()
+	c.removeDirs(trash)
+}
+
+// onEvict is called by c.lru.Add while c.mu is held. Repos that are in use are removed once they are no longer used,
+// unless they are added again (see release).
+func (c *vcsCache) onEvict(key string, _ any) {
+	if c.refs[key] == 0 {
+		c.trashLocked(key)
+	}
+}
+
+// trashLocked moves the directory of the repo with key key out of the way, so that it can be removed without holding c.mu
+// while the repo is created again. c.mu must be held.
+func (c *vcsCache) trashLocked(key string) {
+	dir := filepath.Join(c.dir, key)
+	trashDir, err := os.MkdirTemp(c.dir, vcsCacheEvictedDirNamePrefix)
+	if err != nil {
+		log.Errorf("error evicting VCS cache directory %#v: %v", dir, err)
+		return
+	}
+	c.trash = append(c.trash, trashDir)
+	if err := os.Rename(dir, filepath.Join(trashDir, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Errorf("error evicting VCS cache directory %#v: %v", dir, err)
+	}
+}
+
+func (c *vcsCache) takeTrashLocked() []string {
+	trash := c.trash
+	c.trash = nil
+	return trash
+}
+
+func (c *vcsCache) removeDirs(dirs []string) {
+	for _, dir := range dirs {
+		c.removeDir(dir)
+	}
+}
+
+func (c *vcsCache) removeDir(dir string) {
+	log.Debugf("removing VCS cache directory %#v", dir)
+	// Files in Go module caches can be read-only.
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			_ = os.Chmod(path, 0700)
+		}
+		return nil
+	})
+	if err := os.RemoveAll(dir); err != nil {
+		log.Errorf("error removing VCS cache directory %#v: %v", dir, err)
+	}
+}
+
+// dirSize returns the sum of the sizes of the regular files in dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// vcsCacheKey returns the key of the repo of vcsCache used for modulePath. Repos are isolated by the credentials in effect
+// (see privateModulesElement.Auth), so that a repository fetched with one set of credentials is never served to Go
+// commands that use another set of credentials. Repos are further split by the first three elements of modulePath (the
+// repository of modulePath on GitHub), so that eviction is per repository.
+func vcsCacheKey(privateModulesElement *config.PrivateModulesElement, modulePath string) string {
+	repoPath := modulePath
+	if i := strings.IndexByte(repoPath, '/'); i >= 0 {
+		if j := strings.IndexByte(repoPath[i+1:], '/'); j >= 0 {
+			if k := strings.IndexByte(repoPath[i+1+j+1:], '/'); k >= 0 {
+				repoPath = repoPath[:i+1+j+1+k]
+			}
+		}
+	}
+	var credentials string
+	if privateModulesElement.Auth.GitHubApp != nil {
+		credentials = fmt.Sprintf("gitHubApp:%d", *privateModulesElement.Auth.GitHubApp)
+	} else if len(privateModulesElement.Auth.Credentials) > 0 {
+		// Static credentials are configured per element of .privateModules.
+		credentials = "credentials:" + privateModulesElement.PathPrefix
+	} else if privateModulesElement.Auth.SSH != nil {
+		credentials = "ssh:" + privateModulesElement.PathPrefix
+	}
+	hash := sha256.Sum256([]byte(credentials + "\x00" + repoPath))
+	return hex.EncodeToString(hash[:])
+}
+
+// useVCSCache makes Go commands run in tempGoEnv use the repo of s.vcsCache for modulePath as their "cache/vcs" directory
+// until release is called. release is a no-op if s.vcsCache is nil.
+func (s *Service) useVCSCache(tempGoEnv *tempGoEnv, privateModulesElement *config.PrivateModulesElement,
+	modulePath string) (release func(), err error) {
+	if s.vcsCache == nil {
+		return func() {}, nil
+	}
+	dir, releaseRepo, err := s.vcsCache.acquire(vcsCacheKey(privateModulesElement, modulePath))
+	if err != nil {
+		return nil, err
+	}
+	link := filepath.Join(tempGoEnv.GoPathDir, "pkg", "mod", "cache", "vcs")
+	if err = os.MkdirAll(filepath.Dir(link), 0700); err == nil {
+		err = os.Symlink(dir, link)
+	}
+	if err != nil {
+		releaseRepo()
+		return nil, err
+	}
+	released := false
+	release = func() {
+		if released {
+			return
+		}
+		released = true
+		if err := os.Remove(link); err != nil {
+			log.Errorf("error removing symlink %#v: %v", link, err)
+		}
+		releaseRepo()
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package gocmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+)
+
+func writeVCSCacheTestFile(t *testing.T, dir string, size int) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pack"), make([]byte, size), 0400))
+}
+
+func Test_VCSCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := newVCSCache(dir, 10)
+	require.NoError(t, err)
+
+	dirA, releaseA, err := c.acquire("a")
+	require.NoError(t, err)
+	writeVCSCacheTestFile(t, dirA, 6)
+	releaseA()
+	releaseA()
+
+	// b is in use while a is evicted.
+	dirB, releaseB, err := c.acquire("b")
+	require.NoError(t, err)
+	writeVCSCacheTestFile(t, dirB, 6)
+	dirC, releaseC, err := c.acquire("c")
+	require.NoError(t, err)
+	writeVCSCacheTestFile(t, dirC, 4)
+	releaseC()
+	assert.DirExists(t, dirA)
+	releaseB()
+	assert.NoDirExists(t, dirA)
+	assert.DirExists(t, dirB)
+	assert.DirExists(t, dirC)
+
+	// Repos of a previous process are loaded in LRU order.
+	c, err = newVCSCache(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), c.lru.Size())
+	dirD, releaseD, err := c.acquire("d")
+	require.NoError(t, err)
+	writeVCSCacheTestFile(t, dirD, 1)
+	releaseD()
+	assert.NoDirExists(t, dirC)
+	assert.DirExists(t, dirB)
+	dirEntries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, dirEntries, 2)
+}
+
+func Test_VCSCacheKey(t *testing.T) {
+	gitHubApp1 := int64(1)
+	gitHubApp2 := int64(2)
+	privateModulesElement1 := &config.PrivateModulesElement{Auth: config.PrivateModulesElementAuth{GitHubApp: &gitHubApp1}}
+	privateModulesElement2 := &config.PrivateModulesElement{Auth: config.PrivateModulesElementAuth{GitHubApp: &gitHubApp2}}
+	key := vcsCacheKey(privateModulesElement1, "github.com/org/repo")
+	assert.Equal(t, key, vcsCacheKey(privateModulesElement1, "github.com/org/repo/sub/v2"))
+	assert.NotEqual(t, key, vcsCacheKey(privateModulesElement1, "github.com/org/repo2"))
+	assert.NotEqual(t, key, vcsCacheKey(privateModulesElement2, "github.com/org/repo"))
+	privateModulesElement3 := &config.PrivateModulesElement{
+		Auth:       config.PrivateModulesElementAuth{Credentials: []*config.GitCredentials{{User: "oauth2"}}},
+		PathPrefix: "gitlab.example.com/org",
+	}
+	privateModulesElement4 := &config.PrivateModulesElement{
+		Auth:       config.PrivateModulesElementAuth{Credentials: []*config.GitCredentials{{User: "oauth2"}}},
+		PathPrefix: "gitlab.example.com/org/repo",
+	}
+	key = vcsCacheKey(privateModulesElement3, "gitlab.example.com/org/repo")
+	assert.NotEqual(t, key, vcsCacheKey(privateModulesElement1, "gitlab.example.com/org/repo"))
+	assert.NotEqual(t, key, vcsCacheKey(privateModulesElement4, "gitlab.example.com/org/repo"))
+}
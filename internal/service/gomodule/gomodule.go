@@ -35,8 +35,36 @@ type Service interface {
 	// is true if the specified module version does not exist.
 	Zip(ctx context.Context, moduleVersion *module.Version) (io.ReadCloser, error)
 
+	// ZipRange is like Zip, except that the io.ReadCloser reads at most length bytes of the zip archive starting at byte offset.
+	// If length is negative then the io.ReadCloser reads until the end of the zip archive. ZipRange also returns the size of
+	// the zip archive, so ZipRange with length 0 can be used to determine the size without reading the zip archive.
+	// Returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound)
+	// is true if the specified module version does not exist.
+	ZipRange(ctx context.Context, moduleVersion *module.Version, offset, length int64) (io.ReadCloser, int64, error)
+
+	// ZipURL returns a URL from which the zip archive of the specified module version can be downloaded directly (without
+	// credentials) for a limited time.
+	// Returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound)
+	// is true if no such URL is available (i.e. because the specified module version does not exist, has not been
+	// stored yet or because downloading directly is not enabled), in which case Zip or ZipRange should be used instead.
+	ZipURL(ctx context.Context, moduleVersion *module.Version) (string, error)
+
 	// GoMod returns an io.ReadCloser who's byte stream is the go.mod file of the specified module version.
 	// Returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound)
 	// is true if the specified module version does not exist.
 	GoMod(ctx context.Context, moduleVersion *module.Version) (io.ReadCloser, error)
 }
+
+type bypassCacheContextKey struct{}
+
+// WithBypassCache returns a copy of ctx that makes Service implementations bypass caches of outcomes that can change over
+// time (such as the outcome that a module version does not exist), i.e. because a client requested a fresh response.
+func WithBypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheContextKey{}, true)
+}
+
+// BypassCache returns true if ctx was returned by WithBypassCache (or derived from such a context).
+func BypassCache(ctx context.Context) bool {
+	b, _ := ctx.Value(bypassCacheContextKey{}).(bool)
+	return b
+}
@@ -0,0 +1,167 @@
+package warm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// Format is the format of the input of a warm-up.
+type Format string
+
+const (
+	// FormatGoMod is the format of go.mod files. The requirements are the roots of the warm-up and the replace
+	// directives (that do not replace modules by directories) are applied.
+	FormatGoMod Format = "gomod"
+	// FormatGoSum is the format of go.sum files. A go.sum file lists the files needed to build a module, so its module
+	// graph is not walked.
+	FormatGoSum Format = "gosum"
+	// FormatGoWork is the format of go.work files. The modules used by a workspace are directories, so only the replace
+	// directives of a go.work file (that do not replace modules by directories) are warmed up.
+	FormatGoWork Format = "gowork"
+	// FormatList is a list of module versions, one per line, formatted as "<module>@<version>" or "<module> <version>".
+	// Empty lines and lines starting with "#" are ignored.
+	FormatList Format = "list"
+)
+
+// Input is the parsed input of a warm-up.
+type Input struct {
+	// Mods are module versions whose go.mod files are fetched (in addition to the go.mod files of the module graph of
+	// Roots).
+	Mods []module.Version
+	// Replace maps module versions to their replacements. A key with an empty Version matches all versions. A value with an
+	// empty Version is a directory.
+	Replace map[module.Version]module.Version
+	// Roots are the module versions whose module graph and build list are warmed up.
+	Roots []module.Version
+	// Unresolvable describes the parts of the input that can not be resolved to module versions, such as use directives
+	// of go.work files. They are reported as failures.
+	Unresolvable []string
+	// Zips are module versions whose zip archives are fetched (in addition to the zip archives of the build list of
+	// Roots).
+	Zips []module.Version
+}
+
+// ParseInput parses data of format format.
+func ParseInput(format Format, data []byte) (*Input, error) {
+	switch format {
+	case FormatGoMod:
+		return parseGoMod(data)
+	case FormatGoSum:
+		return parseGoSum(data)
+	case FormatGoWork:
+		return parseGoWork(data)
+	case FormatList:
+		return parseList(data)
+	}
+	return nil, fmt.Errorf("format %#v is not supported", string(format))
+}
+
+func parseGoMod(data []byte) (*Input, error) {
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	input := &Input{}
+	for _, require := range f.Require {
+		input.Roots = append(input.Roots, require.Mod)
+	}
+	input.addReplace(f.Replace)
+	return input, nil
+}
+
+func parseGoSum(data []byte) (*Input, error) {
+	input := &Input{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("go.sum:%d: line is malformed", lineNumber)
+		}
+		// Lines of go.mod files have a version with suffix "/go.mod", other lines are lines of zip archives.
+		if version, ok := strings.CutSuffix(fields[1], "/go.mod"); ok {
+			input.Mods = append(input.Mods, module.Version{Path: fields[0], Version: version})
+		} else {
+			input.Zips = append(input.Zips, module.Version{Path: fields[0], Version: fields[1]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func parseGoWork(data []byte) (*Input, error) {
+	f, err := modfile.ParseWork("go.work", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	input := &Input{}
+	for _, use := range f.Use {
+		input.Unresolvable = append(input.Unresolvable, fmt.Sprintf("use directive %#v can not be resolved to module "+
+			"versions, warm up the go.mod file of the module instead", use.Path))
+	}
+	input.addReplace(f.Replace)
+	for _, replace := range f.Replace {
+		if replace.New.Version != "" {
+			input.Roots = append(input.Roots, replace.New)
+		}
+	}
+	return input, nil
+}
+
+func parseList(data []byte) (*Input, error) {
+	input := &Input{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var fields []string
+		if i := strings.LastIndexByte(line, '@'); i >= 0 {
+			fields = []string{line[:i], line[i+1:]}
+		} else {
+			fields = strings.Fields(line)
+		}
+		if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("line %d: expected <module>@<version>", lineNumber)
+		}
+		input.Roots = append(input.Roots, module.Version{Path: fields[0], Version: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (i *Input) addReplace(replaces []*modfile.Replace) {
+	for _, replace := range replaces {
+		if replace.New.Version == "" {
+			i.Unresolvable = append(i.Unresolvable, fmt.Sprintf("module %#v is replaced by directory %#v, so its "+
+				"requirements can not be resolved", replace.Old.String(), replace.New.Path))
+		}
+		if i.Replace == nil {
+			i.Replace = map[module.Version]module.Version{}
+		}
+		i.Replace[replace.Old] = replace.New
+	}
+}
+
+// replace returns the replacement of moduleVersion, or moduleVersion if it is not replaced.
+func (i *Input) replace(moduleVersion module.Version) module.Version {
+	if r, ok := i.Replace[moduleVersion]; ok {
+		return r
+	}
+	if r, ok := i.Replace[module.Version{Path: moduleVersion.Path}]; ok {
+		return r
+	}
+	return moduleVersion
+}
@@ -0,0 +1,281 @@
+// Package warm pre-populates the caches of a "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule".Service
+// with the build lists of Go modules.
+package warm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
+	servicegomodule "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+const (
+	// FileMod identifies the go.mod file of a module version in an Event.
+	FileMod = "mod"
+	// FileZip identifies the zip archive of a module version in an Event.
+	FileZip = "zip"
+)
+
+// Event is an element of the report of a warm-up. Events are reported when a file of a module version has been fetched
+// (or failed to be fetched), and the last Event of a report has a non-nil Summary.
+type Event struct {
+	Path    string   `json:"path,omitempty"`
+	Version string   `json:"version,omitempty"`
+	File    string   `json:"file,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// Summary summarizes a warm-up.
+type Summary struct {
+	Mods   int `json:"mods"`
+	Zips   int `json:"zips"`
+	Errors int `json:"errors"`
+}
+
+type WarmerOptions struct {
+	GoModuleService servicegomodule.Service
+	// Parallelism is the maximum number of files fetched at the same time, across all warm-ups.
+	Parallelism int
+	// QueueSize is the maximum number of files waiting to be fetched, across all warm-ups. Warm-ups block while the queue
+	// is full.
+	QueueSize int
+}
+
+// Warmer fetches the go.mod files and zip archives of the build lists of Go modules via a
+// "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule".Service, so that they are cached when they are
+// requested. Files are fetched by a bounded number of workers, so that warm-ups do not starve other requests.
+type Warmer struct {
+	goModuleService servicegomodule.Service
+	queue           chan func()
+}
+
+// NewWarmer is a constructor for Warmer. The workers of the returned Warmer run until ctx is done.
+func NewWarmer(ctx context.Context, opts WarmerOptions) (*Warmer, error) {
+	if opts.GoModuleService == nil {
+		return nil, fmt.Errorf("opts.GoModuleService must not be nil")
+	}
+	if opts.Parallelism <= 0 {
+		return nil, fmt.Errorf("opts.Parallelism must be positive")
+	}
+	if opts.QueueSize < 0 {
+		return nil, fmt.Errorf("opts.QueueSize must be non-negative")
+	}
+	w := &Warmer{
+		goModuleService: opts.GoModuleService,
+		queue:           make(chan func(), opts.QueueSize),
+	}
+	for i := 0; i < opts.Parallelism; i++ {
+		go w.work(ctx)
+	}
+	return w, nil
+}
+
+func (w *Warmer) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case task := <-w.queue:
+			task()
+		}
+	}
+}
+
+// Warm fetches the go.mod files of the module graph of input and the zip archives of the build list of input (see
+// "go help modules"), and calls report for each fetched file. Warm does not return until all files have been fetched or
+// ctx is done. report is not called concurrently.
+func (w *Warmer) Warm(ctx context.Context, input *Input, report func(event *Event)) *Summary {
+	r := &warmUp{
+		ctx:    ctx,
+		input:  input,
+		report: report,
+		w:      w,
+	}
+	for _, message := range input.Unresolvable {
+		r.reportEvent(&Event{Error: message})
+	}
+	buildList := r.walkModuleGraph()
+	mods := map[module.Version]struct{}{}
+	for _, moduleVersion := range input.Mods {
+		if _, ok := r.modsFetched[moduleVersion]; !ok {
+			mods[moduleVersion] = struct{}{}
+		}
+	}
+	zips := map[module.Version]struct{}{}
+	for _, version := range buildList {
+		zips[version] = struct{}{}
+	}
+	for _, moduleVersion := range input.Zips {
+		if replacement := input.replace(moduleVersion); replacement.Version != "" {
+			zips[replacement] = struct{}{}
+		}
+	}
+	var wg sync.WaitGroup
+	for moduleVersion := range mods {
+		moduleVersion := input.replace(moduleVersion)
+		if moduleVersion.Version == "" {
+			continue
+		}
+		r.submit(&wg, func() {
+			_, err := r.fetchGoMod(moduleVersion)
+			r.reportFetch(moduleVersion, FileMod, err)
+		})
+	}
+	for moduleVersion := range zips {
+		moduleVersion := moduleVersion
+		r.submit(&wg, func() {
+			r.reportFetch(moduleVersion, FileZip, r.fetchZip(moduleVersion))
+		})
+	}
+	wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	summary := r.summary
+	return &summary
+}
+
+type warmUp struct {
+	ctx    context.Context
+	input  *Input
+	report func(event *Event)
+	w      *Warmer
+	// modsFetched is the set of module versions (before replacement) whose go.mod files have been fetched by
+	// walkModuleGraph.
+	modsFetched map[module.Version]struct{}
+	// mu guards summary and serializes calls of report.
+	mu      sync.Mutex
+	summary Summary
+}
+
+// walkModuleGraph fetches the go.mod files of the module graph of r.input and returns the build list selected by minimal
+// version selection (with replacements applied). The go.mod files of all reachable module versions are fetched, even if
+// module graph pruning would make the go command ignore some of them, because this is a superset of the go.mod files
+// needed by the go command.
+func (r *warmUp) walkModuleGraph() []module.Version {
+	r.modsFetched = map[module.Version]struct{}{}
+	selected := map[string]string{}
+	var mu sync.Mutex
+	var frontier []module.Version
+	visit := func(moduleVersion module.Version) {
+		if _, ok := r.modsFetched[moduleVersion]; ok {
+			return
+		}
+		r.modsFetched[moduleVersion] = struct{}{}
+		if v, ok := selected[moduleVersion.Path]; !ok || semver.Compare(v, moduleVersion.Version) < 0 {
+			selected[moduleVersion.Path] = moduleVersion.Version
+		}
+		frontier = append(frontier, moduleVersion)
+	}
+	for _, moduleVersion := range r.input.Roots {
+		visit(moduleVersion)
+	}
+	for len(frontier) > 0 && r.ctx.Err() == nil {
+		current := frontier
+		frontier = nil
+		var wg sync.WaitGroup
+		for _, moduleVersion := range current {
+			moduleVersion := moduleVersion
+			r.submit(&wg, func() {
+				replacement := r.input.replace(moduleVersion)
+				if replacement.Version == "" {
+					// Replaced by a directory (see Input.Unresolvable).
+					return
+				}
+				requires, err := r.fetchGoMod(replacement)
+				r.reportFetch(replacement, FileMod, err)
+				mu.Lock()
+				defer mu.Unlock()
+				for _, require := range requires {
+					visit(require)
+				}
+			})
+		}
+		wg.Wait()
+	}
+	buildList := make([]module.Version, 0, len(selected))
+	for path, version := range selected {
+		if replacement := r.input.replace(module.Version{Path: path, Version: version}); replacement.Version != "" {
+			buildList = append(buildList, replacement)
+		}
+	}
+	return buildList
+}
+
+// submit adds task to the queue of r.w. If ctx is done then task is not added.
+func (r *warmUp) submit(wg *sync.WaitGroup, task func()) {
+	wg.Add(1)
+	select {
+	case <-r.ctx.Done():
+		wg.Done()
+	case r.w.queue <- func() {
+		defer wg.Done()
+		if r.ctx.Err() != nil {
+			return
+		}
+		task()
+	}:
+	}
+}
+
+func (r *warmUp) fetchGoMod(moduleVersion module.Version) ([]module.Version, error) {
+	readCloser, err := r.w.goModuleService.GoMod(r.ctx, &moduleVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer readCloser.Close()
+	data, err := io.ReadAll(readCloser)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go.mod: %w", err)
+	}
+	requires := make([]module.Version, 0, len(f.Require))
+	for _, require := range f.Require {
+		requires = append(requires, require.Mod)
+	}
+	return requires, nil
+}
+
+func (r *warmUp) fetchZip(moduleVersion module.Version) error {
+	// ZipRange with length 0 fetches the zip archive without reading it.
+	readCloser, _, err := r.w.goModuleService.ZipRange(r.ctx, &moduleVersion, 0, 0)
+	if err != nil {
+		return err
+	}
+	return readCloser.Close()
+}
+
+func (r *warmUp) reportFetch(moduleVersion module.Version, file string, err error) {
+	event := &Event{
+		Path:    moduleVersion.Path,
+		Version: moduleVersion.Version,
+		File:    file,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	r.reportEvent(event)
+}
+
+func (r *warmUp) reportEvent(event *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case event.Error != "":
+		r.summary.Errors++
+	case event.File == FileMod:
+		r.summary.Mods++
+	case event.File == FileZip:
+		r.summary.Zips++
+	}
+	r.report(event)
+}
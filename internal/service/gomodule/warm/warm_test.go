@@ -0,0 +1,102 @@
+package warm
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	servicegomodule "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule"
+)
+
+type fakeService struct {
+	servicegomodule.Service
+	goMods map[string]string
+	mu     sync.Mutex
+	zips   []string
+}
+
+func (f *fakeService) GoMod(ctx context.Context, moduleVersion *module.Version) (io.ReadCloser, error) {
+	goMod, ok := f.goMods[moduleVersion.String()]
+	if !ok {
+		return nil, internalErrors.NewError(internalErrors.NotFound, "not found")
+	}
+	return io.NopCloser(strings.NewReader(goMod)), nil
+}
+
+func (f *fakeService) ZipRange(ctx context.Context, moduleVersion *module.Version, offset, length int64) (io.ReadCloser,
+	int64, error) {
+	if _, ok := f.goMods[moduleVersion.String()]; !ok {
+		return nil, 0, internalErrors.NewError(internalErrors.NotFound, "not found")
+	}
+	f.mu.Lock()
+	f.zips = append(f.zips, moduleVersion.String())
+	f.mu.Unlock()
+	return io.NopCloser(strings.NewReader("")), 0, nil
+}
+
+func Test_Warm(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := &fakeService{
+		goMods: map[string]string{
+			"example.com/a@v1.0.0": "module example.com/a\nrequire (\nexample.com/b v1.0.0\nexample.com/c v1.0.0\n)\n",
+			"example.com/b@v1.0.0": "module example.com/b\nrequire example.com/c v1.1.0\n",
+			"example.com/c@v1.0.0": "module example.com/c\nrequire example.com/d v1.0.0\n",
+			"example.com/c@v1.1.0": "module example.com/c\n",
+			"example.com/d@v1.0.0": "module example.com/d\n",
+			"example.com/f@v1.0.0": "module example.com/f\n",
+		},
+	}
+	w, err := NewWarmer(ctx, WarmerOptions{
+		GoModuleService: f,
+		Parallelism:     2,
+		QueueSize:       1,
+	})
+	require.NoError(t, err)
+	input, err := ParseInput(FormatGoMod, []byte("module example.com/main\n"+
+		"require (\nexample.com/a v1.0.0\nexample.com/e v1.0.0\n)\n"+
+		"replace example.com/d => example.com/f v1.0.0\n"+
+		"replace example.com/e => ../e\n"))
+	require.NoError(t, err)
+	var events []*Event
+	summary := w.Warm(ctx, input, func(event *Event) {
+		events = append(events, event)
+	})
+	// example.com/e is replaced by a directory.
+	assert.Equal(t, &Summary{Mods: 5, Zips: 4, Errors: 1}, summary)
+	assert.Len(t, events, 10)
+	sort.Strings(f.zips)
+	assert.Equal(t, []string{"example.com/a@v1.0.0", "example.com/b@v1.0.0", "example.com/c@v1.1.0", "example.com/f@v1.0.0"}, f.zips)
+}
+
+func Test_ParseInput(t *testing.T) {
+	input, err := ParseInput(FormatGoSum, []byte("example.com/a v1.0.0 h1:x=\nexample.com/a v1.0.0/go.mod h1:y=\n"+
+		"example.com/b v1.0.0/go.mod h1:z=\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []module.Version{{Path: "example.com/a", Version: "v1.0.0"}, {Path: "example.com/b", Version: "v1.0.0"}},
+		input.Mods)
+	assert.Equal(t, []module.Version{{Path: "example.com/a", Version: "v1.0.0"}}, input.Zips)
+
+	input, err = ParseInput(FormatList, []byte("# comment\nexample.com/a@v1.0.0\n\nexample.com/b v1.0.0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []module.Version{{Path: "example.com/a", Version: "v1.0.0"}, {Path: "example.com/b", Version: "v1.0.0"}},
+		input.Roots)
+
+	input, err = ParseInput(FormatGoWork, []byte("go 1.20\nuse ./a\nreplace example.com/c v1.0.0 => example.com/d v1.0.0\n"))
+	require.NoError(t, err)
+	assert.Len(t, input.Unresolvable, 1)
+	assert.Equal(t, []module.Version{{Path: "example.com/d", Version: "v1.0.0"}}, input.Roots)
+
+	_, err = ParseInput(FormatList, []byte("example.com/a\n"))
+	assert.Error(t, err)
+	_, err = ParseInput("yaml", nil)
+	assert.Error(t, err)
+}
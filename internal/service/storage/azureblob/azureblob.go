@@ -0,0 +1,525 @@
+package azureblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	log "github.com/sirupsen/logrus"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+const (
+	apiVersion               = "2021-08-06"
+	headerNameErrorCode      = "X-Ms-Error-Code"
+	headerNameMetadataPrefix = "X-Ms-Meta-"
+)
+
+type StorageOptions struct {
+	// Account is the name of the storage account.
+	Account   string
+	Container string
+	// Endpoint is the base URL of the Blob service. Defaults to https://<Account>.blob.core.windows.net.
+	Endpoint   *url.URL
+	HTTPClient *http.Client
+	// SASToken is a shared access signature (the query string of a SAS URL, with or without leading "?").
+	// Exactly one of SASToken and SharedKey must be set.
+	SASToken string
+	// SharedKey is the (base64-decoded) storage account key. Exactly one of SASToken and SharedKey must be set.
+	SharedKey []byte
+}
+
+// Storage is an implementation of "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage backed by
+// Azure Blob Storage.
+type Storage struct {
+	account      string
+	containerURL string
+	httpClient   *http.Client
+	now          func() time.Time
+	sasQuery     url.Values
+	sharedKey    []byte
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+func NewStorage(opts StorageOptions) (*Storage, error) {
+	if opts.Account == "" {
+		return nil, fmt.Errorf("opts.Account must not be empty")
+	}
+	if opts.Container == "" {
+		return nil, fmt.Errorf("opts.Container must not be empty")
+	}
+	if opts.HTTPClient == nil {
+		return nil, fmt.Errorf("opts.HTTPClient must not be nil")
+	}
+	if (strings.TrimSpace(opts.SASToken) == "") == (len(opts.SharedKey) == 0) {
+		return nil, fmt.Errorf("exactly one of opts.SASToken and opts.SharedKey must be set")
+	}
+	endpoint := opts.Endpoint
+	if endpoint == nil {
+		endpoint = &url.URL{
+			Scheme: "https",
+			Host:   opts.Account + ".blob.core.windows.net",
+		}
+	} else if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf(`opts.Endpoint must have scheme "http" or "https"`)
+	} else if endpoint.Host == "" {
+		return nil, fmt.Errorf("opts.Endpoint must have a non-empty host")
+	}
+	s := &Storage{
+		account: opts.Account,
+		containerURL: endpoint.Scheme + "://" + endpoint.Host + strings.TrimSuffix(endpoint.EscapedPath(), "/") + "/" +
+			url.PathEscape(opts.Container),
+		httpClient: opts.HTTPClient,
+		now:        time.Now,
+		sharedKey:  opts.SharedKey,
+	}
+	if len(opts.SharedKey) == 0 {
+		var err error
+		s.sasQuery, err = url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(opts.SASToken), "?"))
+		if err != nil {
+			return nil, fmt.Errorf("opts.SASToken is invalid: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Storage) CreateObjectExclusively(ctx context.Context, name string, metadata storage.ObjectMetadata,
+	data io.ReadSeeker) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	_, err := data.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	dataLength, err := data.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("If-None-Match", "*")
+	header.Set("X-Ms-Blob-Type", "BlockBlob")
+	for key, value := range metadata {
+		header.Set(headerNameMetadataPrefix+encodeMetadataKey(key), value)
+	}
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodPut, s.blobURL(name, nil), header, data, dataLength)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		// The Blob service responds with 409 BlobAlreadyExists to a Put Blob request with If-None-Match: * if the blob exists.
+		if resp.StatusCode == http.StatusPreconditionFailed || resp.Header.Get(headerNameErrorCode) == "BlobAlreadyExists" {
+			return internalErrors.NewErrorf(
+				internalErrors.PreconditionFailed,
+				"got %d-response to %s %s: %s",
+				resp.StatusCode,
+				resp.Request.Method,
+				redactURL(resp.Request.URL),
+				string(respBodyBytes))
+		}
+	}
+	return unexpectedResponseError(resp, respBodyBytes)
+}
+
+func (s *Storage) DeleteObject(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodDelete, s.blobURL(name, nil), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return notFoundError(resp)
+	}
+	return unexpectedResponseError(resp, respBodyBytes)
+}
+
+func (s *Storage) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	resp, err := s.doRequestStreamBody(ctx, http.MethodGet, s.blobURL(name, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *Storage) GetObjectRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	if name == "" {
+		return nil, 0, fmt.Errorf("name must not be empty")
+	}
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative")
+	}
+	if length == 0 {
+		size, err := s.getObjectSize(ctx, name)
+		if err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(bytes.NewReader(nil)), size, nil
+	}
+	header := http.Header{}
+	header.Set("Range", util.FormatRangeHeader(offset, length))
+	resp, err := s.doRequestStreamBody(ctx, http.MethodGet, s.blobURL(name, nil), header)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The Range header was ignored.
+		data, err := util.SliceReadCloser(resp.Body, offset, length)
+		if err != nil {
+			return nil, 0, err
+		}
+		return data, resp.ContentLength, nil
+	case http.StatusPartialContent:
+		size, err := util.ParseContentRangeSize(resp.Header.Get("Content-Range"))
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, 0, fmt.Errorf("error parsing %d-response to %s %s: %w", resp.StatusCode, resp.Request.Method,
+				redactURL(resp.Request.URL), err)
+		}
+		return resp.Body, size, nil
+	}
+	// offset is greater than or equal to the size of the object's data.
+	size, err := s.getObjectSize(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.NopCloser(bytes.NewReader(nil)), size, nil
+}
+
+func (s *Storage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	resp, err := s.getBlobProperties(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	metadata := storage.ObjectMetadata{}
+	for name, values := range resp.Header {
+		if len(values) > 0 && len(name) > len(headerNameMetadataPrefix) &&
+			strings.EqualFold(name[:len(headerNameMetadataPrefix)], headerNameMetadataPrefix) {
+			key, err := decodeMetadataKey(strings.ToLower(name[len(headerNameMetadataPrefix):]))
+			if err != nil {
+				return nil, fmt.Errorf("%d-response to %s %s has invalid header %#v: %w", resp.StatusCode, resp.Request.Method,
+					redactURL(resp.Request.URL), name, err)
+			}
+			metadata[key] = values[0]
+		}
+	}
+	return metadata, nil
+}
+
+func (s *Storage) ListObjects(ctx context.Context, opts storage.ObjectListOptions) (*storage.ObjectList, error) {
+	if opts.MaxResults < 0 {
+		return nil, fmt.Errorf("opts.MaxResults must be non-negative")
+	}
+	urlQuery := url.Values{}
+	urlQuery.Set("restype", "container")
+	urlQuery.Set("comp", "list")
+	if opts.MaxResults > 0 {
+		urlQuery.Set("maxresults", strconv.FormatInt(int64(opts.MaxResults), 10))
+	}
+	if opts.NamePrefix != "" {
+		urlQuery.Set("prefix", opts.NamePrefix)
+	}
+	if opts.PageToken != "" {
+		urlQuery.Set("marker", opts.PageToken)
+	}
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodGet, s.urlWithQuery(s.containerURL, urlQuery), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedResponseError(resp, respBodyBytes)
+	}
+	respBody := &struct {
+		Blobs struct {
+			Blob []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blob"`
+		} `xml:"Blobs"`
+		NextMarker string `xml:"NextMarker"`
+	}{}
+	// The response body may start with a UTF-8 byte order mark.
+	if err := xml.Unmarshal(bytes.TrimPrefix(respBodyBytes, []byte("\xef\xbb\xbf")), respBody); err != nil {
+		return nil, fmt.Errorf("error unmarshalling body of %d-response to %s %s: %w",
+			resp.StatusCode,
+			resp.Request.Method,
+			redactURL(resp.Request.URL),
+			err)
+	}
+	objList := &storage.ObjectList{
+		NextPageToken: respBody.NextMarker,
+	}
+	for _, blob := range respBody.Blobs.Blob {
+		objList.Names = append(objList.Names, blob.Name)
+	}
+	return objList, nil
+}
+
+func (s *Storage) getObjectSize(ctx context.Context, name string) (int64, error) {
+	resp, err := s.getBlobProperties(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("%d-response to %s %s has no valid Content-Length header", resp.StatusCode, resp.Request.Method,
+			redactURL(resp.Request.URL))
+	}
+	return resp.ContentLength, nil
+}
+
+// getBlobProperties does a Get Blob Properties request. If the returned error is nil then the response has status 200.
+func (s *Storage) getBlobProperties(ctx context.Context, name string) (*http.Response, error) {
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodHead, s.blobURL(name, nil), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, notFoundError(resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedResponseError(resp, respBodyBytes)
+	}
+	return resp, nil
+}
+
+func (s *Storage) blobURL(name string, urlQuery url.Values) string {
+	var sb strings.Builder
+	sb.WriteString(s.containerURL)
+	for _, component := range strings.Split(name, "/") {
+		sb.WriteByte('/')
+		sb.WriteString(url.PathEscape(component))
+	}
+	return s.urlWithQuery(sb.String(), urlQuery)
+}
+
+// doRequest does an authorized request and reads the response body. Intermittent errors are retried. If the returned error is nil
+// then the response's status code is not a status code indicating an intermittent error.
+func (s *Storage) doRequest(ctx context.Context, method, url string, header http.Header, body io.ReadSeeker,
+	bodyLength int64) (*http.Response, []byte, error) {
+	// Inspired by https://github.com/googleapis/google-cloud-go/blob/67b19f0bd698c1df21addff89060b4356816a4d3/storage/invoke.go#L26
+	var backoff gax.Backoff
+	for {
+		req, err := s.newRequest(ctx, method, url, header, body, bodyLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			if !shouldRetryDoRequest(err) {
+				return nil, nil, fmt.Errorf("error doing request %s %s: %w", method, redactURL(req.URL), err)
+			}
+			log.Errorf("retrying because got intermittent error doing request %s %s: %v", method, redactURL(req.URL), err)
+		} else {
+			respBodyBytes, err := io.ReadAll(resp.Body)
+			err2 := resp.Body.Close()
+			if err2 != nil {
+				log.Errorf("error closing body of %d-response to %s %s: %v", resp.StatusCode, method, redactURL(req.URL), err2)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading body of %d-response to %s %s: %w", resp.StatusCode, method,
+					redactURL(req.URL), err)
+			}
+			if !shouldRetryStatusCode(resp.StatusCode) {
+				return resp, respBodyBytes, nil
+			}
+			log.Errorf("retrying because got intermittent %d-response to %s %s: %s", resp.StatusCode, method, redactURL(req.URL),
+				string(respBodyBytes))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(backoff.Pause()):
+		}
+	}
+}
+
+// doRequestStreamBody does an authorized request without a request body. Intermittent errors are retried. If the returned error
+// is nil then the response has status 200 or 206 (or 416 if header has a Range header) and the caller must close the response
+// body.
+func (s *Storage) doRequestStreamBody(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	var backoff gax.Backoff
+	for {
+		req, err := s.newRequest(ctx, method, url, header, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			if !shouldRetryDoRequest(err) {
+				return nil, fmt.Errorf("error doing request %s %s: %w", method, redactURL(req.URL), err)
+			}
+			log.Errorf("retrying because got intermittent error doing request %s %s: %v", method, redactURL(req.URL), err)
+		} else {
+			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+				return resp, nil
+			}
+			respBodyBytes, err := io.ReadAll(resp.Body)
+			if err != nil {
+				log.Errorf("error reading body of %d-response to %s %s: %v", resp.StatusCode, method, redactURL(req.URL), err)
+			}
+			err = resp.Body.Close()
+			if err != nil {
+				log.Errorf("error closing body of %d-response to %s %s: %v", resp.StatusCode, method, redactURL(req.URL), err)
+			}
+			if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && header.Get("Range") != "" {
+				resp.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
+				return resp, nil
+			}
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, notFoundError(resp)
+			}
+			if !shouldRetryStatusCode(resp.StatusCode) {
+				return nil, unexpectedResponseError(resp, respBodyBytes)
+			}
+			log.Errorf("retrying because got intermittent %d-response to %s %s: %s", resp.StatusCode, method, redactURL(req.URL),
+				string(respBodyBytes))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff.Pause()):
+		}
+	}
+}
+
+func (s *Storage) newRequest(ctx context.Context, method, url string, header http.Header, body io.ReadSeeker,
+	bodyLength int64) (*http.Request, error) {
+	var reqBody io.Reader
+	if body != nil {
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		reqBody = body
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request %s %s: %w", method, url, err)
+	}
+	if body != nil {
+		// Ensure the request body is not closed by the transport, so that it can be retried.
+		req.Body = io.NopCloser(reqBody)
+		req.ContentLength = bodyLength
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set(headerNameDate, s.now().UTC().Format(http.TimeFormat))
+	req.Header.Set(headerNameVersion, apiVersion)
+	if s.sharedKey != nil {
+		signRequestSharedKey(req, s.account, s.sharedKey)
+	}
+	return req, nil
+}
+
+// urlWithQuery appends urlQuery and the SAS token (if any) to baseURL.
+func (s *Storage) urlWithQuery(baseURL string, urlQuery url.Values) string {
+	if len(urlQuery) == 0 && len(s.sasQuery) == 0 {
+		return baseURL
+	}
+	allQuery := url.Values{}
+	for name, values := range s.sasQuery {
+		allQuery[name] = values
+	}
+	for name, values := range urlQuery {
+		allQuery[name] = values
+	}
+	return baseURL + "?" + allQuery.Encode()
+}
+
+// encodeMetadataKey encodes key so that it is a valid C# identifier, as required by the Blob service. Characters other than
+// ASCII letters and (non-leading) digits are encoded as "_" followed by two lowercase hex digits.
+func encodeMetadataKey(key string) string {
+	const hexDigits = "0123456789abcdef"
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > 0 && '0' <= c && c <= '9') {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('_')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0xF])
+		}
+	}
+	return sb.String()
+}
+
+func decodeMetadataKey(s string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("unexpected end of escape sequence")
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence %#v", s[i:i+3])
+		}
+		sb.WriteByte(byte(c))
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+func notFoundError(resp *http.Response) error {
+	return internalErrors.NewErrorf(internalErrors.NotFound, "got %d-response to %s %s", resp.StatusCode, resp.Request.Method,
+		redactURL(resp.Request.URL))
+}
+
+// redactURL formats u without the signature of a SAS token, so that URLs can be safely logged.
+func redactURL(u *url.URL) string {
+	urlQuery := u.Query()
+	if _, ok := urlQuery["sig"]; !ok {
+		return u.String()
+	}
+	urlQuery.Set("sig", "REDACTED")
+	u2 := *u
+	u2.RawQuery = urlQuery.Encode()
+	return u2.String()
+}
+
+func shouldRetryDoRequest(err error) bool {
+	if strings.Contains(err.Error(), "REFUSED_STREAM") {
+		return true
+	} else if t, ok := err.(interface{ Temporary() bool }); ok && t.Temporary() {
+		return true
+	}
+	return false
+}
+
+func shouldRetryStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || (500 <= statusCode && statusCode <= 599)
+}
+
+func unexpectedResponseError(resp *http.Response, respBodyBytes []byte) error {
+	return fmt.Errorf("got unexpected %d-response to %s %s: %s", resp.StatusCode, resp.Request.Method, redactURL(resp.Request.URL),
+		string(respBodyBytes))
+}
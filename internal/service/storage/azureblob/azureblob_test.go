@@ -0,0 +1,220 @@
+package azureblob
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/storagetest"
+)
+
+const (
+	testAccount   = "testaccount"
+	testContainer = "test-container"
+	testSASToken  = "sv=2021-08-06&ss=b&srt=co&sp=rwdlc&sig=c2lnbmF0dXJl"
+)
+
+var testSharedKey = []byte("0123456789abcdef0123456789abcdef")
+
+type fakeBlob struct {
+	data     []byte
+	metadata http.Header
+}
+
+// fakeBlobServer is a minimal in-process implementation of the subset of the Blob service API used by Storage.
+type fakeBlobServer struct {
+	mu    sync.Mutex
+	blobs map[string]*fakeBlob
+}
+
+func newFakeBlobServer() *fakeBlobServer {
+	return &fakeBlobServer{
+		blobs: map[string]*fakeBlob{},
+	}
+}
+
+func (f *fakeBlobServer) authorized(req *http.Request) bool {
+	if req.URL.Query().Get("sig") != "" {
+		return req.Header.Get(headerNameAuthorization) == "" && strings.Contains(req.URL.RawQuery, "sig=c2lnbmF0dXJl")
+	}
+	authorization := req.Header.Get(headerNameAuthorization)
+	req.Header.Del(headerNameAuthorization)
+	signRequestSharedKey(req, testAccount, testSharedKey)
+	return authorization != "" && authorization == req.Header.Get(headerNameAuthorization)
+}
+
+func (f *fakeBlobServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !f.authorized(req) || req.Header.Get(headerNameVersion) == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	name, ok := strings.CutPrefix(req.URL.Path, "/"+testContainer)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	name = strings.TrimPrefix(name, "/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if name == "" {
+		f.list(w, req)
+		return
+	}
+	blob := f.blobs[name]
+	switch req.Method {
+	case http.MethodPut:
+		if req.Header.Get("If-None-Match") != "*" || req.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		if blob != nil {
+			w.Header().Set(headerNameErrorCode, "BlobAlreadyExists")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		blob = &fakeBlob{
+			data:     data,
+			metadata: http.Header{},
+		}
+		for name, values := range req.Header {
+			if strings.HasPrefix(name, headerNameMetadataPrefix) {
+				if strings.ContainsAny(name[len(headerNameMetadataPrefix):], "-.") {
+					w.Header().Set(headerNameErrorCode, "InvalidMetadata")
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				blob.metadata[name] = values
+			}
+		}
+		f.blobs[name] = blob
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		if blob == nil {
+			w.Header().Set(headerNameErrorCode, "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for name, values := range blob.metadata {
+			w.Header()[name] = values
+		}
+		// http.ServeContent also handles Range headers.
+		http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(blob.data))
+	case http.MethodDelete:
+		if blob == nil {
+			w.Header().Set(headerNameErrorCode, "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeBlobServer) list(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if query.Get("restype") != "container" || query.Get("comp") != "list" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	prefix := query.Get("prefix")
+	maxResults := 5000
+	if s := query.Get("maxresults"); s != "" {
+		maxResults, _ = strconv.Atoi(s)
+	}
+	var names []string
+	for name := range f.blobs {
+		if strings.HasPrefix(name, prefix) && name >= query.Get("marker") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	type blob struct {
+		Name string `xml:"Name"`
+	}
+	respBody := struct {
+		XMLName    xml.Name `xml:"EnumerationResults"`
+		Blobs      []blob   `xml:"Blobs>Blob"`
+		NextMarker string   `xml:"NextMarker"`
+	}{}
+	if len(names) > maxResults {
+		respBody.NextMarker = names[maxResults]
+		names = names[:maxResults]
+	}
+	for _, name := range names {
+		respBody.Blobs = append(respBody.Blobs, blob{Name: name})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("\xef\xbb\xbf"))
+	_ = xml.NewEncoder(w).Encode(respBody)
+}
+
+func newTestStorage(t *testing.T, useSAS bool) *Storage {
+	t.Helper()
+	server := httptest.NewServer(newFakeBlobServer())
+	t.Cleanup(server.Close)
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	opts := StorageOptions{
+		Account:    testAccount,
+		Container:  testContainer,
+		Endpoint:   endpoint,
+		HTTPClient: server.Client(),
+	}
+	if useSAS {
+		opts.SASToken = "?" + testSASToken
+	} else {
+		opts.SharedKey = testSharedKey
+	}
+	s, err := NewStorage(opts)
+	require.NoError(t, err)
+	return s
+}
+
+func Test_Storage(t *testing.T) {
+	for _, useSAS := range []bool{false, true} {
+		useSAS := useSAS
+		t.Run("useSAS="+strconv.FormatBool(useSAS), func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T) storage.Storage {
+				return newTestStorage(t, useSAS)
+			})
+		})
+	}
+}
+
+func Test_encodeMetadataKey(t *testing.T) {
+	for _, key := range []string{"gomod-commit-time", "a_b", "0abc", "h1.hash"} {
+		encoded := encodeMetadataKey(key)
+		assert.NotContains(t, encoded, "-")
+		assert.NotContains(t, encoded, ".")
+		decoded, err := decodeMetadataKey(strings.ToLower(encoded))
+		require.NoError(t, err)
+		assert.Equal(t, key, decoded)
+	}
+}
+
+func Test_redactURL(t *testing.T) {
+	u, err := url.Parse("https://a.blob.core.windows.net/c/b?" + testSASToken)
+	require.NoError(t, err)
+	assert.NotContains(t, redactURL(u), "c2lnbmF0dXJl")
+}
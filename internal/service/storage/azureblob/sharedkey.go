@@ -0,0 +1,97 @@
+package azureblob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerNameAuthorization = "Authorization"
+	headerNameDate          = "X-Ms-Date"
+	headerNameVersion       = "X-Ms-Version"
+)
+
+// signRequestSharedKey signs req using Shared Key authorization (see
+// https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key).
+// req.ContentLength must be set if req has a body. The X-Ms-Date header must already be set.
+func signRequestSharedKey(req *http.Request, account string, key []byte) {
+	var contentLength string
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	var sb strings.Builder
+	sb.WriteString(req.Method)
+	sb.WriteByte('\n')
+	for _, value := range []string{
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		// Date is empty because X-Ms-Date is set.
+		"",
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	} {
+		sb.WriteString(value)
+		sb.WriteByte('\n')
+	}
+
+	var msHeaderNames []string
+	for name := range req.Header {
+		nameLower := strings.ToLower(name)
+		if strings.HasPrefix(nameLower, "x-ms-") {
+			msHeaderNames = append(msHeaderNames, nameLower)
+		}
+	}
+	sort.Strings(msHeaderNames)
+	for _, name := range msHeaderNames {
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(strings.Fields(req.Header.Get(name)), " "))
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteByte('/')
+	sb.WriteString(account)
+	sb.WriteString(req.URL.EscapedPath())
+	sb.WriteString(canonicalizedQuery(req.URL.Query()))
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(sb.String()))
+	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	req.Header.Set(headerNameAuthorization, fmt.Sprintf("SharedKey %s:%s", account, signature))
+}
+
+func canonicalizedQuery(query url.Values) string {
+	byName := map[string][]string{}
+	for name, values := range query {
+		nameLower := strings.ToLower(name)
+		byName[nameLower] = append(byName[nameLower], values...)
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		values := byName[name]
+		sort.Strings(values)
+		sb.WriteByte('\n')
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(values, ","))
+	}
+	return sb.String()
+}
@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/lru"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+type StorageOptions struct {
+	// DiskDir is the directory in which the disk cache stores data. If DiskDir is empty then the disk cache is disabled.
+	// DiskDir should not be shared with other processes, because each process bounds the size of DiskDir independently.
+	DiskDir string
+	// DiskMaxBytes bounds the total size of the data in the disk cache.
+	DiskMaxBytes int64
+	// DiskNamePrefixes are the prefixes of the names of objects whose data is cached on disk.
+	DiskNamePrefixes []string
+	// MemoryMaxBytes bounds the total size of the memory cache. If MemoryMaxBytes is 0 then the memory cache is disabled.
+	MemoryMaxBytes int64
+	// MemoryMaxObjectBytes bounds the size of the data of an object cached in memory. Data of larger objects is not cached.
+	MemoryMaxObjectBytes int64
+	// MemoryNamePrefixes are the prefixes of the names of objects whose data is cached in memory. Metadata of objects
+	// matching MemoryNamePrefixes or DiskNamePrefixes is cached in memory.
+	MemoryNamePrefixes []string
+	Storage            storage.Storage
+}
+
+// Storage is a read-through cache in front of another "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage.
+//
+// Object data and metadata are cached without ever being invalidated, because objects are immutable: they are only
+// ever created exclusively and deleted. Objects deleted through Storage are removed from the cache, but objects deleted
+// through other instances are not, so only objects that are never deleted should match the configured name prefixes.
+// Errors (including NotFound errors) are not cached.
+type Storage struct {
+	disk                 *diskCache
+	diskNamePrefixes     []string
+	memory               *lru.Cache
+	memoryMaxObjectBytes int64
+	memoryNamePrefixes   []string
+	storage              storage.Storage
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.Wrapper = (*Storage)(nil)
+
+func NewStorage(opts StorageOptions) (*Storage, error) {
+	if opts.Storage == nil {
+		return nil, fmt.Errorf("opts.Storage must not be nil")
+	}
+	if opts.MemoryMaxBytes < 0 {
+		return nil, fmt.Errorf("opts.MemoryMaxBytes must be non-negative")
+	}
+	if opts.MemoryMaxBytes > 0 && (opts.MemoryMaxObjectBytes <= 0 || opts.MemoryMaxObjectBytes > opts.MemoryMaxBytes) {
+		return nil, fmt.Errorf("opts.MemoryMaxObjectBytes must be positive and at most opts.MemoryMaxBytes")
+	}
+	s := &Storage{
+		diskNamePrefixes:     opts.DiskNamePrefixes,
+		memoryMaxObjectBytes: opts.MemoryMaxObjectBytes,
+		memoryNamePrefixes:   opts.MemoryNamePrefixes,
+		storage:              opts.Storage,
+	}
+	if opts.MemoryMaxBytes > 0 {
+		s.memory = lru.New(opts.MemoryMaxBytes, nil)
+	}
+	if opts.DiskDir != "" {
+		if opts.DiskMaxBytes <= 0 {
+			return nil, fmt.Errorf("opts.DiskMaxBytes must be positive if opts.DiskDir is not empty")
+		}
+		var err error
+		s.disk, err = newDiskCache(opts.DiskDir, opts.DiskMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Storage) CreateObjectExclusively(ctx context.Context, name string, metadata storage.ObjectMetadata,
+	data io.ReadSeeker) error {
+	return s.storage.CreateObjectExclusively(ctx, name, metadata, data)
+}
+
+func (s *Storage) DeleteObject(ctx context.Context, name string) error {
+	if s.memory != nil {
+		s.memory.Remove(memoryDataKey(name))
+		s.memory.Remove(memoryMetadataKey(name))
+	}
+	if s.disk != nil {
+		s.disk.Remove(name)
+	}
+	return s.storage.DeleteObject(ctx, name)
+}
+
+func (s *Storage) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	if s.memory != nil && hasAnyPrefix(name, s.memoryNamePrefixes) {
+		if value, ok := s.memory.Get(memoryDataKey(name)); ok {
+			return io.NopCloser(bytes.NewReader(value.([]byte))), nil
+		}
+		data, err := s.storage.GetObject(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		dataBytes, err := io.ReadAll(io.LimitReader(data, s.memoryMaxObjectBytes+1))
+		if err != nil {
+			_ = data.Close()
+			return nil, err
+		}
+		if int64(len(dataBytes)) > s.memoryMaxObjectBytes {
+			return util.NewConcatReader(dataBytes, data, nil, data.Close), nil
+		}
+		if err := data.Close(); err != nil {
+			log.Errorf("error closing reader of object %#v: %v", name, err)
+		}
+		s.memory.Add(memoryDataKey(name), dataBytes, int64(len(name)+len(dataBytes)))
+		return io.NopCloser(bytes.NewReader(dataBytes)), nil
+	}
+	if s.disk != nil && hasAnyPrefix(name, s.diskNamePrefixes) {
+		if fd := s.disk.Open(name); fd != nil {
+			return fd, nil
+		}
+		data, err := s.storage.GetObject(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return s.disk.Fill(name, data), nil
+	}
+	return s.storage.GetObject(ctx, name)
+}
+
+func (s *Storage) GetObjectRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative")
+	}
+	if s.memory != nil && hasAnyPrefix(name, s.memoryNamePrefixes) {
+		if value, ok := s.memory.Get(memoryDataKey(name)); ok {
+			dataBytes := value.([]byte)
+			reader := io.NewSectionReader(bytes.NewReader(dataBytes), 0, int64(len(dataBytes)))
+			return io.NopCloser(sliceSectionReader(reader, offset, length)), int64(len(dataBytes)), nil
+		}
+	} else if s.disk != nil && hasAnyPrefix(name, s.diskNamePrefixes) {
+		if fd := s.disk.Open(name); fd != nil {
+			fileInfo, err := fd.Stat()
+			if err != nil {
+				_ = fd.Close()
+				return nil, 0, err
+			}
+			reader := io.NewSectionReader(fd, 0, fileInfo.Size())
+			return util.NewConcatReader(nil, sliceSectionReader(reader, offset, length), nil, fd.Close), fileInfo.Size(), nil
+		}
+		if offset == 0 && length < 0 {
+			data, size, err := s.storage.GetObjectRange(ctx, name, offset, length)
+			if err != nil {
+				return nil, 0, err
+			}
+			return s.disk.Fill(name, data), size, nil
+		}
+	}
+	return s.storage.GetObjectRange(ctx, name, offset, length)
+}
+
+func (s *Storage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
+	if s.memory == nil || !(hasAnyPrefix(name, s.memoryNamePrefixes) || hasAnyPrefix(name, s.diskNamePrefixes)) {
+		return s.storage.GetObjectMetadata(ctx, name)
+	}
+	if value, ok := s.memory.Get(memoryMetadataKey(name)); ok {
+		return copyMetadata(value.(storage.ObjectMetadata)), nil
+	}
+	metadata, err := s.storage.GetObjectMetadata(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(name))
+	for key, value := range metadata {
+		size += int64(len(key) + len(value))
+	}
+	s.memory.Add(memoryMetadataKey(name), copyMetadata(metadata), size)
+	return metadata, nil
+}
+
+func (s *Storage) ListObjects(ctx context.Context, opts storage.ObjectListOptions) (*storage.ObjectList, error) {
+	return s.storage.ListObjects(ctx, opts)
+}
+
+// Unwrap returns the storage that s caches.
+func (s *Storage) Unwrap() storage.Storage {
+	return s.storage
+}
+
+func copyMetadata(metadata storage.ObjectMetadata) storage.ObjectMetadata {
+	metadataCopy := make(storage.ObjectMetadata, len(metadata))
+	for key, value := range metadata {
+		metadataCopy[key] = value
+	}
+	return metadataCopy
+}
+
+// sliceSectionReader returns a reader of at most length bytes of r starting at byte offset. If length is negative then the
+// returned reader reads until the end of r.
+func sliceSectionReader(r *io.SectionReader, offset, length int64) *io.SectionReader {
+	if offset > r.Size() {
+		offset = r.Size()
+	}
+	if length < 0 || length > r.Size()-offset {
+		length = r.Size() - offset
+	}
+	return io.NewSectionReader(r, offset, length)
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func memoryDataKey(name string) string {
+	return "d:" + name
+}
+
+func memoryMetadataKey(name string) string {
+	return "m:" + name
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/memory"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/storagetest"
+)
+
+// countingStorage counts the calls to GetObject and GetObjectMetadata.
+type countingStorage struct {
+	storage.Storage
+	mu                     sync.Mutex
+	getObjectCount         int
+	getObjectMetadataCount int
+}
+
+func (c *countingStorage) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	c.getObjectCount++
+	c.mu.Unlock()
+	return c.Storage.GetObject(ctx, name)
+}
+
+func (c *countingStorage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
+	c.mu.Lock()
+	c.getObjectMetadataCount++
+	c.mu.Unlock()
+	return c.Storage.GetObjectMetadata(ctx, name)
+}
+
+func newTestStorage(t *testing.T, diskDir string) (*Storage, *countingStorage) {
+	t.Helper()
+	inner, err := memory.NewStorage(memory.StorageOptions{})
+	require.NoError(t, err)
+	counting := &countingStorage{Storage: inner}
+	s, err := NewStorage(StorageOptions{
+		DiskDir:              diskDir,
+		DiskMaxBytes:         100,
+		DiskNamePrefixes:     []string{"zip/"},
+		MemoryMaxBytes:       100,
+		MemoryMaxObjectBytes: 20,
+		MemoryNamePrefixes:   []string{"gomod/"},
+		Storage:              counting,
+	})
+	require.NoError(t, err)
+	return s, counting
+}
+
+func readObject(t *testing.T, s storage.Storage, name string) string {
+	t.Helper()
+	data, err := s.GetObject(context.Background(), name)
+	require.NoError(t, err)
+	dataBytes, err := io.ReadAll(data)
+	require.NoError(t, err)
+	require.NoError(t, data.Close())
+	return string(dataBytes)
+}
+
+func Test_Storage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, _ := newTestStorage(t, t.TempDir())
+		return s
+	})
+}
+
+func Test_Storage_Memory(t *testing.T) {
+	ctx := context.Background()
+	s, counting := newTestStorage(t, t.TempDir())
+	name := "gomod/example.com/m@v1.0.0"
+	metadata := storage.ObjectMetadata{"gomod-commit-time": "2020-01-01T00:00:00Z"}
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, metadata, strings.NewReader("module m\n")))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "module m\n", readObject(t, s, name))
+		metadataActual, err := s.GetObjectMetadata(ctx, name)
+		require.NoError(t, err)
+		assert.Equal(t, metadata, metadataActual)
+	}
+	assert.Equal(t, 1, counting.getObjectCount)
+	assert.Equal(t, 1, counting.getObjectMetadataCount)
+
+	// Objects larger than MemoryMaxObjectBytes are read in full but not cached.
+	large := "gomod/example.com/large@v1.0.0"
+	require.NoError(t, s.CreateObjectExclusively(ctx, large, nil, strings.NewReader(strings.Repeat("x", 21))))
+	assert.Equal(t, strings.Repeat("x", 21), readObject(t, s, large))
+	assert.Equal(t, strings.Repeat("x", 21), readObject(t, s, large))
+	assert.Equal(t, 3, counting.getObjectCount)
+
+	// The total size of the memory cache is bounded.
+	for _, c := range "abcdefghij" {
+		other := "gomod/" + string(c)
+		require.NoError(t, s.CreateObjectExclusively(ctx, other, nil, strings.NewReader("0123456789")))
+		readObject(t, s, other)
+		assert.LessOrEqual(t, s.memory.Size(), int64(100))
+	}
+}
+
+func Test_Storage_Disk(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, counting := newTestStorage(t, dir)
+	name := "zip/example.com/m@v1.0.0"
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, nil, strings.NewReader("zip data")))
+
+	// Data is only cached if it is read until the end.
+	data, err := s.GetObject(ctx, name)
+	require.NoError(t, err)
+	_, err = data.Read(make([]byte, 3))
+	require.NoError(t, err)
+	require.NoError(t, data.Close())
+	assert.Equal(t, "zip data", readObject(t, s, name))
+	assert.Equal(t, "zip data", readObject(t, s, name))
+	assert.Equal(t, 2, counting.getObjectCount)
+
+	// The disk cache survives restarts.
+	s2, counting2 := newTestStorage(t, dir)
+	require.NoError(t, s2.CreateObjectExclusively(ctx, name, nil, strings.NewReader("other data")))
+	assert.Equal(t, "zip data", readObject(t, s2, name))
+	assert.Equal(t, 0, counting2.getObjectCount)
+
+	// The total size of the disk cache is bounded.
+	for _, c := range "abcdefghijklmnopqrstuvwxyz" {
+		other := "zip/" + string(c)
+		require.NoError(t, s2.CreateObjectExclusively(ctx, other, nil, bytes.NewReader(make([]byte, 10))))
+		readObject(t, s2, other)
+		assert.LessOrEqual(t, s2.disk.lru.Size(), int64(100))
+	}
+	assert.Equal(t, "other data", readObject(t, s2, name))
+
+	// Deleting an object removes it from the cache.
+	require.NoError(t, s2.DeleteObject(ctx, "zip/z"))
+	_, err = s2.GetObject(ctx, "zip/z")
+	assert.Error(t, err)
+}
+
+// signingStorage implements storage.ObjectURLSigner.
+type signingStorage struct {
+	storage.Storage
+}
+
+func (s *signingStorage) SignObjectURL(ctx context.Context, name string, expires time.Duration) (string, error) {
+	return "https://storage.example.com/" + name, nil
+}
+
+func Test_Storage_AsObjectURLSigner(t *testing.T) {
+	s, _ := newTestStorage(t, t.TempDir())
+	_, ok := storage.AsObjectURLSigner(s)
+	assert.False(t, ok)
+
+	inner, err := memory.NewStorage(memory.StorageOptions{})
+	require.NoError(t, err)
+	s, err = NewStorage(StorageOptions{
+		MemoryMaxBytes:       100,
+		MemoryMaxObjectBytes: 20,
+		Storage:              &signingStorage{Storage: inner},
+	})
+	require.NoError(t, err)
+	signer, ok := storage.AsObjectURLSigner(s)
+	require.True(t, ok)
+	signedURL, err := signer.SignObjectURL(context.Background(), "zip/a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "https://storage.example.com/zip/a", signedURL)
+}
@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/lru"
+)
+
+const (
+	diskObjectsDirName = "objects"
+	diskTmpDirName     = "tmp"
+)
+
+// diskCache caches object data in files. Each file is named after the SHA-256 hash of the object name, so any object name
+// maps to a valid file name.
+type diskCache struct {
+	lru        *lru.Cache
+	objectsDir string
+	tmpDir     string
+}
+
+func newDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error making file name %#v absolute: %w", dir, err)
+	}
+	d := &diskCache{
+		objectsDir: filepath.Join(absDir, diskObjectsDirName),
+		tmpDir:     filepath.Join(absDir, diskTmpDirName),
+	}
+	d.lru = lru.New(maxBytes, func(key string, _ any) {
+		d.removeFile(key)
+	})
+	// Temporary files are left behind if the process crashes while filling the cache.
+	if err := os.RemoveAll(d.tmpDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(d.tmpDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(d.objectsDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load adds the files of a previous process to the LRU, using their modification times as last use times.
+func (d *diskCache) load() error {
+	dirEntries, err := os.ReadDir(d.objectsDir)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		modTime time.Time
+		name    string
+		size    int64
+	}
+	var files []file
+	for _, dirEntry := range dirEntries {
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if !fileInfo.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, file{
+			modTime: fileInfo.ModTime(),
+			name:    dirEntry.Name(),
+			size:    fileInfo.Size(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, file := range files {
+		if !d.lru.Add(file.name, nil, file.size) {
+			d.removeFile(file.name)
+		}
+	}
+	return nil
+}
+
+// Open returns the cached data of the object named name, or nil if the data is not cached.
+func (d *diskCache) Open(name string) *os.File {
+	key := diskCacheKey(name)
+	if _, ok := d.lru.Get(key); !ok {
+		return nil
+	}
+	file := filepath.Join(d.objectsDir, key)
+	fd, err := os.Open(file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Errorf("error opening storage cache file %#v: %v", file, err)
+		}
+		d.lru.Remove(key)
+		return nil
+	}
+	// Persist the last use time, so that the LRU order survives restarts.
+	now := time.Now()
+	if err := os.Chtimes(file, now, now); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Errorf("error updating times of storage cache file %#v: %v", file, err)
+	}
+	return fd
+}
+
+// Fill returns a reader that reads data and caches it as the data of the object named name. The data is only cached if
+// the returned reader is read until io.EOF.
+func (d *diskCache) Fill(name string, data io.ReadCloser) io.ReadCloser {
+	tmpFD, err := os.CreateTemp(d.tmpDir, "")
+	if err != nil {
+		log.Errorf("error creating temporary file to fill storage cache: %v", err)
+		return data
+	}
+	return &diskCacheFiller{
+		d:     d,
+		data:  data,
+		key:   diskCacheKey(name),
+		tmpFD: tmpFD,
+	}
+}
+
+// Remove removes the data of the object named name from the cache.
+func (d *diskCache) Remove(name string) {
+	key := diskCacheKey(name)
+	d.lru.Remove(key)
+	d.removeFile(key)
+}
+
+func (d *diskCache) removeFile(key string) {
+	file := filepath.Join(d.objectsDir, key)
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Errorf("error removing storage cache file %#v: %v", file, err)
+	}
+}
+
+func diskCacheKey(name string) string {
+	h := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(h[:])
+}
+
+type diskCacheFiller struct {
+	d     *diskCache
+	data  io.ReadCloser
+	key   string
+	size  int64
+	tmpFD *os.File
+}
+
+func (f *diskCacheFiller) Close() error {
+	f.abort()
+	return f.data.Close()
+}
+
+func (f *diskCacheFiller) Read(p []byte) (n int, err error) {
+	n, err = f.data.Read(p)
+	if f.tmpFD == nil {
+		return
+	}
+	if n > 0 {
+		if _, err2 := f.tmpFD.Write(p[:n]); err2 != nil {
+			log.Errorf("error writing temporary file to fill storage cache: %v", err2)
+			f.abort()
+			return
+		}
+		f.size += int64(n)
+	}
+	if err == io.EOF {
+		f.commit()
+	}
+	return
+}
+
+func (f *diskCacheFiller) abort() {
+	if f.tmpFD == nil {
+		return
+	}
+	if err := f.tmpFD.Close(); err != nil {
+		log.Errorf("error closing temporary file %#v: %v", f.tmpFD.Name(), err)
+	}
+	if err := os.Remove(f.tmpFD.Name()); err != nil {
+		log.Errorf("error removing temporary file %#v: %v", f.tmpFD.Name(), err)
+	}
+	f.tmpFD = nil
+}
+
+func (f *diskCacheFiller) commit() {
+	tmpFile := f.tmpFD.Name()
+	err := f.tmpFD.Close()
+	f.tmpFD = nil
+	if err == nil {
+		err = os.Rename(tmpFile, filepath.Join(f.d.objectsDir, f.key))
+	}
+	if err != nil {
+		log.Errorf("error committing temporary file %#v to storage cache: %v", tmpFile, err)
+		if err := os.Remove(tmpFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Errorf("error removing temporary file %#v: %v", tmpFile, err)
+		}
+		return
+	}
+	if !f.d.lru.Add(f.key, nil, f.size) {
+		f.d.removeFile(f.key)
+	}
+}
@@ -0,0 +1,401 @@
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+)
+
+const (
+	// defaultMaxResults is the page size used by ListObjects if opts.MaxResults is 0.
+	defaultMaxResults = 1000
+
+	// maxMetadataLength bounds the length of the metadata header of object files so that corrupt files
+	// cannot cause large allocations.
+	maxMetadataLength = 1 << 20
+
+	// objectFileSuffix is appended to the encoded last component of an object name to get the object's file name.
+	// The suffix cannot be produced by encodeNameComponent, so object files never collide with directories.
+	objectFileSuffix = "%obj"
+
+	objectsDirName = "objects"
+	tmpDirName     = "tmp"
+)
+
+type StorageOptions struct {
+	// Dir is the directory in which objects are stored. Dir is created if it does not exist.
+	// Multiple processes can safely share Dir, as long as Dir is on a file system that supports hard links.
+	Dir string
+}
+
+// Storage is an implementation of "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage that stores
+// objects as files on the local file system.
+//
+// Each object is a single file that starts with a header containing the object's metadata, followed by the object's data.
+// Objects are created by writing a temporary file and hard-linking it to the object's file name. Hard-linking fails if
+// the target exists, which makes CreateObjectExclusively atomic across processes sharing the same directory.
+type Storage struct {
+	objectsDir string
+	tmpDir     string
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+func NewStorage(opts StorageOptions) (*Storage, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("opts.Dir must not be empty")
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("error making file name %#v absolute: %w", opts.Dir, err)
+	}
+	s := &Storage{
+		objectsDir: filepath.Join(dir, objectsDirName),
+		tmpDir:     filepath.Join(dir, tmpDirName),
+	}
+	if err := os.MkdirAll(s.objectsDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.tmpDir, 0700); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Storage) CreateObjectExclusively(ctx context.Context, name string, metadata storage.ObjectMetadata,
+	data io.ReadSeeker) (err error) {
+	file, err := s.objectFile(name)
+	if err != nil {
+		return
+	}
+	if _, err = os.Lstat(file); err == nil {
+		err = internalErrors.NewErrorf(internalErrors.PreconditionFailed, "object %#v already exists", name)
+		return
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	_, err = data.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+	tmpFD, err := os.CreateTemp(s.tmpDir, "")
+	if err != nil {
+		return
+	}
+	tmpFile := tmpFD.Name()
+	defer func() {
+		err2 := os.Remove(tmpFile)
+		if err2 != nil && !errors.Is(err2, fs.ErrNotExist) {
+			log.Errorf("error removing temporary file %#v: %v", tmpFile, err2)
+		}
+	}()
+	err = writeObjectFile(ctx, tmpFD, metadata, data)
+	err2 := tmpFD.Close()
+	if err != nil {
+		return
+	}
+	if err2 != nil {
+		err = err2
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return
+	}
+	if err = os.Link(tmpFile, file); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			err = internalErrors.NewErrorf(internalErrors.PreconditionFailed, "object %#v already exists", name)
+		}
+		return
+	}
+	return
+}
+
+func (s *Storage) DeleteObject(ctx context.Context, name string) error {
+	file, err := s.objectFile(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return internalErrors.NewErrorf(internalErrors.NotFound, "object %#v does not exist", name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	fd, _, dataOffset, err := s.openObjectFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fd.Seek(dataOffset, io.SeekStart); err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	return fd, nil
+}
+
+func (s *Storage) GetObjectRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative")
+	}
+	fd, _, dataOffset, err := s.openObjectFile(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	fileInfo, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return nil, 0, err
+	}
+	size := fileInfo.Size() - dataOffset
+	start := offset
+	if start > size {
+		start = size
+	}
+	end := size
+	if length >= 0 && length < end-start {
+		end = start + length
+	}
+	return &sectionReadCloser{
+		SectionReader: io.NewSectionReader(fd, dataOffset+start, end-start),
+		fd:            fd,
+	}, size, nil
+}
+
+func (s *Storage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
+	fd, metadata, _, err := s.openObjectFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := fd.Close(); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (s *Storage) ListObjects(ctx context.Context, opts storage.ObjectListOptions) (*storage.ObjectList, error) {
+	if opts.MaxResults < 0 {
+		return nil, fmt.Errorf("opts.MaxResults must be non-negative")
+	}
+	maxResults := opts.MaxResults
+	if maxResults == 0 {
+		maxResults = defaultMaxResults
+	}
+	// Only walk the directory that corresponds to the part of opts.NamePrefix up to and including the last "/".
+	dirNamePrefix := opts.NamePrefix[:strings.LastIndexByte(opts.NamePrefix, '/')+1]
+	dir := s.objectsDir
+	for _, component := range strings.Split(strings.TrimSuffix(dirNamePrefix, "/"), "/") {
+		if component == "" {
+			if dirNamePrefix != "" {
+				return &storage.ObjectList{}, nil
+			}
+			break
+		}
+		dir = filepath.Join(dir, encodeNameComponent(component))
+	}
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), objectFileSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.objectsDir, path)
+		if err != nil {
+			return err
+		}
+		name, err := decodeName(strings.TrimSuffix(filepath.ToSlash(rel), objectFileSuffix))
+		if err != nil {
+			log.Errorf("ignoring file %#v in storage directory: %v", path, err)
+			return nil
+		}
+		if strings.HasPrefix(name, opts.NamePrefix) && name > opts.PageToken {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	objList := &storage.ObjectList{}
+	if len(names) > maxResults {
+		names = names[:maxResults]
+		objList.NextPageToken = names[len(names)-1]
+	}
+	objList.Names = names
+	return objList, nil
+}
+
+func (s *Storage) objectFile(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("name must not be empty")
+	}
+	components := strings.Split(name, "/")
+	elems := make([]string, 0, len(components)+1)
+	elems = append(elems, s.objectsDir)
+	for _, component := range components {
+		if component == "" {
+			return "", fmt.Errorf(`name %#v is invalid or not supported: name must not start or end with "/" and must not contain "//"`, name)
+		}
+		elems = append(elems, encodeNameComponent(component))
+	}
+	elems[len(elems)-1] += objectFileSuffix
+	return filepath.Join(elems...), nil
+}
+
+func (s *Storage) openObjectFile(name string) (fd *os.File, metadata storage.ObjectMetadata, dataOffset int64, err error) {
+	file, err := s.objectFile(name)
+	if err != nil {
+		return
+	}
+	fd, err = os.Open(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = internalErrors.NewErrorf(internalErrors.NotFound, "object %#v does not exist", name)
+		}
+		return
+	}
+	metadata, dataOffset, err = readObjectFileHeader(fd)
+	if err != nil {
+		err2 := fd.Close()
+		if err2 != nil {
+			log.Errorf("error closing file %#v: %v", file, err2)
+		}
+		fd = nil
+		err = fmt.Errorf("error reading header of file %#v: %w", file, err)
+	}
+	return
+}
+
+// readObjectFileHeader reads the header written by writeObjectFile and returns the metadata and the offset of the
+// object's data.
+func readObjectFileHeader(r io.Reader) (storage.ObjectMetadata, int64, error) {
+	br := bufio.NewReader(r)
+	metadataLength, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, 0, fmt.Errorf("file does not start with a valid 64-bit varint: %w", err)
+	}
+	if metadataLength > maxMetadataLength {
+		return nil, 0, fmt.Errorf("file's metadata length (%d) is too large", metadataLength)
+	}
+	var arr [binary.MaxVarintLen64]byte
+	varintLength := binary.PutUvarint(arr[:], metadataLength)
+	metadataJSONBytes := make([]byte, int(metadataLength))
+	if _, err := io.ReadFull(br, metadataJSONBytes); err != nil {
+		return nil, 0, fmt.Errorf("error reading file's metadata: %w", err)
+	}
+	var metadata storage.ObjectMetadata
+	if err := json.Unmarshal(metadataJSONBytes, &metadata); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshalling file's metadata: %w", err)
+	}
+	return metadata, int64(varintLength) + int64(metadataLength), nil
+}
+
+func writeObjectFile(ctx context.Context, fd *os.File, metadata storage.ObjectMetadata, data io.Reader) error {
+	metadataJSONBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	var arr [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(arr[:], uint64(len(metadataJSONBytes)))
+	w := bufio.NewWriter(fd)
+	_, _ = w.Write(arr[:n])
+	_, _ = w.Write(metadataJSONBytes)
+	if _, err := io.Copy(w, data); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fd.Sync()
+}
+
+// encodeNameComponent encodes a component of an object name (a substring not containing "/") such that the result is a valid
+// file name on common file systems, does not depend on case sensitivity of the file system, and is never equal to "." or "..".
+// Bytes in the set [a-z0-9._~@+-] are kept (except for a leading "."), all other bytes are encoded as "%" followed by two
+// upper case hexadecimal digits.
+func encodeNameComponent(component string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(component); i++ {
+		c := component[i]
+		if ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || strings.IndexByte("_~@+-", c) >= 0 || (c == '.' && i > 0) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0xF])
+		}
+	}
+	return sb.String()
+}
+
+// decodeName is the inverse of encoding each "/"-separated component of a name with encodeNameComponent.
+func decodeName(encodedName string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(encodedName); i++ {
+		c := encodedName[i]
+		if c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(encodedName) {
+			return "", fmt.Errorf("encoded name %#v has an invalid escape sequence", encodedName)
+		}
+		hi, ok1 := unhex(encodedName[i+1])
+		lo, ok2 := unhex(encodedName[i+2])
+		if !ok1 || !ok2 {
+			return "", fmt.Errorf("encoded name %#v has an invalid escape sequence", encodedName)
+		}
+		sb.WriteByte(hi<<4 | lo)
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	fd *os.File
+}
+
+func (s *sectionReadCloser) Close() error {
+	return s.fd.Close()
+}
@@ -0,0 +1,79 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/storagetest"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := NewStorage(StorageOptions{
+		Dir: t.TempDir(),
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func Test_Storage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t)
+	})
+}
+
+func Test_Storage_CreateObjectExclusively_Race(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	const n = 16
+	var waitGroup sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		// Use a separate *Storage per goroutine to mimic separate processes sharing dir.
+		s, err := NewStorage(StorageOptions{Dir: dir})
+		require.NoError(t, err)
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			errs[i] = s.CreateObjectExclusively(ctx, "concat/example.com/m@v1.0.0", nil, bytes.NewReader([]byte(fmt.Sprint(i))))
+		}(i)
+	}
+	waitGroup.Wait()
+	successCount := 0
+	for _, err := range errs {
+		if err == nil {
+			successCount++
+		} else {
+			assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+		}
+	}
+	assert.Equal(t, 1, successCount)
+}
+
+func Test_encodeNameComponent(t *testing.T) {
+	for _, c := range []struct {
+		Input    string
+		Expected string
+	}{
+		{"github.com", "github.com"},
+		{"Azure", "%41zure"},
+		{"..", "%2E."},
+		{"v1.0.0+incompatible", "v1.0.0+incompatible"},
+		{"a%obj", "a%25obj"},
+	} {
+		actual := encodeNameComponent(c.Input)
+		assert.Equal(t, c.Expected, actual)
+		decoded, err := decodeName(actual)
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.Input, decoded)
+		}
+	}
+}
@@ -48,6 +48,7 @@ type Storage struct {
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.ObjectURLSigner = (*Storage)(nil)
 
 func NewStorage(opts StorageOptions) (*Storage, error) {
 	if opts.Bucket == "" {
@@ -229,6 +230,32 @@ func (s *Storage) GetObject(ctx context.Context, name string) (io.ReadCloser, er
 	return data, nil
 }
 
+func (s *Storage) GetObjectRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	if name == "" {
+		return nil, 0, fmt.Errorf("name must not be empty")
+	}
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative")
+	}
+	obj := s.gcsClientBucket.Object(name)
+	if length != 0 {
+		data, err := obj.NewRangeReader(ctx, offset, length)
+		if err == nil {
+			return data, data.Attrs.Size, nil
+		}
+		var googleAPIErr *googleapi.Error
+		if !errors.As(err, &googleAPIErr) || googleAPIErr.Code != http.StatusRequestedRangeNotSatisfiable {
+			return nil, 0, mapGCSPackageError(err)
+		}
+		// offset is greater than or equal to the size of the object's data.
+	}
+	objAttrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, 0, mapGCSPackageError(err)
+	}
+	return io.NopCloser(bytes.NewReader(nil)), objAttrs.Size, nil
+}
+
 func (s *Storage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
 	if name == "" {
 		return nil, fmt.Errorf("name must not be empty")
@@ -351,6 +378,22 @@ func (s *Storage) ListObjects(ctx context.Context, opts storage.ObjectListOption
 	}
 }
 
+// SignObjectURL returns a V4 signed URL. See
+// "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".ObjectURLSigner.
+// The URL is signed with the credentials of the GCS client. If these are not the credentials of a service account with
+// a private key (i.e. on Compute Engine) then the URL is signed by the IAM Credentials API, which requires the
+// service account to have the iam.serviceAccounts.signBlob permission on itself.
+func (s *Storage) SignObjectURL(ctx context.Context, name string, expires time.Duration) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("name must not be empty")
+	}
+	return s.gcsClientBucket.SignedURL(name, &gcs.SignedURLOptions{
+		Expires: time.Now().Add(expires),
+		Method:  http.MethodGet,
+		Scheme:  gcs.SigningSchemeV4,
+	})
+}
+
 func mapGCSPackageError(err error) error {
 	if err == gcs.ErrObjectNotExist {
 		return internalErrors.NewError(internalErrors.NotFound, err.Error())
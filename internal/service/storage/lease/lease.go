@@ -0,0 +1,155 @@
+// Package lease implements leases on top of "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage.
+//
+// A lease is an object that is created exclusively and deleted when the lease is released. Leases expire, so that a
+// lease of a holder that died can be acquired by another holder. storage.Storage does not support conditional deletes,
+// so if two holders acquire the same expired lease at the same time then both can briefly believe they hold the lease.
+// Leases should therefore be used to avoid duplicate work, and not to protect work that is unsafe to do concurrently.
+package lease
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+)
+
+const (
+	metadataKeyExpires = "lease-expires"
+	metadataKeyHolder  = "lease-holder"
+)
+
+// Lease is a lease held by a holder. Lease is not safe for concurrent use.
+type Lease struct {
+	expires time.Time
+	holder  string
+	name    string
+	storage storage.Storage
+	ttl     time.Duration
+}
+
+// Acquire acquires the lease named name on behalf of holder for duration ttl. name is the name of the object used to
+// represent the lease. If the lease is held by another holder and has not expired then Acquire returns an error e such
+// that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, PreconditionFailed) is true.
+func Acquire(ctx context.Context, s storage.Storage, name, holder string, ttl time.Duration) (*Lease, error) {
+	if holder == "" {
+		return nil, fmt.Errorf("holder must not be empty")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	// Two attempts: the second attempt follows deleting an expired lease.
+	for attempt := 0; attempt < 2; attempt++ {
+		expires := time.Now().Add(ttl)
+		err := s.CreateObjectExclusively(ctx, name, storage.ObjectMetadata{
+			metadataKeyExpires: expires.UTC().Format(time.RFC3339Nano),
+			metadataKeyHolder:  holder,
+		}, bytes.NewReader(nil))
+		if err == nil {
+			return &Lease{
+				expires: expires,
+				holder:  holder,
+				name:    name,
+				storage: s,
+				ttl:     ttl,
+			}, nil
+		}
+		if !internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return nil, err
+		}
+		metadata, err := s.GetObjectMetadata(ctx, name)
+		if err != nil {
+			if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+				// Released in the meantime.
+				continue
+			}
+			return nil, err
+		}
+		// A lease with an unparsable expiry is treated as expired, so that it can not block forever.
+		otherExpires, err := time.Parse(time.RFC3339Nano, metadata[metadataKeyExpires])
+		if err == nil && time.Now().Before(otherExpires) {
+			return nil, internalErrors.NewErrorf(internalErrors.PreconditionFailed, "lease %#v is held by %#v until %s", name,
+				metadata[metadataKeyHolder], otherExpires.UTC().Format(time.RFC3339))
+		}
+		// Another holder may have taken over the expired lease in the meantime, in which case its lease must not be
+		// deleted. This check narrows but does not close the window in which that can happen (see package doc).
+		metadata2, err := s.GetObjectMetadata(ctx, name)
+		if err != nil {
+			if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if metadata2[metadataKeyHolder] != metadata[metadataKeyHolder] || metadata2[metadataKeyExpires] != metadata[metadataKeyExpires] {
+			continue
+		}
+		log.Infof("deleting expired lease %#v of holder %#v", name, metadata[metadataKeyHolder])
+		if err := s.DeleteObject(ctx, name); err != nil && !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			return nil, err
+		}
+	}
+	return nil, internalErrors.NewErrorf(internalErrors.PreconditionFailed, "lease %#v is contended", name)
+}
+
+// Expires returns the time at which l expires.
+func (l *Lease) Expires() time.Time {
+	return l.expires
+}
+
+// Renew extends l such that it expires after the time to live passed to Acquire. Because objects can not be updated,
+// Renew replaces the object that represents l. If l was lost (i.e. because it expired and was acquired by another holder)
+// then Renew returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e,
+// PreconditionFailed) is true.
+func (l *Lease) Renew(ctx context.Context) error {
+	metadata, err := l.storage.GetObjectMetadata(ctx, l.name)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			return internalErrors.NewErrorf(internalErrors.PreconditionFailed, "lease %#v was lost", l.name)
+		}
+		return err
+	}
+	if metadata[metadataKeyHolder] != l.holder || metadata[metadataKeyExpires] != l.expires.UTC().Format(time.RFC3339Nano) {
+		return internalErrors.NewErrorf(internalErrors.PreconditionFailed, "lease %#v was lost to holder %#v", l.name,
+			metadata[metadataKeyHolder])
+	}
+	if err := l.storage.DeleteObject(ctx, l.name); err != nil && !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		return err
+	}
+	expires := time.Now().Add(l.ttl)
+	err = l.storage.CreateObjectExclusively(ctx, l.name, storage.ObjectMetadata{
+		metadataKeyExpires: expires.UTC().Format(time.RFC3339Nano),
+		metadataKeyHolder:  l.holder,
+	}, bytes.NewReader(nil))
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			return internalErrors.NewErrorf(internalErrors.PreconditionFailed, "lease %#v was lost while renewing it", l.name)
+		}
+		return err
+	}
+	l.expires = expires
+	return nil
+}
+
+// Release releases l. Release does nothing if l expired and was acquired by another holder, or by the same holder again
+// (i.e. by another goroutine of the same process).
+func (l *Lease) Release(ctx context.Context) error {
+	metadata, err := l.storage.GetObjectMetadata(ctx, l.name)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+			return nil
+		}
+		return err
+	}
+	if metadata[metadataKeyHolder] != l.holder || metadata[metadataKeyExpires] != l.expires.UTC().Format(time.RFC3339Nano) {
+		return nil
+	}
+	err = l.storage.DeleteObject(ctx, l.name)
+	if err != nil && !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package lease
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/memory"
+)
+
+const name = "lease/concat/example.com/m@v1.0.0"
+
+func newStorage(t *testing.T) *memory.Storage {
+	s, err := memory.NewStorage(memory.StorageOptions{})
+	require.NoError(t, err)
+	return s
+}
+
+func Test_Acquire(t *testing.T) {
+	ctx := context.Background()
+	s := newStorage(t)
+	lease, err := Acquire(ctx, s, name, "a", time.Minute)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lease.Expires(), 10*time.Second)
+
+	_, err = Acquire(ctx, s, name, "b", time.Minute)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+
+	require.NoError(t, lease.Release(ctx))
+	_, err = s.GetObjectMetadata(ctx, name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+
+	_, err = Acquire(ctx, s, name, "b", time.Minute)
+	assert.NoError(t, err)
+}
+
+func Test_Acquire_Expired(t *testing.T) {
+	ctx := context.Background()
+	s := newStorage(t)
+	leaseA, err := Acquire(ctx, s, name, "a", time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	leaseB, err := Acquire(ctx, s, name, "b", time.Minute)
+	require.NoError(t, err)
+
+	// Releasing a lease that was acquired by another holder does not release the other holder's lease.
+	require.NoError(t, leaseA.Release(ctx))
+	_, err = Acquire(ctx, s, name, "c", time.Minute)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+	require.NoError(t, leaseB.Release(ctx))
+}
+
+func Test_Release_ReacquiredBySameHolder(t *testing.T) {
+	ctx := context.Background()
+	s := newStorage(t)
+	lease1, err := Acquire(ctx, s, name, "a", time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	lease2, err := Acquire(ctx, s, name, "a", time.Minute)
+	require.NoError(t, err)
+
+	// Releasing the expired lease does not release the lease acquired again by the same holder.
+	require.NoError(t, lease1.Release(ctx))
+	_, err = Acquire(ctx, s, name, "b", time.Minute)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+	require.NoError(t, lease2.Release(ctx))
+	_, err = s.GetObjectMetadata(ctx, name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+}
+
+func Test_Acquire_InvalidExpires(t *testing.T) {
+	ctx := context.Background()
+	s := newStorage(t)
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, storage.ObjectMetadata{metadataKeyHolder: "a"}, bytes.NewReader(nil)))
+	_, err := Acquire(ctx, s, name, "b", time.Minute)
+	assert.NoError(t, err)
+}
+
+func Test_Renew(t *testing.T) {
+	ctx := context.Background()
+	s := newStorage(t)
+	lease, err := Acquire(ctx, s, name, "a", time.Minute)
+	require.NoError(t, err)
+	expires := lease.Expires()
+	time.Sleep(time.Millisecond)
+	require.NoError(t, lease.Renew(ctx))
+	assert.True(t, lease.Expires().After(expires))
+	_, err = Acquire(ctx, s, name, "b", time.Minute)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+
+	// Another holder takes over after the lease expired.
+	require.NoError(t, s.DeleteObject(ctx, name))
+	leaseB, err := Acquire(ctx, s, name, "b", time.Minute)
+	require.NoError(t, err)
+	err = lease.Renew(ctx)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+	require.NoError(t, leaseB.Renew(ctx))
+}
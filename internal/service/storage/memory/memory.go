@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+)
+
+// defaultMaxResults is the page size used by ListObjects if opts.MaxResults is 0.
+const defaultMaxResults = 1000
+
+type StorageOptions struct {
+	// MaxPageSize, if positive, caps the number of names returned by a single call to ListObjects regardless of
+	// opts.MaxResults. This is useful for tests of code that must handle pages with fewer names than requested.
+	MaxPageSize int
+}
+
+type object struct {
+	data     []byte
+	metadata storage.ObjectMetadata
+}
+
+// Storage is an implementation of "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage that stores
+// objects in memory. Objects do not survive the process and are not shared between replicas, so Storage is mostly useful
+// for tests and development loops.
+type Storage struct {
+	maxPageSize int
+	mu          sync.RWMutex
+	objects     map[string]*object
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+func NewStorage(opts StorageOptions) (*Storage, error) {
+	if opts.MaxPageSize < 0 {
+		return nil, fmt.Errorf("opts.MaxPageSize must be non-negative")
+	}
+	return &Storage{
+		maxPageSize: opts.MaxPageSize,
+		objects:     map[string]*object{},
+	}, nil
+}
+
+func (s *Storage) CreateObjectExclusively(ctx context.Context, name string, metadata storage.ObjectMetadata,
+	data io.ReadSeeker) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dataBytes, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	obj := &object{
+		data:     dataBytes,
+		metadata: storage.ObjectMetadata{},
+	}
+	for key, value := range metadata {
+		obj.metadata[key] = value
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[name]; ok {
+		return internalErrors.NewErrorf(internalErrors.PreconditionFailed, "object %#v already exists", name)
+	}
+	s.objects[name] = obj
+	return nil
+}
+
+func (s *Storage) DeleteObject(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[name]; !ok {
+		return notFoundError(name)
+	}
+	delete(s.objects, name)
+	return nil
+}
+
+func (s *Storage) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	obj := s.objects[name]
+	s.mu.RUnlock()
+	if obj == nil {
+		return nil, notFoundError(name)
+	}
+	// obj.data is never mutated, so it is safe to read without holding s.mu.
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (s *Storage) GetObjectRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative")
+	}
+	s.mu.RLock()
+	obj := s.objects[name]
+	s.mu.RUnlock()
+	if obj == nil {
+		return nil, 0, notFoundError(name)
+	}
+	size := int64(len(obj.data))
+	start := offset
+	if start > size {
+		start = size
+	}
+	end := size
+	if length >= 0 && length < end-start {
+		end = start + length
+	}
+	return io.NopCloser(bytes.NewReader(obj.data[start:end])), size, nil
+}
+
+func (s *Storage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
+	s.mu.RLock()
+	obj := s.objects[name]
+	s.mu.RUnlock()
+	if obj == nil {
+		return nil, notFoundError(name)
+	}
+	metadata := make(storage.ObjectMetadata, len(obj.metadata))
+	for key, value := range obj.metadata {
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+func (s *Storage) ListObjects(ctx context.Context, opts storage.ObjectListOptions) (*storage.ObjectList, error) {
+	if opts.MaxResults < 0 {
+		return nil, fmt.Errorf("opts.MaxResults must be non-negative")
+	}
+	maxResults := opts.MaxResults
+	if maxResults == 0 {
+		maxResults = defaultMaxResults
+	}
+	if s.maxPageSize > 0 && maxResults > s.maxPageSize {
+		maxResults = s.maxPageSize
+	}
+	var names []string
+	s.mu.RLock()
+	for name := range s.objects {
+		if strings.HasPrefix(name, opts.NamePrefix) && name > opts.PageToken {
+			names = append(names, name)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+	objList := &storage.ObjectList{}
+	if len(names) > maxResults {
+		names = names[:maxResults]
+		objList.NextPageToken = names[len(names)-1]
+	}
+	objList.Names = names
+	return objList, nil
+}
+
+func notFoundError(name string) error {
+	return internalErrors.NewErrorf(internalErrors.NotFound, "object %#v does not exist", name)
+}
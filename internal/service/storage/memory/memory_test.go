@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/storagetest"
+)
+
+func Test_Storage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(StorageOptions{})
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func Test_Storage_MaxPageSize(t *testing.T) {
+	// Pages with fewer names than requested must not end listing.
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewStorage(StorageOptions{MaxPageSize: 1})
+		require.NoError(t, err)
+		return s
+	})
+}
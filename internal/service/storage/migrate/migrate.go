@@ -0,0 +1,322 @@
+// Package migrate copies objects from one "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage to
+// another.
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+)
+
+// progressInterval is the number of objects after which progress is logged.
+const progressInterval = 1000
+
+type Options struct {
+	Destination storage.Storage
+	// NamePrefixes are the prefixes of the names of the objects to copy.
+	NamePrefixes []string
+	// Parallelism is the maximum number of objects copied at the same time.
+	Parallelism int
+	// ScratchDir is the directory in which the data of objects is buffered, because
+	// storage.Storage.CreateObjectExclusively needs an io.ReadSeeker. If ScratchDir is empty then os.TempDir() is used.
+	ScratchDir string
+	Source     storage.Storage
+	// Verify, if true, makes Migrate compare the data and metadata of each object in Destination with that of the same
+	// object in Source after copying (or after finding that the object already exists in Destination).
+	Verify bool
+}
+
+// ObjectError is an error that occurred while migrating a single object.
+type ObjectError struct {
+	Name string
+	Err  error
+}
+
+// Report is the result of a migration.
+type Report struct {
+	// Copied is the number of objects that were copied.
+	Copied int
+	// Existed is the number of objects that were not copied because they already existed in the destination, for
+	// example because a previous migration was interrupted.
+	Existed int
+	// Failed are the objects that could not be copied or verified.
+	Failed []ObjectError
+	// Mismatched are the objects whose data or metadata in the destination differs from that in the source.
+	Mismatched []ObjectError
+	// Vanished is the number of objects that were listed but deleted from the source before they could be copied or
+	// verified. A server using the source deletes "concat/" objects after indexing them.
+	Vanished int
+	// Verified is the number of objects whose data and metadata in the destination equals that in the source.
+	Verified int
+}
+
+// OK returns true if and only if no object failed or mismatched.
+func (r *Report) OK() bool {
+	return len(r.Failed) == 0 && len(r.Mismatched) == 0
+}
+
+// WriteTo writes a human-readable summary of r to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "copied: %d\n", r.Copied)
+	fmt.Fprintf(&buf, "already existed: %d\n", r.Existed)
+	fmt.Fprintf(&buf, "vanished from source: %d\n", r.Vanished)
+	fmt.Fprintf(&buf, "verified: %d\n", r.Verified)
+	fmt.Fprintf(&buf, "mismatched: %d\n", len(r.Mismatched))
+	for _, objErr := range r.Mismatched {
+		fmt.Fprintf(&buf, "  - %s: %v\n", objErr.Name, objErr.Err)
+	}
+	fmt.Fprintf(&buf, "failed: %d\n", len(r.Failed))
+	for _, objErr := range r.Failed {
+		fmt.Fprintf(&buf, "  - %s: %v\n", objErr.Name, objErr.Err)
+	}
+	return buf.WriteTo(w)
+}
+
+type migrator struct {
+	opts Options
+	// mu guards report.
+	mu     sync.Mutex
+	report *Report
+}
+
+// Migrate copies all objects whose names start with any of opts.NamePrefixes from opts.Source to opts.Destination using
+// CreateObjectExclusively. Objects that already exist in opts.Destination are not copied, so an interrupted migration
+// can be resumed by running Migrate again.
+//
+// Migrate only returns an error if listing fails or ctx is done. Errors migrating individual objects are collected in
+// the report.
+func Migrate(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Source == nil {
+		return nil, fmt.Errorf("opts.Source must not be nil")
+	}
+	if opts.Destination == nil {
+		return nil, fmt.Errorf("opts.Destination must not be nil")
+	}
+	if opts.Parallelism <= 0 {
+		return nil, fmt.Errorf("opts.Parallelism must be positive")
+	}
+	m := &migrator{
+		opts:   opts,
+		report: &Report{},
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	names := make(chan string)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < opts.Parallelism; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for name := range names {
+				m.migrateObject(ctx, name)
+			}
+		}()
+	}
+	err := m.list(ctx, names)
+	close(names)
+	waitGroup.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortObjectErrors(m.report.Failed)
+	sortObjectErrors(m.report.Mismatched)
+	return m.report, nil
+}
+
+func (m *migrator) list(ctx context.Context, names chan<- string) error {
+	for _, namePrefix := range m.opts.NamePrefixes {
+		opts := storage.ObjectListOptions{NamePrefix: namePrefix}
+		for {
+			objList, err := m.opts.Source.ListObjects(ctx, opts)
+			if err != nil {
+				return fmt.Errorf("error listing objects with name prefix %#v: %w", namePrefix, err)
+			}
+			for _, name := range objList.Names {
+				select {
+				case names <- name:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if objList.NextPageToken == "" {
+				break
+			}
+			opts.PageToken = objList.NextPageToken
+		}
+	}
+	return nil
+}
+
+func (m *migrator) migrateObject(ctx context.Context, name string) {
+	copied, sourceHash, err := m.copyObject(ctx, name)
+	if err == nil && m.opts.Verify {
+		err = m.verifyObject(ctx, name, sourceHash)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.report
+	if err == nil {
+		if copied {
+			r.Copied++
+		} else {
+			r.Existed++
+		}
+		if m.opts.Verify {
+			r.Verified++
+		}
+	} else if internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		r.Vanished++
+	} else if mismatch, ok := err.(*mismatchError); ok {
+		if copied {
+			r.Copied++
+		} else {
+			r.Existed++
+		}
+		r.Mismatched = append(r.Mismatched, ObjectError{Name: name, Err: mismatch})
+	} else {
+		r.Failed = append(r.Failed, ObjectError{Name: name, Err: err})
+	}
+	if n := r.Copied + r.Existed + r.Vanished + len(r.Failed); n%progressInterval == 0 {
+		log.Infof("migrated %d objects (%d copied, %d already existed)", n, r.Copied, r.Existed)
+	}
+}
+
+// copyObject copies the object named name unless it already exists in the destination. If the object was copied then
+// the SHA-256 hash of its data is also returned. Returns an error e such that
+// "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound) is true if the object does not exist in
+// the source.
+func (m *migrator) copyObject(ctx context.Context, name string) (copied bool, sourceHash []byte, err error) {
+	_, err = m.opts.Destination.GetObjectMetadata(ctx, name)
+	if err == nil {
+		return
+	}
+	if !internalErrors.ErrorIsCode(err, internalErrors.NotFound) {
+		err = fmt.Errorf("error getting metadata of object in destination: %w", err)
+		return
+	}
+	metadata, err := m.opts.Source.GetObjectMetadata(ctx, name)
+	if err != nil {
+		return
+	}
+	data, err := m.opts.Source.GetObject(ctx, name)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err2 := data.Close(); err2 != nil {
+			log.Errorf("error closing reader of object %#v: %v", name, err2)
+		}
+	}()
+	tempFile, err := os.CreateTemp(m.opts.ScratchDir, "migrate-storage-")
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = tempFile.Close()
+		if err2 := os.Remove(tempFile.Name()); err2 != nil {
+			log.Errorf("error removing temporary file %#v: %v", tempFile.Name(), err2)
+		}
+	}()
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(tempFile, hash), data); err != nil {
+		err = fmt.Errorf("error reading data of object in source: %w", err)
+		return
+	}
+	err = m.opts.Destination.CreateObjectExclusively(ctx, name, metadata, tempFile)
+	if err != nil {
+		if internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed) {
+			// Another migration created the object concurrently.
+			err = nil
+		}
+		return
+	}
+	log.Debugf("copied object %#v", name)
+	copied = true
+	sourceHash = hash.Sum(nil)
+	return
+}
+
+type mismatchError struct {
+	msg string
+}
+
+func (m *mismatchError) Error() string {
+	return m.msg
+}
+
+// verifyObject compares the object named name in the destination with the same object in the source. sourceHash is
+// the SHA-256 hash of the data of the object in the source, or nil if it is unknown.
+func (m *migrator) verifyObject(ctx context.Context, name string, sourceHash []byte) error {
+	sourceMetadata, err := m.opts.Source.GetObjectMetadata(ctx, name)
+	if err != nil {
+		return err
+	}
+	// Errors from the destination are not wrapped, so that they are not mistaken for the object vanishing from the source.
+	destinationMetadata, err := m.opts.Destination.GetObjectMetadata(ctx, name)
+	if err != nil {
+		return fmt.Errorf("error getting metadata of object in destination: %v", err)
+	}
+	if !metadataEqual(sourceMetadata, destinationMetadata) {
+		return &mismatchError{msg: fmt.Sprintf("metadata in destination (%v) differs from metadata in source (%v)",
+			destinationMetadata, sourceMetadata)}
+	}
+	if sourceHash == nil {
+		sourceHash, err = hashObject(ctx, m.opts.Source, name)
+		if err != nil {
+			return err
+		}
+	}
+	destinationHash, err := hashObject(ctx, m.opts.Destination, name)
+	if err != nil {
+		return fmt.Errorf("error reading data of object in destination: %v", err)
+	}
+	if !bytes.Equal(sourceHash, destinationHash) {
+		return &mismatchError{msg: "data in destination differs from data in source"}
+	}
+	return nil
+}
+
+func hashObject(ctx context.Context, s storage.Storage, name string) ([]byte, error) {
+	data, err := s.GetObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, data); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+func metadataEqual(a, b storage.ObjectMetadata) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if valueB, ok := b[key]; !ok || value != valueB {
+			return false
+		}
+	}
+	return true
+}
+
+func sortObjectErrors(objErrs []ObjectError) {
+	sort.Slice(objErrs, func(i, j int) bool {
+		return objErrs[i].Name < objErrs[j].Name
+	})
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/memory"
+)
+
+var namePrefixes = []string{"gomod/", "zip/", "concat/"}
+
+func newStorage(t *testing.T) *memory.Storage {
+	s, err := memory.NewStorage(memory.StorageOptions{MaxPageSize: 3})
+	require.NoError(t, err)
+	return s
+}
+
+func newSource(t *testing.T) *memory.Storage {
+	ctx := context.Background()
+	source := newStorage(t)
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("example.com/m@v1.%d.0", i)
+		metadata := storage.ObjectMetadata{"gomod-commit-time": fmt.Sprint(i)}
+		require.NoError(t, source.CreateObjectExclusively(ctx, "gomod/"+name, metadata, bytes.NewReader([]byte(name))))
+		require.NoError(t, source.CreateObjectExclusively(ctx, "zip/"+name, nil, bytes.NewReader([]byte("zip "+name))))
+	}
+	require.NoError(t, source.CreateObjectExclusively(ctx, "concat/example.com/m@v2.0.0", nil, bytes.NewReader([]byte("c"))))
+	// Not migrated because no name prefix matches.
+	require.NoError(t, source.CreateObjectExclusively(ctx, "other/x", nil, bytes.NewReader(nil)))
+	return source
+}
+
+func Test_Migrate(t *testing.T) {
+	ctx := context.Background()
+	source := newSource(t)
+	destination := newStorage(t)
+	opts := Options{
+		Destination:  destination,
+		NamePrefixes: namePrefixes,
+		Parallelism:  4,
+		ScratchDir:   t.TempDir(),
+		Source:       source,
+		Verify:       true,
+	}
+	report, err := Migrate(ctx, opts)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 21, report.Copied)
+	assert.Equal(t, 0, report.Existed)
+	assert.Equal(t, 21, report.Verified)
+
+	for _, name := range []string{"gomod/example.com/m@v1.3.0", "zip/example.com/m@v1.3.0", "concat/example.com/m@v2.0.0"} {
+		expected, err := source.GetObjectMetadata(ctx, name)
+		require.NoError(t, err)
+		actual, err := destination.GetObjectMetadata(ctx, name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, readObject(t, source, name), readObject(t, destination, name))
+	}
+	_, err = destination.GetObjectMetadata(ctx, "other/x")
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound))
+
+	// A second migration copies nothing.
+	report, err = Migrate(ctx, opts)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 0, report.Copied)
+	assert.Equal(t, 21, report.Existed)
+	assert.Equal(t, 21, report.Verified)
+}
+
+func Test_Migrate_Resume(t *testing.T) {
+	ctx := context.Background()
+	source := newSource(t)
+	destination := newStorage(t)
+	// Simulate an interrupted migration.
+	require.NoError(t, destination.CreateObjectExclusively(ctx, "zip/example.com/m@v1.0.0", nil,
+		bytes.NewReader([]byte("zip example.com/m@v1.0.0"))))
+	// Objects that differ are reported.
+	require.NoError(t, destination.CreateObjectExclusively(ctx, "zip/example.com/m@v1.1.0", nil, bytes.NewReader([]byte("x"))))
+	require.NoError(t, destination.CreateObjectExclusively(ctx, "gomod/example.com/m@v1.1.0", nil,
+		bytes.NewReader([]byte("example.com/m@v1.1.0"))))
+	report, err := Migrate(ctx, Options{
+		Destination:  destination,
+		NamePrefixes: namePrefixes,
+		Parallelism:  2,
+		ScratchDir:   t.TempDir(),
+		Source:       source,
+		Verify:       true,
+	})
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 18, report.Copied)
+	assert.Equal(t, 3, report.Existed)
+	assert.Equal(t, 19, report.Verified)
+	if assert.Len(t, report.Mismatched, 2) {
+		assert.Equal(t, "gomod/example.com/m@v1.1.0", report.Mismatched[0].Name)
+		assert.Contains(t, report.Mismatched[0].Err.Error(), "metadata")
+		assert.Equal(t, "zip/example.com/m@v1.1.0", report.Mismatched[1].Name)
+		assert.Contains(t, report.Mismatched[1].Err.Error(), "data")
+	}
+	assert.Empty(t, report.Failed)
+
+	var buf bytes.Buffer
+	_, err = report.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "mismatched: 2\n  - gomod/example.com/m@v1.1.0: ")
+}
+
+func readObject(t *testing.T, s storage.Storage, name string) []byte {
+	data, err := s.GetObject(context.Background(), name)
+	require.NoError(t, err)
+	defer data.Close()
+	dataBytes, err := io.ReadAll(data)
+	require.NoError(t, err)
+	return dataBytes
+}
@@ -0,0 +1,462 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	log "github.com/sirupsen/logrus"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/util"
+)
+
+const headerNameMetadataPrefix = "X-Amz-Meta-"
+
+type StorageOptions struct {
+	Bucket      string
+	Credentials *Credentials
+	// Endpoint is the base URL of the S3 API, for example https://s3.eu-west-1.amazonaws.com or the URL of an S3-compatible
+	// service. Defaults to https://s3.<Region>.amazonaws.com.
+	Endpoint   *url.URL
+	HTTPClient *http.Client
+	Region     string
+	// UsePathStyle configures whether to address the bucket as part of the path (i.e. <Endpoint>/<Bucket>/<key>) instead of
+	// as part of the host (i.e. <Bucket>.<Endpoint host>/<key>). Path-style addressing is typically needed for S3-compatible
+	// services.
+	UsePathStyle bool
+}
+
+// Storage is an implementation of "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage backed by Amazon S3
+// or an S3-compatible service that supports conditional writes (PutObject with If-None-Match: *).
+type Storage struct {
+	bucketURL   string
+	credentials Credentials
+	httpClient  *http.Client
+	region      string
+	now         func() time.Time
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.ObjectURLSigner = (*Storage)(nil)
+
+func NewStorage(opts StorageOptions) (*Storage, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("opts.Bucket must not be empty")
+	}
+	if opts.Credentials == nil {
+		return nil, fmt.Errorf("opts.Credentials must not be nil")
+	}
+	if opts.Credentials.AccessKeyID == "" || opts.Credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("opts.Credentials.AccessKeyID and opts.Credentials.SecretAccessKey must not be empty")
+	}
+	if opts.HTTPClient == nil {
+		return nil, fmt.Errorf("opts.HTTPClient must not be nil")
+	}
+	if opts.Region == "" {
+		return nil, fmt.Errorf("opts.Region must not be empty")
+	}
+	endpoint := opts.Endpoint
+	if endpoint == nil {
+		endpoint = &url.URL{
+			Scheme: "https",
+			Host:   "s3." + opts.Region + ".amazonaws.com",
+		}
+	} else if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf(`opts.Endpoint must have scheme "http" or "https"`)
+	} else if endpoint.Host == "" {
+		return nil, fmt.Errorf("opts.Endpoint must have a non-empty host")
+	}
+	basePath := strings.TrimSuffix(endpoint.EscapedPath(), "/")
+	var bucketURL string
+	if opts.UsePathStyle {
+		bucketURL = endpoint.Scheme + "://" + endpoint.Host + basePath + "/" + uriEncode(opts.Bucket, true)
+	} else {
+		bucketURL = endpoint.Scheme + "://" + opts.Bucket + "." + endpoint.Host + basePath
+	}
+	s := &Storage{
+		bucketURL:   bucketURL,
+		credentials: *opts.Credentials,
+		httpClient:  opts.HTTPClient,
+		region:      opts.Region,
+		now:         time.Now,
+	}
+	return s, nil
+}
+
+func (s *Storage) CreateObjectExclusively(ctx context.Context, name string, metadata storage.ObjectMetadata,
+	data io.ReadSeeker) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	_, err := data.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	dataSHA256H := sha256.New()
+	dataLength, err := io.Copy(dataSHA256H, data)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("If-None-Match", "*")
+	for key, value := range metadata {
+		header.Set(headerNameMetadataPrefix+key, value)
+	}
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodPut, s.objectURL(name), header, data, dataLength,
+		hex.EncodeToString(dataSHA256H.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed:
+		return internalErrors.NewErrorf(
+			internalErrors.PreconditionFailed,
+			"got %d-response to %s %s: %s",
+			resp.StatusCode,
+			resp.Request.Method,
+			resp.Request.URL.String(),
+			string(respBodyBytes))
+	}
+	return unexpectedResponseError(resp, respBodyBytes)
+}
+
+func (s *Storage) DeleteObject(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	// S3 responds to DeleteObject with 204 regardless of whether the object exists, so do a HEAD request first to implement
+	// the NotFound contract.
+	if _, err := s.GetObjectMetadata(ctx, name); err != nil {
+		return err
+	}
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodDelete, s.objectURL(name), nil, nil, 0, emptyPayloadSHA256Hex)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return unexpectedResponseError(resp, respBodyBytes)
+}
+
+func (s *Storage) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	resp, err := s.doRequestStreamBody(ctx, http.MethodGet, s.objectURL(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *Storage) GetObjectRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	if name == "" {
+		return nil, 0, fmt.Errorf("name must not be empty")
+	}
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("offset must be non-negative")
+	}
+	if length == 0 {
+		size, err := s.getObjectSize(ctx, name)
+		if err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(bytes.NewReader(nil)), size, nil
+	}
+	header := http.Header{}
+	header.Set("Range", util.FormatRangeHeader(offset, length))
+	resp, err := s.doRequestStreamBody(ctx, http.MethodGet, s.objectURL(name), header)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The Range header was ignored.
+		data, err := util.SliceReadCloser(resp.Body, offset, length)
+		if err != nil {
+			return nil, 0, err
+		}
+		return data, resp.ContentLength, nil
+	case http.StatusPartialContent:
+		size, err := util.ParseContentRangeSize(resp.Header.Get("Content-Range"))
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, 0, fmt.Errorf("error parsing %d-response to %s %s: %w", resp.StatusCode, resp.Request.Method,
+				resp.Request.URL.String(), err)
+		}
+		return resp.Body, size, nil
+	}
+	// offset is greater than or equal to the size of the object's data.
+	size, err := s.getObjectSize(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.NopCloser(bytes.NewReader(nil)), size, nil
+}
+
+func (s *Storage) GetObjectMetadata(ctx context.Context, name string) (storage.ObjectMetadata, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	resp, err := s.headObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	metadata := storage.ObjectMetadata{}
+	for name, values := range resp.Header {
+		if len(values) > 0 && len(name) > len(headerNameMetadataPrefix) &&
+			strings.EqualFold(name[:len(headerNameMetadataPrefix)], headerNameMetadataPrefix) {
+			metadata[strings.ToLower(name[len(headerNameMetadataPrefix):])] = values[0]
+		}
+	}
+	return metadata, nil
+}
+
+func (s *Storage) ListObjects(ctx context.Context, opts storage.ObjectListOptions) (*storage.ObjectList, error) {
+	if opts.MaxResults < 0 {
+		return nil, fmt.Errorf("opts.MaxResults must be non-negative")
+	}
+	urlQuery := url.Values{}
+	urlQuery.Set("list-type", "2")
+	if opts.MaxResults > 0 {
+		urlQuery.Set("max-keys", strconv.FormatInt(int64(opts.MaxResults), 10))
+	}
+	if opts.NamePrefix != "" {
+		urlQuery.Set("prefix", opts.NamePrefix)
+	}
+	if opts.PageToken != "" {
+		urlQuery.Set("continuation-token", opts.PageToken)
+	}
+	url := s.bucketURL + "/?" + strings.ReplaceAll(urlQuery.Encode(), "+", "%20")
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodGet, url, nil, nil, 0, emptyPayloadSHA256Hex)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedResponseError(resp, respBodyBytes)
+	}
+	respBody := &struct {
+		Contents []struct {
+			Key string `xml:"Key"`
+		} `xml:"Contents"`
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+	}{}
+	if err := xml.Unmarshal(respBodyBytes, respBody); err != nil {
+		return nil, fmt.Errorf("error unmarshalling body of %d-response to %s %s: %w",
+			resp.StatusCode,
+			resp.Request.Method,
+			resp.Request.URL.String(),
+			err)
+	}
+	objList := &storage.ObjectList{}
+	for _, item := range respBody.Contents {
+		objList.Names = append(objList.Names, item.Key)
+	}
+	if respBody.IsTruncated {
+		if respBody.NextContinuationToken == "" {
+			return nil, fmt.Errorf("body of %d-response to %s %s unexpectedly has IsTruncated = true but no NextContinuationToken",
+				resp.StatusCode,
+				resp.Request.Method,
+				resp.Request.URL.String())
+		}
+		objList.NextPageToken = respBody.NextContinuationToken
+	}
+	return objList, nil
+}
+
+// doRequest does a signed request and reads the response body. Intermittent errors are retried. If the returned error is nil
+// then the response's status code is not a status code indicating an intermittent error.
+func (s *Storage) doRequest(ctx context.Context, method, url string, header http.Header, body io.ReadSeeker, bodyLength int64,
+	bodySHA256Hex string) (*http.Response, []byte, error) {
+	// Inspired by https://github.com/googleapis/google-cloud-go/blob/67b19f0bd698c1df21addff89060b4356816a4d3/storage/invoke.go#L26
+	var backoff gax.Backoff
+	for {
+		var reqBody io.Reader
+		if body != nil {
+			if _, err := body.Seek(0, io.SeekStart); err != nil {
+				return nil, nil, err
+			}
+			reqBody = body
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating request %s %s: %w", method, url, err)
+		}
+		if body != nil {
+			// Ensure the request body is not closed by the transport, so that it can be retried.
+			req.Body = io.NopCloser(reqBody)
+			req.ContentLength = bodyLength
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		signRequest(req, &s.credentials, s.region, bodySHA256Hex, s.now())
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			if !shouldRetryDoRequest(err) {
+				return nil, nil, fmt.Errorf("error doing request %s %s: %w", method, url, err)
+			}
+			log.Errorf("retrying because got intermittent error doing request %s %s: %v", method, url, err)
+		} else {
+			respBodyBytes, err := io.ReadAll(resp.Body)
+			err2 := resp.Body.Close()
+			if err2 != nil {
+				log.Errorf("error closing body of %d-response to %s %s: %v", resp.StatusCode, method, url, err2)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading body of %d-response to %s %s: %w", resp.StatusCode, method, url, err)
+			}
+			// S3 responds with 409 ConditionalRequestConflict if a conflicting operation occurs during a conditional write,
+			// in which case AWS recommends to retry.
+			if !shouldRetryStatusCode(resp.StatusCode) &&
+				!(resp.StatusCode == http.StatusConflict && method == http.MethodPut) {
+				return resp, respBodyBytes, nil
+			}
+			log.Errorf("retrying because got intermittent %d-response to %s %s: %s", resp.StatusCode, method, url, string(respBodyBytes))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(backoff.Pause()):
+		}
+	}
+}
+
+// doRequestStreamBody does a signed request without a request body. Intermittent errors are retried. If the returned error is nil
+// then the response has status 200 or 206 (or 416 if header has a Range header) and the caller must close the response body.
+func (s *Storage) doRequestStreamBody(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	var backoff gax.Backoff
+	for {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request %s %s: %w", method, url, err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		signRequest(req, &s.credentials, s.region, emptyPayloadSHA256Hex, s.now())
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			if !shouldRetryDoRequest(err) {
+				return nil, fmt.Errorf("error doing request %s %s: %w", method, url, err)
+			}
+			log.Errorf("retrying because got intermittent error doing request %s %s: %v", method, url, err)
+		} else {
+			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+				return resp, nil
+			}
+			respBodyBytes, err := io.ReadAll(resp.Body)
+			if err != nil {
+				log.Errorf("error reading body of %d-response to %s %s: %v", resp.StatusCode, method, url, err)
+			}
+			err = resp.Body.Close()
+			if err != nil {
+				log.Errorf("error closing body of %d-response to %s %s: %v", resp.StatusCode, method, url, err)
+			}
+			if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && header.Get("Range") != "" {
+				resp.Body = io.NopCloser(bytes.NewReader(respBodyBytes))
+				return resp, nil
+			}
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, notFoundError(resp)
+			}
+			if !shouldRetryStatusCode(resp.StatusCode) {
+				return nil, unexpectedResponseError(resp, respBodyBytes)
+			}
+			log.Errorf("retrying because got intermittent %d-response to %s %s: %s", resp.StatusCode, method, url, string(respBodyBytes))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff.Pause()):
+		}
+	}
+}
+
+func (s *Storage) getObjectSize(ctx context.Context, name string) (int64, error) {
+	resp, err := s.headObject(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("%d-response to %s %s has no valid Content-Length header", resp.StatusCode, resp.Request.Method,
+			resp.Request.URL.String())
+	}
+	return resp.ContentLength, nil
+}
+
+// headObject does a HEAD request for the object named name. If the returned error is nil then the response has status 200.
+func (s *Storage) headObject(ctx context.Context, name string) (*http.Response, error) {
+	resp, respBodyBytes, err := s.doRequest(ctx, http.MethodHead, s.objectURL(name), nil, nil, 0, emptyPayloadSHA256Hex)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, notFoundError(resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedResponseError(resp, respBodyBytes)
+	}
+	return resp, nil
+}
+
+// maxPresignExpires is the maximum expiry of a presigned URL supported by S3.
+const maxPresignExpires = 7 * 24 * time.Hour
+
+// SignObjectURL returns a presigned URL. See "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".ObjectURLSigner.
+func (s *Storage) SignObjectURL(ctx context.Context, name string, expires time.Duration) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("name must not be empty")
+	}
+	if expires < time.Second || expires > maxPresignExpires {
+		return "", fmt.Errorf("expires must be at least 1s and at most %s", maxPresignExpires)
+	}
+	u, err := url.Parse(s.objectURL(name))
+	if err != nil {
+		return "", err
+	}
+	return presignURL(u, &s.credentials, s.region, expires, s.now()), nil
+}
+
+func (s *Storage) objectURL(name string) string {
+	return s.bucketURL + "/" + uriEncode(name, false)
+}
+
+func notFoundError(resp *http.Response) error {
+	return internalErrors.NewErrorf(internalErrors.NotFound, "got %d-response to %s %s", resp.StatusCode, resp.Request.Method,
+		resp.Request.URL.String())
+}
+
+func shouldRetryDoRequest(err error) bool {
+	if strings.Contains(err.Error(), "REFUSED_STREAM") {
+		return true
+	} else if t, ok := err.(interface{ Temporary() bool }); ok && t.Temporary() {
+		return true
+	}
+	return false
+}
+
+func shouldRetryStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || (500 <= statusCode && statusCode <= 599)
+}
+
+func unexpectedResponseError(resp *http.Response, respBodyBytes []byte) error {
+	return fmt.Errorf("got unexpected %d-response to %s %s: %s", resp.StatusCode, resp.Request.Method, resp.Request.URL.String(),
+		string(respBodyBytes))
+}
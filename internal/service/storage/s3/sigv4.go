@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerNameAuthorization   = "Authorization"
+	headerNameContentSHA256   = "X-Amz-Content-Sha256"
+	headerNameDate            = "X-Amz-Date"
+	headerNameSecurityToken   = "X-Amz-Security-Token"
+	signatureAlgorithm        = "AWS4-HMAC-SHA256"
+	signatureService          = "s3"
+	signatureTimeFormat       = "20060102T150405Z"
+	signatureDateFormat       = "20060102"
+	signatureScopeTerminator  = "aws4_request"
+	unsignedPayload           = "UNSIGNED-PAYLOAD"
+	emptyPayloadSHA256Hex     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unreservedURIEncodedChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_.~"
+)
+
+// Credentials are AWS credentials used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is optional and only needed for temporary credentials.
+	SessionToken string
+}
+
+// signRequest signs req using AWS Signature Version 4 (see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html).
+// All headers of req and the Host header are signed. payloadSHA256Hex is the hex-encoded SHA-256 hash of the request body.
+func signRequest(req *http.Request, credentials *Credentials, region, payloadSHA256Hex string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(signatureTimeFormat)
+	req.Header.Set(headerNameDate, amzDate)
+	req.Header.Set(headerNameContentSHA256, payloadSHA256Hex)
+	if credentials.SessionToken != "" {
+		req.Header.Set(headerNameSecurityToken, credentials.SessionToken)
+	}
+	req.Header.Del(headerNameAuthorization)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headerValues := map[string]string{
+		"host": host,
+	}
+	for name, values := range req.Header {
+		trimmedValues := make([]string, len(values))
+		for i, value := range values {
+			trimmedValues[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headerValues[strings.ToLower(name)] = strings.Join(trimmedValues, ",")
+	}
+	headerNames := make([]string, 0, len(headerValues))
+	for name := range headerValues {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteByte(':')
+		canonicalHeaders.WriteString(headerValues[name])
+		canonicalHeaders.WriteByte('\n')
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQueryString(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadSHA256Hex,
+	}, "\n")
+	signature := computeSignature(credentials, region, canonicalRequest, now)
+	req.Header.Set(headerNameAuthorization, fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signatureAlgorithm, credentials.AccessKeyID, signatureScope(region, now), signedHeaders, signature))
+}
+
+// presignURL returns u with query parameters that authenticate a GET request for duration expires using AWS Signature
+// Version 4 (see https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html). Only the Host header is
+// signed. u must not have query parameters.
+func presignURL(u *url.URL, credentials *Credentials, region string, expires time.Duration, now time.Time) string {
+	now = now.UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", signatureAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+signatureScope(region, now))
+	query.Set("X-Amz-Date", now.Format(signatureTimeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	query.Set("X-Amz-SignedHeaders", "host")
+	canonicalURI := u.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalURI,
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	signature := computeSignature(credentials, region, canonicalRequest, now)
+	return u.Scheme + "://" + u.Host + canonicalURI + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// computeSignature computes the signature of canonicalRequest. now must be in UTC.
+func computeSignature(credentials *Credentials, region, canonicalRequest string, now time.Time) string {
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	date := now.Format(signatureDateFormat)
+	stringToSign := strings.Join([]string{
+		signatureAlgorithm,
+		now.Format(signatureTimeFormat),
+		signatureScope(region, now),
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, signatureService)
+	key = hmacSHA256(key, signatureScopeTerminator)
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func signatureScope(region string, now time.Time) string {
+	return strings.Join([]string{now.Format(signatureDateFormat), region, signatureService, signatureScopeTerminator}, "/")
+}
+
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(uriEncode(key, true))
+			sb.WriteByte('=')
+			sb.WriteString(uriEncode(value, true))
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode encodes s as specified by AWS Signature Version 4. If encodeSlash is false then "/" is not encoded.
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if strings.IndexByte(unreservedURIEncodedChars, c) >= 0 || (c == '/' && !encodeSlash) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0xF])
+		}
+	}
+	return sb.String()
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"io"
+	"time"
 )
 
 //
@@ -23,6 +24,13 @@ type Storage interface {
 	// is true if no object named name exists.
 	GetObject(ctx context.Context, name string) (io.ReadCloser, error)
 
+	// GetObjectRange returns at most length bytes of the data of an object as a reader, starting at byte offset, and the
+	// size of the object's data. If length is negative then the reader reads until the end of the data.
+	// The reader is empty if offset is greater than or equal to the size of the object's data.
+	// Returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound)
+	// is true if no object named name exists.
+	GetObjectRange(ctx context.Context, name string, offset, length int64) (data io.ReadCloser, size int64, err error)
+
 	// GetObjectMetadata returns the metadata of an object as a reader.
 	// Returns an error e such that "github.com/go-mod-proxy/go-mod-proxy/internal/errors".ErrorIsCode(e, NotFound)
 	// is true if no object named name exists.
@@ -33,6 +41,36 @@ type Storage interface {
 	ListObjects(ctx context.Context, opts ObjectListOptions) (*ObjectList, error)
 }
 
+// ObjectURLSigner is an optional interface that a Storage can implement to let clients read objects directly from the
+// underlying storage service, instead of through this process.
+type ObjectURLSigner interface {
+	// SignObjectURL returns a URL with which the data of the object named name can be read by a GET request (without
+	// further credentials) until expires has elapsed. SignObjectURL does not check whether an object named name exists.
+	SignObjectURL(ctx context.Context, name string, expires time.Duration) (string, error)
+}
+
+// Wrapper is implemented by a Storage that wraps another Storage (i.e. a cache).
+type Wrapper interface {
+	// Unwrap returns the wrapped Storage.
+	Unwrap() Storage
+}
+
+// AsObjectURLSigner returns s as an ObjectURLSigner if s implements ObjectURLSigner, or the first Storage in the chain
+// of wrapped Storages of s (see Wrapper) that does. AsObjectURLSigner returns false if there is no such Storage.
+func AsObjectURLSigner(s Storage) (ObjectURLSigner, bool) {
+	for s != nil {
+		if signer, ok := s.(ObjectURLSigner); ok {
+			return signer, true
+		}
+		wrapper, ok := s.(Wrapper)
+		if !ok {
+			break
+		}
+		s = wrapper.Unwrap()
+	}
+	return nil, false
+}
+
 type ObjectList struct {
 	Names         []string
 	NextPageToken string
@@ -0,0 +1,150 @@
+// Package storageconfig creates a "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage from
+// configuration.
+package storageconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+
+	"github.com/go-mod-proxy/go-mod-proxy/internal/config"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/azureblob"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/cache"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/filesystem"
+	storagegcs "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/gcs"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage/s3"
+)
+
+type StorageOptions struct {
+	Config *config.Storage
+	// GoogleHTTPClient is the HTTP client used to access Google Cloud Storage. GoogleHTTPClient must not be nil if
+	// Config.GCS is not nil.
+	GoogleHTTPClient *http.Client
+	// HTTPClient is the HTTP client used to access the other storage services.
+	HTTPClient *http.Client
+}
+
+// NewStorage creates the storage configured by opts.Config, wrapped in a cache if opts.Config.Cache is not nil.
+func NewStorage(ctx context.Context, opts StorageOptions) (storage.Storage, error) {
+	if opts.Config == nil {
+		return nil, fmt.Errorf("opts.Config must not be nil")
+	}
+	if opts.HTTPClient == nil {
+		return nil, fmt.Errorf("opts.HTTPClient must not be nil")
+	}
+	cfg := opts.Config
+	var s storage.Storage
+	var err error
+	if cfg.GCS != nil {
+		if opts.GoogleHTTPClient == nil {
+			return nil, fmt.Errorf("opts.GoogleHTTPClient must not be nil if opts.Config.GCS is not nil")
+		}
+		gcsClient, err := gcs.NewClient(ctx, option.WithHTTPClient(opts.GoogleHTTPClient))
+		if err != nil {
+			return nil, err
+		}
+		s, err = storagegcs.NewStorage(storagegcs.StorageOptions{
+			Bucket:     cfg.GCS.Bucket,
+			GCSClient:  gcsClient,
+			HTTPClient: opts.GoogleHTTPClient,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.AzureBlob != nil {
+		var sasToken string
+		if cfg.AzureBlob.SASToken != nil {
+			sasToken = string(cfg.AzureBlob.SASToken.Plaintext)
+		}
+		s, err = azureblob.NewStorage(azureblob.StorageOptions{
+			Account:    cfg.AzureBlob.Account,
+			Container:  cfg.AzureBlob.Container,
+			Endpoint:   cfg.AzureBlob.EndpointParsed,
+			HTTPClient: opts.HTTPClient,
+			SASToken:   sasToken,
+			SharedKey:  cfg.AzureBlob.SharedKeyDecoded,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.Filesystem != nil {
+		s, err = filesystem.NewStorage(filesystem.StorageOptions{
+			Dir: cfg.Filesystem.Dir,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.S3 != nil {
+		credentials, err := getS3Credentials(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		s, err = s3.NewStorage(s3.StorageOptions{
+			Bucket:       cfg.S3.Bucket,
+			Credentials:  credentials,
+			Endpoint:     cfg.S3.EndpointParsed,
+			HTTPClient:   opts.HTTPClient,
+			Region:       cfg.S3.Region,
+			UsePathStyle: cfg.S3.UsePathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("opts.Config is unexpectedly invalid")
+	}
+	if cfg.Cache != nil {
+		s, err = newStorageCache(cfg.Cache, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func getS3Credentials(s3Cfg *config.S3Storage) (*s3.Credentials, error) {
+	if s3Cfg.AccessKeyID != nil {
+		credentials := &s3.Credentials{
+			AccessKeyID:     string(s3Cfg.AccessKeyID.Plaintext),
+			SecretAccessKey: string(s3Cfg.SecretAccessKey.Plaintext),
+		}
+		if s3Cfg.SessionToken != nil {
+			credentials.SessionToken = string(s3Cfg.SessionToken.Plaintext)
+		}
+		return credentials, nil
+	}
+	credentials := &s3.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf(".storage.s3.accessKeyId is not set so environment variables AWS_ACCESS_KEY_ID and " +
+			"AWS_SECRET_ACCESS_KEY must be set (to non-empty values)")
+	}
+	return credentials, nil
+}
+
+func newStorageCache(cfg *config.StorageCache, s storage.Storage) (storage.Storage, error) {
+	// The name prefixes are those of the objects stored by the gocmd service. "concat/" objects are not cached because
+	// they are deleted.
+	opts := cache.StorageOptions{
+		DiskNamePrefixes:   []string{"zip/"},
+		MemoryNamePrefixes: []string{"gomod/"},
+		Storage:            s,
+	}
+	if cfg.Disk != nil {
+		opts.DiskDir = cfg.Disk.Dir
+		opts.DiskMaxBytes = cfg.Disk.MaxBytes
+	}
+	if cfg.Memory != nil {
+		opts.MemoryMaxBytes = cfg.Memory.MaxBytes
+		opts.MemoryMaxObjectBytes = cfg.Memory.MaxObjectBytes
+	}
+	return cache.NewStorage(opts)
+}
@@ -0,0 +1,326 @@
+// Package storagetest implements a conformance test suite for implementations of
+// "github.com/go-mod-proxy/go-mod-proxy/internal/service/storage".Storage.
+//
+// The suite checks the parts of the contract that "github.com/go-mod-proxy/go-mod-proxy/internal/service/gomodule/gocmd".Service
+// relies on: atomic exclusive creation (also under concurrency), error codes, metadata round-trips and listing.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internalErrors "github.com/go-mod-proxy/go-mod-proxy/internal/errors"
+	"github.com/go-mod-proxy/go-mod-proxy/internal/service/storage"
+)
+
+// NewStorageFunc returns a new, empty storage.Storage. It is called once per test case, so implementations can use
+// t.TempDir, t.Cleanup etc.
+type NewStorageFunc func(t *testing.T) storage.Storage
+
+// Run runs the conformance test suite as subtests of t.
+func Run(t *testing.T, newStorage NewStorageFunc) {
+	for _, c := range []struct {
+		Name string
+		Test func(t *testing.T, s storage.Storage)
+	}{
+		{"CreateObjectExclusively", testCreateObjectExclusively},
+		{"CreateObjectExclusively_AfterDelete", testCreateObjectExclusivelyAfterDelete},
+		{"CreateObjectExclusively_Exists", testCreateObjectExclusivelyExists},
+		{"CreateObjectExclusively_Race", testCreateObjectExclusivelyRace},
+		{"Data", testData},
+		{"GetObjectRange", testGetObjectRange},
+		{"ListObjects_EmptyPrefix", testListObjectsEmptyPrefix},
+		{"ListObjects_NoMatches", testListObjectsNoMatches},
+		{"ListObjects_Pagination", testListObjectsPagination},
+		{"ListObjects_Prefix", testListObjectsPrefix},
+		{"Metadata", testMetadata},
+		{"NotFound", testNotFound},
+	} {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			c.Test(t, newStorage(t))
+		})
+	}
+}
+
+func testCreateObjectExclusively(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := "gomod/github.com/Org/repo@v1.0.0+incompatible"
+	metadata := storage.ObjectMetadata{"gomod-commit-time": "2020-01-01T00:00:00Z"}
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, metadata, bytes.NewReader([]byte("module x\n"))))
+	assertObject(t, s, name, metadata, []byte("module x\n"))
+
+	require.NoError(t, s.DeleteObject(ctx, name))
+	assertNotFound(t, s, name)
+}
+
+func testCreateObjectExclusivelyAfterDelete(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := "concat/example.com/m@v1.0.0"
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, nil, bytes.NewReader([]byte("a"))))
+	require.NoError(t, s.DeleteObject(ctx, name))
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, nil, bytes.NewReader([]byte("b"))))
+	assertObject(t, s, name, nil, []byte("b"))
+}
+
+func testCreateObjectExclusivelyExists(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := "concat/example.com/m@v1.0.0"
+	metadata := storage.ObjectMetadata{"a": "1"}
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, metadata, bytes.NewReader([]byte("first"))))
+	err := s.CreateObjectExclusively(ctx, name, storage.ObjectMetadata{"a": "2"}, bytes.NewReader([]byte("second")))
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+	// The failed creation must not have modified the existing object.
+	assertObject(t, s, name, metadata, []byte("first"))
+}
+
+func testCreateObjectExclusivelyRace(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const n = 16
+	name := "concat/example.com/m@v1.0.0"
+	start := make(chan struct{})
+	var waitGroup sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			<-start
+			errs[i] = s.CreateObjectExclusively(ctx, name, storage.ObjectMetadata{"i": fmt.Sprint(i)},
+				bytes.NewReader([]byte(fmt.Sprint(i))))
+		}(i)
+	}
+	close(start)
+	waitGroup.Wait()
+	winner := -1
+	for i, err := range errs {
+		if err == nil {
+			if winner >= 0 {
+				t.Errorf("both creation %d and creation %d of object %#v succeeded", winner, i, name)
+			}
+			winner = i
+		} else {
+			assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.PreconditionFailed), "%v", err)
+		}
+	}
+	if assert.GreaterOrEqual(t, winner, 0, "no creation succeeded") {
+		// The object must be the one written by the only successful creation.
+		assertObject(t, s, name, storage.ObjectMetadata{"i": fmt.Sprint(winner)}, []byte(fmt.Sprint(winner)))
+	}
+}
+
+func testData(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	large := make([]byte, 1<<20)
+	_, _ = rand.New(rand.NewSource(1)).Read(large)
+	for name, data := range map[string][]byte{
+		"zip/example.com/empty@v1.0.0": {},
+		"zip/example.com/large@v1.0.0": large,
+	} {
+		require.NoError(t, s.CreateObjectExclusively(ctx, name, nil, bytes.NewReader(data)))
+		assertObject(t, s, name, nil, data)
+	}
+}
+
+func testGetObjectRange(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := "zip/example.com/m@v1.0.0"
+	require.NoError(t, s.CreateObjectExclusively(ctx, name, nil, bytes.NewReader([]byte("0123456789"))))
+	emptyName := "zip/example.com/empty@v1.0.0"
+	require.NoError(t, s.CreateObjectExclusively(ctx, emptyName, nil, bytes.NewReader(nil)))
+	for _, c := range []struct {
+		Name     string
+		Offset   int64
+		Length   int64
+		Expected string
+		Size     int64
+	}{
+		{name, 0, -1, "0123456789", 10},
+		{name, 0, 10, "0123456789", 10},
+		{name, 0, 100, "0123456789", 10},
+		{name, 3, 4, "3456", 10},
+		{name, 3, -1, "3456789", 10},
+		{name, 9, 1, "9", 10},
+		{name, 1, math.MaxInt64, "123456789", 10},
+		{name, 5, 0, "", 10},
+		{name, 10, -1, "", 10},
+		{name, 10, 1, "", 10},
+		{name, 100, 5, "", 10},
+		{emptyName, 0, -1, "", 0},
+		{emptyName, 0, 1, "", 0},
+	} {
+		t.Run(fmt.Sprintf("%s,%d,%d", c.Name, c.Offset, c.Length), func(t *testing.T) {
+			data, size, err := s.GetObjectRange(ctx, c.Name, c.Offset, c.Length)
+			require.NoError(t, err)
+			dataBytes, err := io.ReadAll(data)
+			assert.NoError(t, err)
+			assert.NoError(t, data.Close())
+			assert.Equal(t, c.Expected, string(dataBytes))
+			assert.Equal(t, c.Size, size)
+		})
+	}
+	_, _, err := s.GetObjectRange(ctx, "zip/example.com/m@v1.1.0", 0, -1)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "%v", err)
+}
+
+func testListObjectsEmptyPrefix(t *testing.T, s storage.Storage) {
+	names := []string{"concat/example.com/m@v1.0.0", "gomod/example.com/m@v1.0.0", "zip/example.com/m@v1.0.0"}
+	createObjects(t, s, names)
+	assert.Equal(t, names, listAll(t, s, storage.ObjectListOptions{}))
+}
+
+func testListObjectsNoMatches(t *testing.T, s storage.Storage) {
+	createObjects(t, s, []string{"gomod/example.com/m@v1.0.0"})
+	objList, err := s.ListObjects(context.Background(), storage.ObjectListOptions{NamePrefix: "zip/"})
+	require.NoError(t, err)
+	assert.Empty(t, objList.Names)
+	assert.Empty(t, objList.NextPageToken)
+}
+
+func testListObjectsPagination(t *testing.T, s storage.Storage) {
+	var names []string
+	for i := 0; i < 7; i++ {
+		names = append(names, fmt.Sprintf("gomod/example.com/m@v1.%d.0", i))
+	}
+	createObjects(t, s, names)
+	createObjects(t, s, []string{"zip/example.com/m@v1.0.0"})
+	for _, maxResults := range []int{0, 1, 2, 3, 7, 8, 100} {
+		t.Run(fmt.Sprintf("MaxResults=%d", maxResults), func(t *testing.T) {
+			listed := listAll(t, s, storage.ObjectListOptions{NamePrefix: "gomod/", MaxResults: maxResults})
+			assert.Equal(t, names, listed)
+		})
+	}
+	err := s.DeleteObject(context.Background(), names[0])
+	require.NoError(t, err)
+	assert.Equal(t, names[1:], listAll(t, s, storage.ObjectListOptions{NamePrefix: "gomod/", MaxResults: 2}))
+}
+
+func testListObjectsPrefix(t *testing.T, s storage.Storage) {
+	createObjects(t, s, []string{
+		"gomod/example.com/m/sub@v1.0.0",
+		"gomod/example.com/m@v1.0.0",
+		"gomod/example.com/m@v1.1.0",
+		"gomod/example.com/mm@v1.0.0",
+		"zip/example.com/m@v1.0.0",
+	})
+	for _, c := range []struct {
+		NamePrefix string
+		Expected   []string
+	}{
+		{"gomod/example.com/m@", []string{"gomod/example.com/m@v1.0.0", "gomod/example.com/m@v1.1.0"}},
+		{"gomod/example.com/m/", []string{"gomod/example.com/m/sub@v1.0.0"}},
+		// Prefixes need not end at a "/".
+		{"gomod/example.com/m", []string{
+			"gomod/example.com/m/sub@v1.0.0",
+			"gomod/example.com/m@v1.0.0",
+			"gomod/example.com/m@v1.1.0",
+			"gomod/example.com/mm@v1.0.0",
+		}},
+		{"gomod/example.com/m@v1.1.0", []string{"gomod/example.com/m@v1.1.0"}},
+		{"zip/", []string{"zip/example.com/m@v1.0.0"}},
+	} {
+		t.Run(c.NamePrefix, func(t *testing.T) {
+			assert.Equal(t, c.Expected, listAll(t, s, storage.ObjectListOptions{NamePrefix: c.NamePrefix, MaxResults: 2}))
+		})
+	}
+}
+
+func testMetadata(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	for name, metadata := range map[string]storage.ObjectMetadata{
+		"gomod/example.com/a@v1.0.0": nil,
+		"gomod/example.com/b@v1.0.0": {},
+		"gomod/example.com/c@v1.0.0": {
+			"gomod-commit-time": "2020-01-01T00:00:00Z",
+			"h1":                "h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		},
+	} {
+		require.NoError(t, s.CreateObjectExclusively(ctx, name, metadata, bytes.NewReader(nil)))
+		assertObject(t, s, name, metadata, nil)
+	}
+}
+
+func testNotFound(t *testing.T, s storage.Storage) {
+	createObjects(t, s, []string{"gomod/example.com/m@v1.0.0"})
+	assertNotFound(t, s, "gomod/example.com/m@v1.1.0")
+	assertNotFound(t, s, "gomod/example.com/m")
+}
+
+func assertNotFound(t *testing.T, s storage.Storage, name string) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := s.GetObject(ctx, name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "GetObject: %v", err)
+	_, err = s.GetObjectMetadata(ctx, name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "GetObjectMetadata: %v", err)
+	err = s.DeleteObject(ctx, name)
+	assert.True(t, internalErrors.ErrorIsCode(err, internalErrors.NotFound), "DeleteObject: %v", err)
+}
+
+// assertObject asserts that the object named name has the given metadata and data. A nil metadata is equivalent to an
+// empty metadata.
+func assertObject(t *testing.T, s storage.Storage, name string, metadata storage.ObjectMetadata, data []byte) {
+	t.Helper()
+	ctx := context.Background()
+	readCloser, err := s.GetObject(ctx, name)
+	if assert.NoError(t, err) {
+		dataActual, err := io.ReadAll(readCloser)
+		assert.NoError(t, err)
+		assert.NoError(t, readCloser.Close())
+		assert.True(t, bytes.Equal(data, dataActual), "data of object %#v differs from the data it was created with", name)
+	}
+	metadataActual, err := s.GetObjectMetadata(ctx, name)
+	if assert.NoError(t, err) {
+		if len(metadata) == 0 {
+			assert.Empty(t, metadataActual)
+		} else {
+			assert.Equal(t, metadata, metadataActual)
+		}
+	}
+}
+
+func createObjects(t *testing.T, s storage.Storage, names []string) {
+	t.Helper()
+	for _, name := range names {
+		require.NoError(t, s.CreateObjectExclusively(context.Background(), name, nil, bytes.NewReader([]byte(name))))
+	}
+}
+
+// listAll lists all pages. Pages may have fewer names than opts.MaxResults even if there are more pages, so only
+// an empty NextPageToken ends the listing. Returns the sorted names and fails t if a name is listed more than once.
+func listAll(t *testing.T, s storage.Storage, opts storage.ObjectListOptions) []string {
+	t.Helper()
+	var names []string
+	seen := map[string]struct{}{}
+	for i := 0; ; i++ {
+		require.Less(t, i, 1000, "too many pages")
+		objList, err := s.ListObjects(context.Background(), opts)
+		require.NoError(t, err)
+		if opts.MaxResults > 0 {
+			assert.LessOrEqual(t, len(objList.Names), opts.MaxResults)
+		}
+		for _, name := range objList.Names {
+			if _, ok := seen[name]; ok {
+				t.Errorf("object %#v was listed more than once", name)
+			}
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+		if objList.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = objList.NextPageToken
+	}
+	sort.Strings(names)
+	return names
+}
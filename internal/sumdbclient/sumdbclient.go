@@ -0,0 +1,233 @@
+// Package sumdbclient verifies the hashes of module versions against a checksum database (see
+// https://go.googlesource.com/proposal/+/master/design/25530-sumdb.md) using "golang.org/x/mod/sumdb".Client.
+package sumdbclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+)
+
+const (
+	// maxCacheEntries bounds the number of tiles and lookup results cached in memory. Tiles are at most 8KiB (with the
+	// default tile height of 8).
+	maxCacheEntries = 4096
+	// readRemoteTimeout bounds the duration of requests to the checksum database, because
+	// "golang.org/x/mod/sumdb".ClientOps does not pass a context.Context.
+	readRemoteTimeout = time.Minute
+)
+
+type ClientOptions struct {
+	HTTPClient *http.Client
+	// Name is the name of the checksum database (i.e. sum.golang.org).
+	Name string
+	// ParentProxy is optional. If ParentProxy is not nil and the module proxy at ParentProxy proxies the checksum database
+	// (see https://go.googlesource.com/proposal/+/master/design/25530-sumdb.md#proxying-a-checksum-database) then the
+	// checksum database is accessed through the module proxy, like the Go toolchain does.
+	ParentProxy *url.URL
+	// PublicKey is the public key of the checksum database, such that Name+"+"+PublicKey is the verifier key (see
+	// "golang.org/x/mod/sumdb/note").
+	PublicKey string
+	URL       *url.URL
+}
+
+// Client verifies hashes of module versions against a checksum database. Client is safe for concurrent use.
+type Client struct {
+	name string
+	ops  *clientOps
+}
+
+// NewClient creates a new Client.
+func NewClient(opts ClientOptions) (*Client, error) {
+	if opts.HTTPClient == nil {
+		return nil, fmt.Errorf("opts.HTTPClient must not be nil")
+	}
+	if opts.Name == "" {
+		return nil, fmt.Errorf("opts.Name must not be empty")
+	}
+	if opts.PublicKey == "" {
+		return nil, fmt.Errorf("opts.PublicKey must not be empty")
+	}
+	if opts.URL == nil {
+		return nil, fmt.Errorf("opts.URL must not be nil")
+	}
+	ops := &clientOps{
+		cache:       map[string][]byte{},
+		httpClient:  opts.HTTPClient,
+		name:        opts.Name,
+		parentProxy: opts.ParentProxy,
+		url:         strings.TrimSuffix(opts.URL.String(), "/"),
+		verifierKey: opts.Name + "+" + opts.PublicKey,
+	}
+	return &Client{
+		name: opts.Name,
+		ops:  ops,
+	}, nil
+}
+
+// Verify returns an error if the checksum database does not have the h1: hash zipSum for the zip file of moduleVersion
+// or the h1: hash goModSum for the go.mod file of moduleVersion.
+func (c *Client) Verify(moduleVersion module.Version, zipSum, goModSum string) error {
+	// Both lookups share the record of moduleVersion.
+	client := c.newClient()
+	if err := c.verify(client, moduleVersion.Path, moduleVersion.Version, zipSum); err != nil {
+		return err
+	}
+	return c.verify(client, moduleVersion.Path, moduleVersion.Version+"/go.mod", goModSum)
+}
+
+// VerifyGoMod returns an error if the checksum database does not have the h1: hash goModSum for the go.mod file of
+// moduleVersion.
+func (c *Client) VerifyGoMod(moduleVersion module.Version, goModSum string) error {
+	return c.verify(c.newClient(), moduleVersion.Path, moduleVersion.Version+"/go.mod", goModSum)
+}
+
+// newClient returns a new "golang.org/x/mod/sumdb".Client. A "golang.org/x/mod/sumdb".Client caches the outcomes of
+// lookups and tile reads (including errors) for its lifetime without bound, so it must not be reused across verifications.
+// The latest signed tree and tiles are shared via c.ops instead.
+func (c *Client) newClient() *sumdb.Client {
+	return sumdb.NewClient(c.ops)
+}
+
+func (c *Client) verify(client *sumdb.Client, path, version, sum string) error {
+	lines, err := client.Lookup(path, version)
+	if err != nil {
+		return fmt.Errorf("error looking up %s %s in checksum database %s: %w", path, version, c.name, err)
+	}
+	want := path + " " + version + " " + sum
+	for _, line := range lines {
+		if line == want {
+			return nil
+		}
+	}
+	err = fmt.Errorf("SECURITY ERROR: hash %s of %s %s does not match checksum database %s (which has %#v)", sum, path,
+		version, c.name, lines)
+	log.Error(err)
+	return err
+}
+
+// clientOps implements "golang.org/x/mod/sumdb".ClientOps. The latest signed tree, tiles and lookup results are kept in
+// memory. Only data that has been verified is written to the cache.
+type clientOps struct {
+	cache       map[string][]byte
+	cacheMu     sync.Mutex
+	httpClient  *http.Client
+	latest      []byte
+	latestMu    sync.Mutex
+	name        string
+	parentProxy *url.URL
+	url         string
+	urlOnce     sync.Once
+	verifierKey string
+}
+
+var _ sumdb.ClientOps = (*clientOps)(nil)
+
+// baseURL returns the URL of the checksum database, or the URL at which the parent proxy proxies the checksum database.
+func (c *clientOps) baseURL() string {
+	c.urlOnce.Do(func() {
+		if c.parentProxy == nil {
+			return
+		}
+		proxyURL := strings.TrimSuffix(c.parentProxy.String(), "/") + "/sumdb/" + c.name
+		if _, err := c.get(proxyURL + "/supported"); err != nil {
+			log.Debugf("accessing checksum database %s directly because parent proxy does not proxy it: %v", c.name, err)
+			return
+		}
+		log.Debugf("accessing checksum database %s through parent proxy", c.name)
+		c.url = proxyURL
+	})
+	return c.url
+}
+
+func (c *clientOps) get(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), readRemoteTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading body of %d-response to %s %s: %v", resp.StatusCode, req.Method, url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected %d-response to %s %s: %s", resp.StatusCode, req.Method, url, string(respBodyBytes))
+	}
+	return respBodyBytes, nil
+}
+
+func (c *clientOps) ReadRemote(path string) ([]byte, error) {
+	return c.get(c.baseURL() + path)
+}
+
+func (c *clientOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(c.verifierKey), nil
+	}
+	if file == c.name+"/latest" {
+		c.latestMu.Lock()
+		defer c.latestMu.Unlock()
+		return c.latest, nil
+	}
+	return nil, fmt.Errorf("unknown config file %#v", file)
+}
+
+func (c *clientOps) WriteConfig(file string, old, new []byte) error {
+	if file != c.name+"/latest" {
+		return fmt.Errorf("unknown config file %#v", file)
+	}
+	c.latestMu.Lock()
+	defer c.latestMu.Unlock()
+	if !bytes.Equal(old, c.latest) {
+		return sumdb.ErrWriteConflict
+	}
+	c.latest = new
+	return nil
+}
+
+func (c *clientOps) ReadCache(file string) ([]byte, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	data, ok := c.cache[file]
+	if !ok {
+		return nil, fmt.Errorf("not cached")
+	}
+	return data, nil
+}
+
+func (c *clientOps) WriteCache(file string, data []byte) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if len(c.cache) >= maxCacheEntries {
+		// Evict an arbitrary entry.
+		for key := range c.cache {
+			delete(c.cache, key)
+			break
+		}
+	}
+	c.cache[file] = data
+}
+
+func (c *clientOps) Log(msg string) {
+	log.Debug(msg)
+}
+
+func (c *clientOps) SecurityError(msg string) {
+	log.Error(msg)
+}
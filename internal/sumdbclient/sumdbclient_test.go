@@ -0,0 +1,89 @@
+package sumdbclient
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const (
+	testZipSum   = "h1:Zip/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	testGoModSum = "h1:GoMod/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+)
+
+// newTestClient returns a Client of a test checksum database. The first failRequests requests to the checksum database
+// fail.
+func newTestClient(t *testing.T, failRequests int) *Client {
+	name := "sum.example.com"
+	signerKey, verifierKey, err := note.GenerateKey(rand.Reader, name)
+	require.NoError(t, err)
+	handler := sumdb.NewServer(sumdb.NewTestServer(signerKey, func(path, version string) ([]byte, error) {
+		if path != "example.com/m" {
+			return nil, fmt.Errorf("module %s not found", path)
+		}
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod %s\n", path, version, testZipSum, path, version, testGoModSum)), nil
+	}))
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		fail := failRequests > 0
+		failRequests--
+		mu.Unlock()
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := NewClient(ClientOptions{
+		HTTPClient: http.DefaultClient,
+		Name:       name,
+		PublicKey:  strings.TrimPrefix(verifierKey, name+"+"),
+		URL:        serverURL,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func Test_Client_Verify(t *testing.T) {
+	client := newTestClient(t, 0)
+	moduleVersion := module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	assert.NoError(t, client.Verify(moduleVersion, testZipSum, testGoModSum))
+	// The second lookup of the same module version is served from memory.
+	assert.NoError(t, client.Verify(moduleVersion, testZipSum, testGoModSum))
+
+	assert.NoError(t, client.VerifyGoMod(moduleVersion, testGoModSum))
+
+	err := client.Verify(moduleVersion, testZipSum, testZipSum)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "SECURITY ERROR")
+	}
+	err = client.VerifyGoMod(moduleVersion, testZipSum)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "SECURITY ERROR")
+	}
+	err = client.Verify(module.Version{Path: "example.com/other", Version: "v1.0.0"}, testZipSum, testGoModSum)
+	assert.Error(t, err)
+}
+
+func Test_Client_Verify_Retry(t *testing.T) {
+	client := newTestClient(t, 1)
+	moduleVersion := module.Version{Path: "example.com/m", Version: "v1.0.0"}
+	assert.Error(t, client.Verify(moduleVersion, testZipSum, testGoModSum))
+	// The failure is not remembered.
+	assert.NoError(t, client.Verify(moduleVersion, testZipSum, testGoModSum))
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 func ReadJSON200Response(resp *http.Response, respBody any, disallowUnknownFields bool) error {
@@ -32,3 +35,41 @@ func ReadJSON200Response(resp *http.Response, respBody any, disallowUnknownField
 	}
 	return nil
 }
+
+// FormatRangeHeader formats the value of a Range header that requests length bytes starting at byte offset. If length is
+// negative (or so large that the range would end after byte math.MaxInt64) then all bytes starting at byte offset are
+// requested. length must not be 0.
+func FormatRangeHeader(offset, length int64) string {
+	if length < 0 || length > math.MaxInt64-offset {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// ParseContentRangeSize parses the complete length from the value of a Content-Range header, such as "bytes 0-9/100" or
+// "bytes */100".
+func ParseContentRangeSize(contentRange string) (int64, error) {
+	i := strings.LastIndexByte(contentRange, '/')
+	if !strings.HasPrefix(contentRange, "bytes ") || i < 0 {
+		return 0, fmt.Errorf("value %#v of Content-Range header is invalid", contentRange)
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("value %#v of Content-Range header is invalid or has an unknown complete length", contentRange)
+	}
+	return size, nil
+}
+
+// SliceReadCloser discards the first offset bytes of r and returns a reader that reads at most length bytes of the
+// remainder of r. If length is negative then the returned reader reads all of the remainder of r. Closing the returned
+// reader closes r. If an error is returned then r is closed.
+func SliceReadCloser(r io.ReadCloser, offset, length int64) (io.ReadCloser, error) {
+	if _, err := io.CopyN(io.Discard, r, offset); err != nil && err != io.EOF {
+		_ = r.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return r, nil
+	}
+	return NewConcatReader(nil, io.LimitReader(r, length), nil, r.Close), nil
+}
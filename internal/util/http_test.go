@@ -2,6 +2,7 @@ package util
 
 import (
 	"errors"
+	"math"
 	"net/http"
 	urlpkg "net/url"
 	"testing"
@@ -80,3 +81,10 @@ func Test_ReadJSON200Response(t *testing.T) {
 		}
 	})
 }
+
+func Test_FormatRangeHeader(t *testing.T) {
+	assert.Equal(t, "bytes=3-", FormatRangeHeader(3, -1))
+	assert.Equal(t, "bytes=3-6", FormatRangeHeader(3, 4))
+	assert.Equal(t, "bytes=1-", FormatRangeHeader(1, math.MaxInt64))
+	assert.Equal(t, "bytes=0-9223372036854775806", FormatRangeHeader(0, math.MaxInt64))
+}